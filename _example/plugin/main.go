@@ -29,6 +29,13 @@ func main() {
 	pl := lib.Plugin{
 		Address: ":9000",
 		Logger:  log.Default(),
+		Name:    "printer",
+		Version: "v1.0.0",
+		Methods: []lib.Method{{
+			Name:        "Print",
+			Description: "prints the message to the log",
+			Vars:        []lib.Var{{Name: "message", Type: lib.TypeString, Required: true}},
+		}},
 	}
 	if err := pl.Listen(context.Background(), Handler{}); err != nil {
 		log.Printf("[WARN] listener stopped, reason: %v", err)
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"errors"
+
+	"github.com/cappuccinotm/dastracker/app/secrets"
 	"github.com/cappuccinotm/dastracker/pkg/logx"
 )
 
@@ -14,8 +16,9 @@ type CommonOptionsCommander interface {
 
 // CommonOpts sets externally from main, shared across all commands
 type CommonOpts struct {
-	Version string
-	Logger  logx.Logger
+	Version  string
+	Logger   logx.Logger
+	Redactor *secrets.Redactor // redacts revealed secrets from the log output
 }
 
 // SetCommon satisfies CommonOptionsCommander interface and sets common option fields
@@ -23,6 +26,7 @@ type CommonOpts struct {
 func (c *CommonOpts) SetCommon(opts CommonOpts) {
 	c.Version = opts.Version
 	c.Logger = opts.Logger
+	c.Redactor = opts.Redactor
 }
 
 // ErrInterrupted is returned when the signal to application to stop was caught.
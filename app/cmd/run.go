@@ -2,45 +2,68 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
-	"path"
 	"time"
 
+	"github.com/cappuccinotm/dastracker/app/flowcheck"
+	"github.com/cappuccinotm/dastracker/app/rest"
+	"github.com/cappuccinotm/dastracker/app/secrets"
 	"github.com/cappuccinotm/dastracker/app/store"
 	"github.com/cappuccinotm/dastracker/app/store/engine"
-	boltEngs "github.com/cappuccinotm/dastracker/app/store/engine/bolt"
 	"github.com/cappuccinotm/dastracker/app/store/engine/static"
 	"github.com/cappuccinotm/dastracker/app/store/service"
 	"github.com/cappuccinotm/dastracker/app/tracker"
+	"github.com/cappuccinotm/dastracker/lib"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
 	"github.com/gorilla/mux"
-	bolt "go.etcd.io/bbolt"
 	"golang.org/x/sync/errgroup"
 	"syscall"
 )
 
 // Run starts a tracker listener.
 type Run struct {
-	ConfLocation string `short:"c" long:"config_location" env:"CONFIG_LOCATION" description:"location of the configuration file"`
-	Store        struct {
-		Type string `long:"type" env:"TYPE" choice:"bolt" description:"type of storage"`
-		Bolt struct {
-			Path    string        `long:"path" env:"PATH" default:"./var" description:"parent dir for bolt files"`
-			Timeout time.Duration `long:"timeout" env:"TIMEOUT" default:"30s" description:"bolt timeout"`
-		} `group:"bolt" namespace:"bolt" env-namespace:"BOLT"`
-	} `group:"store" namespace:"store" env-namespace:"STORE"`
-	Webhook struct {
+	ConfLocation string    `short:"c" long:"config_location" env:"CONFIG_LOCATION" description:"location of the configuration file"`
+	Store        StoreOpts `group:"store" namespace:"store" env-namespace:"STORE"`
+	Webhook      struct {
 		BaseURL string `long:"base_url" env:"BASE_URL" description:"base url for webhooks"`
 		Addr    string `long:"addr" env:"ADDR" description:"local address to listen"`
 	} `group:"webhook" namespace:"webhook" env-namespace:"WEBHOOK"`
+	Backup struct {
+		Location string        `long:"location" env:"LOCATION" description:"directory for hot backups, disabled if empty"`
+		Interval time.Duration `long:"interval" env:"INTERVAL" default:"24h" description:"interval between hot backups, only on SIGUSR1 if zero"`
+	} `group:"backup" namespace:"backup" env-namespace:"BACKUP"`
+	Admin struct {
+		Addr  string `long:"addr" env:"ADDR" description:"local address of the admin API, disabled if empty"`
+		Token string `long:"token" env:"TOKEN" description:"bearer token to access the admin API"`
+	} `group:"admin" namespace:"admin" env-namespace:"ADMIN"`
+	History struct {
+		MaxAge          time.Duration `long:"max_age" env:"MAX_AGE" default:"720h" description:"remove job runs older than, zero to keep all"`
+		MaxRuns         int           `long:"max_runs" env:"MAX_RUNS" default:"10000" description:"maximum number of job runs to keep, zero to keep all"`
+		CleanupInterval time.Duration `long:"cleanup_interval" env:"CLEANUP_INTERVAL" default:"1h" description:"interval between removals of outdated job runs, zero to disable"`
+	} `group:"history" namespace:"history" env-namespace:"HISTORY"`
+	Secrets struct {
+		EnvPrefix string `long:"env_prefix" env:"ENV_PREFIX" description:"prefix of environment variables with secrets, disabled if empty"`
+		Dir       string `long:"dir" env:"DIR" description:"directory with secrets, one file per secret, disabled if empty"`
+		File      string `long:"file" env:"FILE" description:"location of the encrypted file with secrets, disabled if empty"`
+		Key       string `long:"key" env:"KEY" description:"base64-encoded key to decrypt the file with secrets"`
+	} `group:"secrets" namespace:"secrets" env-namespace:"SECRETS"`
+	EnvAllow      []string      `long:"env_allow" env:"ENV_ALLOW" env-delim:"," description:"environment variables, available to env function, NAME* allows all with the prefix"`
+	DryRun        bool          `long:"dry_run" env:"DRY_RUN" description:"don't call trackers, only log the planned calls"`
 	UpdateTimeout time.Duration `long:"update_timeout" env:"UPDATE_TIMEOUT" description:"amount of time per processing single update"`
 	CommonOpts
 }
 
 // Execute runs the command
 func (r Run) Execute(_ []string) error {
+	if err := r.prepareSecrets(); err != nil {
+		return fmt.Errorf("prepare secrets: %w", err)
+	}
+
 	flowStore, err := r.prepareFlowStore()
 	if err != nil {
 		return fmt.Errorf("prepare flow storage: %w", err)
@@ -51,16 +74,31 @@ func (r Run) Execute(_ []string) error {
 		return fmt.Errorf("initialize tickets store: %w", err)
 	}
 
-	trackers, err := r.prepareTrackers(flowStore)
+	trackers, err := prepareTrackers(flowStore, r.Logger)
 	if err != nil {
 		return fmt.Errorf("prepare trackers: %w", err)
 	}
+	// closed after the actor, as it unsubscribes from the trackers on exit
+	defer closeTrackers(trackers, r.Logger)
+
+	if err = r.checkPlugins(flowStore, trackers); err != nil {
+		return fmt.Errorf("check plugins: %w", err)
+	}
 
 	subStore, err := r.prepareSubscriptionsStore()
 	if err != nil {
 		return fmt.Errorf("prepare subscriptions store: %w", err)
 	}
 
+	if r.Admin.Addr != "" && r.Admin.Token == "" {
+		return errors.New("admin API requires the token to be set")
+	}
+
+	runsStore, err := r.Store.prepareRuns()
+	if err != nil {
+		return fmt.Errorf("prepare runs store: %w", err)
+	}
+
 	subscriptionsManager := &service.SubscriptionsManager{
 		BaseURL: r.Webhook.BaseURL,
 		Router:  mux.NewRouter(),
@@ -73,9 +111,12 @@ func (r Run) Execute(_ []string) error {
 		SubscriptionsManager: subscriptionsManager,
 		Trackers:             trackers,
 		TicketsStore:         ticketsStore,
+		Runs:                 runsStore,
 		Flow:                 flowStore,
 		Log:                  r.Logger.Sub("[actor]: "),
 		UpdateTimeout:        r.UpdateTimeout,
+		DryRun:               r.DryRun,
+		Redactor:             r.Redactor,
 	}
 
 	ctx, stop := context.WithCancel(context.Background())
@@ -106,6 +147,63 @@ func (r Run) Execute(_ []string) error {
 		return nil
 	})
 
+	if r.Admin.Addr != "" {
+		admin := &rest.Admin{
+			Addr:          r.Admin.Addr,
+			Token:         r.Admin.Token,
+			Flow:          flowStore,
+			Tickets:       ticketsStore,
+			Subscriptions: subStore,
+			Runs:          runsStore,
+			Runner:        actor,
+			Logger:        r.Logger.Sub("[admin]: "),
+		}
+
+		eg.Go(func() error {
+			if err := admin.Listen(ctx); err != nil {
+				return fmt.Errorf("admin server stopped running, reason: %w", err)
+			}
+			return nil
+		})
+	}
+
+	if r.History.CleanupInterval > 0 {
+		retention := &service.RunsRetention{
+			Runs:     runsStore,
+			Log:      r.Logger.Sub("[runs retention]: "),
+			MaxAge:   r.History.MaxAge,
+			MaxRuns:  r.History.MaxRuns,
+			Interval: r.History.CleanupInterval,
+		}
+
+		eg.Go(func() error {
+			if err := retention.Listen(ctx); err != nil {
+				return fmt.Errorf("runs retention stopped, reason: %w", err)
+			}
+			return nil
+		})
+	}
+
+	if r.Backup.Location != "" {
+		backup := &service.Backup{
+			Tickets:       ticketsStore,
+			Subscriptions: subStore,
+			Log:           r.Logger.Sub("[backup]: "),
+		}
+
+		// SIGUSR1 makes a hot backup immediately
+		trigger := make(chan os.Signal, 1)
+		signal.Notify(trigger, syscall.SIGUSR1)
+
+		eg.Go(func() error {
+			defer signal.Stop(trigger)
+			if err := backup.Schedule(ctx, r.Backup.Location, r.Backup.Interval, trigger); err != nil {
+				return fmt.Errorf("backup scheduler stopped, reason: %w", err)
+			}
+			return nil
+		})
+	}
+
 	if err = eg.Wait(); err != nil {
 		return err
 	}
@@ -113,72 +211,143 @@ func (r Run) Execute(_ []string) error {
 	return nil
 }
 
+// prepareSecrets restricts the env function and sets up the providers
+// of secrets, revealed secrets are redacted from the output.
+func (r Run) prepareSecrets() error {
+	if r.EnvAllow == nil {
+		r.Logger.Printf("[WARN] all environment variables are available to the configuration, " +
+			"restrict them with --env_allow")
+	}
+	store.AllowEnv(r.EnvAllow)
+
+	var providers secrets.Chain
+	if r.Secrets.EnvPrefix != "" {
+		providers = append(providers, secrets.Env{Prefix: r.Secrets.EnvPrefix})
+	}
+	if r.Secrets.Dir != "" {
+		providers = append(providers, secrets.Dir{Path: r.Secrets.Dir})
+	}
+	if r.Secrets.File != "" {
+		file, err := secrets.LoadEncrypted(r.Secrets.File, r.Secrets.Key)
+		if err != nil {
+			return fmt.Errorf("load encrypted secrets: %w", err)
+		}
+		providers = append(providers, file)
+	}
+
+	if len(providers) > 0 {
+		store.SetSecrets(r.Redactor.Wrap(providers))
+	}
+
+	return nil
+}
+
 func (r Run) prepareFlowStore() (engine.Flow, error) {
 	return static.NewStatic(r.ConfLocation)
 }
 
-func (r Run) prepareTrackers(flowStore engine.Flow) (map[string]tracker.Interface, error) {
-	trackers, err := flowStore.ListTrackers(context.Background())
+// checkPlugins checks that the configuration uses only methods and variables,
+// provided by the plugins.
+func (r Run) checkPlugins(flowStore engine.Flow, trackers map[string]tracker.Interface) error {
+	issues, err := flowcheck.ValidatePlugins(context.Background(), flowStore, describePlugins(trackers))
 	if err != nil {
-		return nil, fmt.Errorf("get trackers configs: %w", err)
+		return fmt.Errorf("validate flow: %w", err)
 	}
 
-	res := map[string]tracker.Interface{}
-	for _, trk := range trackers {
-		if trk.With, err = store.Evaluate(trk.With, store.EvalData{}); err != nil {
-			return nil, fmt.Errorf("evaluate variables for tracker %q: %w", trk.Name, err)
-		}
+	for _, issue := range issues {
+		r.Logger.Printf("[ERROR] %s", issue)
+	}
 
-		sublogger := r.Logger.Sub(fmt.Sprintf("[tracker|%s]: ", trk.Name))
+	if len(issues) > 0 {
+		return fmt.Errorf("configuration contains %d errors", len(issues))
+	}
 
-		switch trk.Driver {
-		case "rpc":
-			if res[trk.Name], err = tracker.NewJSONRPC(trk.Name, sublogger, trk.With); err != nil {
-				return nil, fmt.Errorf("initialize jsonrpc tracker %q: %w", trk.Name, err)
-			}
-		case "github":
-			if res[trk.Name], err = tracker.NewGithub(tracker.GithubParams{
-				Name:   trk.Name,
-				Vars:   trk.With,
-				Client: &http.Client{Timeout: 5 * time.Second},
-				Logger: sublogger,
-			}); err != nil {
-				return nil, fmt.Errorf("initialize github tracker %q: %w", trk.Name, err)
-			}
-		default:
-			return nil, fmt.Errorf("unsupported driver: %q", trk.Driver)
+	return nil
+}
+
+// describePlugins returns descriptions of the trackers,
+// which describe themselves, by their names.
+func describePlugins(trackers map[string]tracker.Interface) map[string]lib.Description {
+	res := map[string]lib.Description{}
+	for name, trk := range trackers {
+		d, ok := trk.(tracker.Describer)
+		if !ok {
+			continue
+		}
+		if desc, ok := d.Description(); ok {
+			res[name] = desc
 		}
 	}
+	return res
+}
 
-	return res, nil
+// closeTrackers closes the trackers, which hold resources, e.g. stops
+// the plugins, launched by dastracker.
+func closeTrackers(trackers map[string]tracker.Interface, l logx.Logger) {
+	for name, trk := range trackers {
+		c, ok := trk.(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := c.Close(); err != nil {
+			l.Printf("[WARN] failed to close tracker %q: %v", name, err)
+		}
+	}
 }
 
-func (r Run) prepareSubscriptionsStore() (engine.Subscriptions, error) {
-	switch r.Store.Type {
-	case "bolt":
-		subscriptions, err := boltEngs.NewSubscription(
-			path.Join(r.Store.Bolt.Path, "subscriptions.db"),
-			bolt.Options{Timeout: r.Store.Bolt.Timeout},
-			r.Logger.Sub("[subscriptions store]: "),
-		)
+func prepareTrackers(flowStore engine.Flow, l logx.Logger) (map[string]tracker.Interface, error) {
+	trackers, err := flowStore.ListTrackers(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("get trackers configs: %w", err)
+	}
+
+	res := map[string]tracker.Interface{}
+	for _, trk := range trackers {
+		svc, err := prepareTracker(trk, l.Sub(fmt.Sprintf("[tracker|%s]: ", trk.Name)))
 		if err != nil {
-			return nil, fmt.Errorf("initialize bolt store: %w", err)
+			// plugins, launched for the previous trackers, must be stopped
+			closeTrackers(res, l)
+			return nil, err
 		}
-		return subscriptions, nil
-	default:
-		return nil, fmt.Errorf("unsupported store type: %q", r.Store.Type)
+		res[trk.Name] = svc
 	}
+
+	return res, nil
 }
 
-func (r Run) prepareTicketsStore() (engine.Tickets, error) {
-	switch r.Store.Type {
-	case "bolt":
-		tickets, err := boltEngs.NewTickets(path.Join(r.Store.Bolt.Path, "tickets.db"), bolt.Options{Timeout: r.Store.Bolt.Timeout})
+func prepareTracker(trk store.Tracker, l logx.Logger) (tracker.Interface, error) {
+	vars, err := store.Evaluate(trk.With, store.EvalData{})
+	if err != nil {
+		return nil, fmt.Errorf("evaluate variables for tracker %q: %w", trk.Name, err)
+	}
+
+	switch trk.Driver {
+	case "rpc":
+		svc, err := tracker.NewJSONRPC(trk.Name, l, vars)
+		if err != nil {
+			return nil, fmt.Errorf("initialize jsonrpc tracker %q: %w", trk.Name, err)
+		}
+		return svc, nil
+	case "github":
+		svc, err := tracker.NewGithub(tracker.GithubParams{
+			Name:   trk.Name,
+			Vars:   vars,
+			Client: &http.Client{Timeout: 5 * time.Second},
+			Logger: l,
+		})
 		if err != nil {
-			return nil, fmt.Errorf("initialize bolt store: %w", err)
+			return nil, fmt.Errorf("initialize github tracker %q: %w", trk.Name, err)
 		}
-		return tickets, nil
+		return svc, nil
 	default:
-		return nil, fmt.Errorf("unsupported store type: %q", r.Store.Type)
+		return nil, fmt.Errorf("unsupported driver: %q", trk.Driver)
 	}
 }
+
+func (r *Run) prepareSubscriptionsStore() (engine.Subscriptions, error) {
+	return r.Store.prepareSubscriptions(r.Logger.Sub("[subscriptions store]: "))
+}
+
+func (r *Run) prepareTicketsStore() (engine.Tickets, error) {
+	return r.Store.prepareTickets()
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+)
+
+// Runs groups commands to inspect the history of job runs.
+type Runs struct {
+	List RunsList `command:"list" description:"list job runs, the most recent first"`
+	Show RunsShow `command:"show" description:"show the details of a single job run"`
+}
+
+// RunsList prints the job runs, matching the filters.
+type RunsList struct {
+	Store      StoreOpts     `group:"store" namespace:"store" env-namespace:"STORE"`
+	Job        string        `long:"job" description:"show runs of the job"`
+	Trigger    string        `long:"trigger" description:"show runs, caused by the trigger"`
+	Tracker    string        `long:"tracker" description:"show runs over updates from the tracker"`
+	TaskID     string        `long:"task_id" description:"show runs over updates of the task"`
+	TicketID   string        `long:"ticket_id" description:"show runs over the ticket"`
+	OnlyFailed bool          `long:"failed" description:"show only failed runs"`
+	Since      time.Duration `long:"since" description:"show runs started within the given duration"`
+	Limit      int           `long:"limit" default:"50" description:"maximum number of runs to show"`
+	CommonOpts
+}
+
+// Execute runs the command
+func (r RunsList) Execute(_ []string) error {
+	runs, err := r.Store.prepareRuns()
+	if err != nil {
+		return fmt.Errorf("prepare runs store: %w", err)
+	}
+
+	req := engine.ListRunsRequest{
+		JobName:     r.Job,
+		TriggerName: r.Trigger,
+		Update:      store.Locator{Tracker: r.Tracker, ID: r.TaskID},
+		TicketID:    r.TicketID,
+		OnlyFailed:  r.OnlyFailed,
+		Limit:       r.Limit,
+	}
+	if r.Since > 0 {
+		req.Since = time.Now().Add(-r.Since)
+	}
+
+	list, err := runs.List(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("list runs: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTARTED AT\tDURATION\tJOB\tTRIGGER\tUPDATE\tSTEPS\tERROR")
+	for _, run := range list {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			run.ID, run.StartedAt.Format(time.RFC3339), run.Duration, run.JobName, run.TriggerName,
+			run.Update, len(run.Steps), strings.ReplaceAll(run.Error, "\n", " "))
+	}
+
+	if err = w.Flush(); err != nil {
+		return fmt.Errorf("print runs: %w", err)
+	}
+
+	return nil
+}
+
+// RunsShow prints the job run with all its steps.
+type RunsShow struct {
+	Store StoreOpts `group:"store" namespace:"store" env-namespace:"STORE"`
+	Args  struct {
+		RunID string `positional-arg-name:"run_id" description:"identifier of the run"`
+	} `positional-args:"yes" required:"yes"`
+	CommonOpts
+}
+
+// Execute runs the command
+func (r RunsShow) Execute(_ []string) error {
+	runs, err := r.Store.prepareRuns()
+	if err != nil {
+		return fmt.Errorf("prepare runs store: %w", err)
+	}
+
+	run, err := runs.Get(context.Background(), r.Args.RunID)
+	if err != nil {
+		return fmt.Errorf("get run: %w", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(run); err != nil {
+		return fmt.Errorf("print run: %w", err)
+	}
+
+	return nil
+}
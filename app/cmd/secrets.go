@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/cappuccinotm/dastracker/app/secrets"
+)
+
+// Secrets groups commands to maintain the encrypted file with secrets.
+type Secrets struct {
+	Keygen  SecretsKeygen  `command:"keygen" description:"generate a new key to encrypt secrets"`
+	Encrypt SecretsEncrypt `command:"encrypt" description:"encrypt secrets from the JSON file"`
+}
+
+// SecretsKeygen prints a new random key.
+type SecretsKeygen struct {
+	CommonOpts
+}
+
+// Execute runs the command
+func (s SecretsKeygen) Execute(_ []string) error {
+	key, err := secrets.NewKey()
+	if err != nil {
+		return fmt.Errorf("generate key: %w", err)
+	}
+	fmt.Println(key)
+	return nil
+}
+
+// SecretsEncrypt encrypts the JSON object with secrets, so that it
+// might be used as the file with secrets by the run command.
+type SecretsEncrypt struct {
+	Key    string `short:"k" long:"key" env:"SECRETS_KEY" required:"true" description:"base64-encoded key to encrypt secrets"`
+	Input  string `short:"i" long:"input" required:"true" description:"location of the JSON object with secrets"`
+	Output string `short:"o" long:"output" required:"true" description:"location of the encrypted file"`
+	CommonOpts
+}
+
+// Execute runs the command
+func (s SecretsEncrypt) Execute(_ []string) error {
+	bts, err := os.ReadFile(s.Input)
+	if err != nil {
+		return fmt.Errorf("read secrets: %w", err)
+	}
+
+	var values map[string]string
+	if err = json.Unmarshal(bts, &values); err != nil {
+		return fmt.Errorf("unmarshal secrets: %w", err)
+	}
+
+	enc, err := secrets.Encrypt(s.Key, values)
+	if err != nil {
+		return fmt.Errorf("encrypt secrets: %w", err)
+	}
+
+	if err = os.WriteFile(s.Output, enc, 0o600); err != nil {
+		return fmt.Errorf("write encrypted secrets: %w", err)
+	}
+
+	s.Logger.Printf("[INFO] %d secrets encrypted to %s", len(values), s.Output)
+	return nil
+}
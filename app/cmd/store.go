@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	boltEngs "github.com/cappuccinotm/dastracker/app/store/engine/bolt"
+	"github.com/cappuccinotm/dastracker/app/store/engine/memory"
+	"github.com/cappuccinotm/dastracker/app/store/engine/sqlite"
+	"github.com/cappuccinotm/dastracker/app/store/service"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+	bolt "go.etcd.io/bbolt"
+)
+
+// StoreOpts defines parameters to connect to the storage.
+type StoreOpts struct {
+	Type string `long:"type" env:"TYPE" choice:"bolt" choice:"memory" choice:"sqlite" description:"type of storage"`
+	Bolt struct {
+		Path    string        `long:"path" env:"PATH" default:"./var" description:"parent dir for bolt files"`
+		Timeout time.Duration `long:"timeout" env:"TIMEOUT" default:"30s" description:"bolt timeout"`
+	} `group:"bolt" namespace:"bolt" env-namespace:"BOLT"`
+	SQLite struct {
+		Path string `long:"path" env:"PATH" default:"./var/dastracker.db" description:"location of the sqlite database file"`
+	} `group:"sqlite" namespace:"sqlite" env-namespace:"SQLITE"`
+
+	sqliteDB *sql.DB // shared by all sqlite stores
+}
+
+// Store groups commands to maintain the storage.
+type Store struct {
+	Migrate StoreMigrate `command:"migrate" description:"apply pending migrations of the storage schema"`
+	Export  StoreExport  `command:"export" description:"dump tickets and subscriptions to a JSON Lines file"`
+	Import  StoreImport  `command:"import" description:"restore tickets and subscriptions from a JSON Lines file"`
+}
+
+// StoreMigrate applies pending migrations of the storage schema.
+type StoreMigrate struct {
+	Store  StoreOpts `group:"store" namespace:"store" env-namespace:"STORE"`
+	DryRun bool      `long:"dry-run" description:"only list pending migrations, without applying them"`
+	CommonOpts
+}
+
+// Execute runs the command
+func (s StoreMigrate) Execute(_ []string) error {
+	migrations, err := s.Store.migrate(s.DryRun)
+	if err != nil {
+		return fmt.Errorf("migrate storage: %w", err)
+	}
+
+	action := "applied"
+	if s.DryRun {
+		action = "pending"
+	}
+
+	for _, f := range migrations {
+		if f.missing {
+			s.Logger.Printf("[INFO] %s: file doesn't exist, it will be created with the latest schema", f.name)
+			continue
+		}
+
+		if len(f.migrations) == 0 {
+			s.Logger.Printf("[INFO] %s: schema is up to date", f.name)
+			continue
+		}
+
+		for _, m := range f.migrations {
+			s.Logger.Printf("[INFO] %s: %s migration %d: %s", f.name, action, m.Version, m.Description)
+		}
+	}
+
+	return nil
+}
+
+// StoreExport dumps the state of the storage to the file.
+type StoreExport struct {
+	Store StoreOpts `group:"store" namespace:"store" env-namespace:"STORE"`
+	File  string    `short:"f" long:"file" required:"true" description:"location of the dump file"`
+	CommonOpts
+}
+
+// Execute runs the command
+func (s StoreExport) Execute(_ []string) error {
+	backup, err := s.Store.prepareBackup(s.Logger)
+	if err != nil {
+		return fmt.Errorf("prepare storage: %w", err)
+	}
+
+	f, err := os.OpenFile(s.File, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600) //nolint:gosec // path is set by operator
+	if err != nil {
+		return fmt.Errorf("create dump file: %w", err)
+	}
+
+	stats, err := backup.Export(context.Background(), f)
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("export: %w", err)
+	}
+
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("close dump file: %w", err)
+	}
+
+	s.Logger.Printf("[INFO] exported %d tickets and %d subscriptions to %s",
+		stats.Tickets, stats.Subscriptions, s.File)
+	return nil
+}
+
+// StoreImport restores the state of the storage from the file.
+type StoreImport struct {
+	Store StoreOpts `group:"store" namespace:"store" env-namespace:"STORE"`
+	File  string    `short:"f" long:"file" required:"true" description:"location of the dump file"`
+	CommonOpts
+}
+
+// Execute runs the command
+func (s StoreImport) Execute(_ []string) error {
+	backup, err := s.Store.prepareBackup(s.Logger)
+	if err != nil {
+		return fmt.Errorf("prepare storage: %w", err)
+	}
+
+	f, err := os.Open(s.File) //nolint:gosec // path is set by operator
+	if err != nil {
+		return fmt.Errorf("open dump file: %w", err)
+	}
+	defer f.Close() //nolint:errcheck // read-only file
+
+	stats, err := backup.Import(context.Background(), f)
+	if err != nil {
+		return fmt.Errorf("import, %d tickets and %d subscriptions were imported before the failure: %w",
+			stats.Tickets, stats.Subscriptions, err)
+	}
+
+	s.Logger.Printf("[INFO] imported %d tickets and %d subscriptions from %s",
+		stats.Tickets, stats.Subscriptions, s.File)
+	return nil
+}
+
+// fileMigrations describes migrations of a single storage file.
+type fileMigrations struct {
+	name       string
+	migrations []engine.Migration
+	missing    bool // the file doesn't exist yet, reported only in dry run
+}
+
+// migrate applies migrations to each of the storage files and returns them.
+func (s StoreOpts) migrate(dryRun bool) ([]fileMigrations, error) {
+	switch s.Type {
+	case "bolt":
+		opts := bolt.Options{Timeout: s.Bolt.Timeout}
+
+		files := []struct {
+			name    string
+			migrate func(string, bolt.Options, bool) ([]engine.Migration, error)
+		}{
+			{name: "tickets.db", migrate: boltEngs.MigrateTickets},
+			{name: "subscriptions.db", migrate: boltEngs.MigrateSubscriptions},
+			{name: "runs.db", migrate: boltEngs.MigrateRuns},
+		}
+
+		var res []fileMigrations
+		for _, f := range files {
+			migrations, err := f.migrate(path.Join(s.Bolt.Path, f.name), opts, dryRun)
+			if err != nil && (!dryRun || !errors.Is(err, errs.ErrNotFound)) {
+				return nil, fmt.Errorf("migrate %s: %w", f.name, err)
+			}
+			res = append(res, fileMigrations{name: f.name, migrations: migrations, missing: err != nil})
+		}
+
+		return res, nil
+	case "sqlite":
+		migrations, err := sqlite.Migrate(s.SQLite.Path, dryRun)
+		if err != nil && (!dryRun || !errors.Is(err, errs.ErrNotFound)) {
+			return nil, fmt.Errorf("migrate sqlite: %w", err)
+		}
+		return []fileMigrations{{name: path.Base(s.SQLite.Path), migrations: migrations, missing: err != nil}}, nil
+	default:
+		return nil, fmt.Errorf("migrations are not supported by store type: %q", s.Type)
+	}
+}
+
+// openSQLite opens the sqlite database on the first call, all sqlite
+// stores share the same handle, as sqlite allows only one writer at a time.
+func (s *StoreOpts) openSQLite() (*sql.DB, error) {
+	if s.sqliteDB != nil {
+		return s.sqliteDB, nil
+	}
+
+	db, err := sqlite.Open(s.SQLite.Path)
+	if err != nil {
+		return nil, err
+	}
+	s.sqliteDB = db
+	return db, nil
+}
+
+func (s *StoreOpts) prepareBackup(l logx.Logger) (*service.Backup, error) {
+	tickets, err := s.prepareTickets()
+	if err != nil {
+		return nil, fmt.Errorf("initialize tickets store: %w", err)
+	}
+
+	subscriptions, err := s.prepareSubscriptions(l.Sub("[subscriptions store]: "))
+	if err != nil {
+		return nil, fmt.Errorf("initialize subscriptions store: %w", err)
+	}
+
+	return &service.Backup{Tickets: tickets, Subscriptions: subscriptions, Log: l}, nil
+}
+
+func (s *StoreOpts) prepareSubscriptions(l logx.Logger) (engine.Subscriptions, error) {
+	switch s.Type {
+	case "bolt":
+		subscriptions, err := boltEngs.NewSubscription(
+			path.Join(s.Bolt.Path, "subscriptions.db"),
+			bolt.Options{Timeout: s.Bolt.Timeout},
+			l,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("initialize bolt store: %w", err)
+		}
+		return subscriptions, nil
+	case "memory":
+		return memory.NewSubscriptions(), nil
+	case "sqlite":
+		db, err := s.openSQLite()
+		if err != nil {
+			return nil, fmt.Errorf("initialize sqlite store: %w", err)
+		}
+		return sqlite.NewSubscriptions(db, l), nil
+	default:
+		return nil, fmt.Errorf("unsupported store type: %q", s.Type)
+	}
+}
+
+func (s *StoreOpts) prepareTickets() (engine.Tickets, error) {
+	switch s.Type {
+	case "bolt":
+		tickets, err := boltEngs.NewTickets(path.Join(s.Bolt.Path, "tickets.db"), bolt.Options{Timeout: s.Bolt.Timeout})
+		if err != nil {
+			return nil, fmt.Errorf("initialize bolt store: %w", err)
+		}
+		return tickets, nil
+	case "memory":
+		return memory.NewTickets(), nil
+	case "sqlite":
+		db, err := s.openSQLite()
+		if err != nil {
+			return nil, fmt.Errorf("initialize sqlite store: %w", err)
+		}
+		return sqlite.NewTickets(db), nil
+	default:
+		return nil, fmt.Errorf("unsupported store type: %q", s.Type)
+	}
+}
+
+func (s *StoreOpts) prepareRuns() (engine.Runs, error) {
+	switch s.Type {
+	case "bolt":
+		runs, err := boltEngs.NewRuns(path.Join(s.Bolt.Path, "runs.db"), bolt.Options{Timeout: s.Bolt.Timeout})
+		if err != nil {
+			return nil, fmt.Errorf("initialize bolt store: %w", err)
+		}
+		return runs, nil
+	case "memory":
+		return memory.NewRuns(), nil
+	case "sqlite":
+		db, err := s.openSQLite()
+		if err != nil {
+			return nil, fmt.Errorf("initialize sqlite store: %w", err)
+		}
+		return sqlite.NewRuns(db), nil
+	default:
+		return nil, fmt.Errorf("unsupported store type: %q", s.Type)
+	}
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cappuccinotm/dastracker/app/flowtest"
+	"github.com/cappuccinotm/dastracker/app/store/engine/static"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+)
+
+// Test runs the flow over the recorded updates with fake trackers
+// and compares the results with golden files.
+type Test struct {
+	ConfLocation string `short:"c" long:"config_location" env:"CONFIG_LOCATION" required:"true" description:"location of the configuration file"`
+	Update       bool   `long:"update" description:"rewrite golden files with the actual results"`
+	Args         struct {
+		Fixtures string `positional-arg-name:"fixtures" description:"fixture file or directory with fixtures"`
+	} `positional-args:"yes" required:"yes"`
+	CommonOpts
+}
+
+// Execute runs the command
+func (t Test) Execute(_ []string) error {
+	flow, err := static.NewStatic(t.ConfLocation)
+	if err != nil {
+		return fmt.Errorf("load flow: %w", err)
+	}
+
+	runner := &flowtest.Runner{Flow: flow, Log: logx.Nop()}
+
+	reports, err := runner.Check(context.Background(), t.Args.Fixtures, t.Update)
+	if err != nil {
+		return fmt.Errorf("check fixtures: %w", err)
+	}
+
+	failed := 0
+	for _, r := range reports {
+		switch {
+		case r.Updated:
+			t.Logger.Printf("[INFO] fixture %s: golden file updated", r.Fixture)
+		case r.Diff != "":
+			failed++
+			t.Logger.Printf("[WARN] fixture %s doesn't match the golden file:\n%s", r.Fixture, r.Diff)
+		default:
+			t.Logger.Printf("[INFO] fixture %s: ok", r.Fixture)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d fixtures failed", failed, len(reports))
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cappuccinotm/dastracker/app/flowcheck"
+	"github.com/cappuccinotm/dastracker/app/store/engine/static"
+	"github.com/cappuccinotm/dastracker/lib"
+)
+
+// Validate checks that the configuration might be run.
+type Validate struct {
+	ConfLocation string `short:"c" long:"config_location" env:"CONFIG_LOCATION" required:"true" description:"location of the configuration file"`
+	Plugins      bool   `long:"plugins" description:"connect to the plugins to check their methods and variables"`
+	CommonOpts
+}
+
+// Execute runs the command
+func (v Validate) Execute(_ []string) error {
+	flow, err := static.NewStatic(v.ConfLocation)
+	if err != nil {
+		return fmt.Errorf("load flow: %w", err)
+	}
+
+	var plugins map[string]lib.Description
+	if v.Plugins {
+		trackers, err := prepareTrackers(flow, v.Logger)
+		if err != nil {
+			return fmt.Errorf("prepare trackers: %w", err)
+		}
+		defer closeTrackers(trackers, v.Logger)
+		plugins = describePlugins(trackers)
+	}
+
+	issues, err := flowcheck.Validate(context.Background(), flow, plugins)
+	if err != nil {
+		return fmt.Errorf("validate flow: %w", err)
+	}
+
+	for _, issue := range issues {
+		v.Logger.Printf("[ERROR] %s", issue)
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("configuration contains %d errors", len(issues))
+	}
+
+	v.Logger.Printf("[INFO] configuration is valid")
+	return nil
+}
+
+// Lint looks for suspicious constructions in the configuration.
+type Lint struct {
+	ConfLocation string `short:"c" long:"config_location" env:"CONFIG_LOCATION" required:"true" description:"location of the configuration file"`
+	CommonOpts
+}
+
+// Execute runs the command
+func (l Lint) Execute(_ []string) error {
+	flow, err := static.NewStatic(l.ConfLocation)
+	if err != nil {
+		return fmt.Errorf("load flow: %w", err)
+	}
+
+	issues, err := flowcheck.Lint(context.Background(), flow)
+	if err != nil {
+		return fmt.Errorf("lint flow: %w", err)
+	}
+
+	for _, issue := range issues {
+		l.Logger.Printf("[WARN] %s", issue)
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("configuration contains %d warnings", len(issues))
+	}
+
+	l.Logger.Printf("[INFO] no warnings")
+	return nil
+}
@@ -5,6 +5,7 @@ package errs
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // Standard errors.
@@ -50,6 +51,61 @@ func (e ErrActionNotSupported) Error() string {
 	return fmt.Sprintf("action %q is not supported", string(e))
 }
 
+// ErrInvalidArgument indicates that the tracker rejected the request
+// due to invalid variables, thus the call must not be retried.
+type ErrInvalidArgument string
+
+// Error returns the string representation of the error.
+func (e ErrInvalidArgument) Error() string {
+	return fmt.Sprintf("invalid argument: %s", string(e))
+}
+
+// ErrConflict indicates that the task has been changed concurrently.
+type ErrConflict string
+
+// Error returns the string representation of the error.
+func (e ErrConflict) Error() string {
+	return fmt.Sprintf("conflict: %s", string(e))
+}
+
+// ErrUnavailable indicates that the tracker is temporarily unavailable,
+// the call might be retried after RetryAfter, if it is set.
+type ErrUnavailable struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+// Error returns the string representation of the error.
+func (e ErrUnavailable) Error() string {
+	return fmt.Sprintf("tracker is unavailable: %s", e.Message)
+}
+
+// ErrRateLimited indicates that the tracker limits the calls,
+// the call might be retried after RetryAfter, if it is set.
+type ErrRateLimited struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+// Error returns the string representation of the error.
+func (e ErrRateLimited) Error() string {
+	return fmt.Sprintf("rate limit exceeded: %s", e.Message)
+}
+
+// RetryAfter returns true, if the failed call might be retried, and the
+// delay, requested by the tracker, zero if the delay is up to the caller.
+func RetryAfter(err error) (time.Duration, bool) {
+	var unavailable ErrUnavailable
+	if errors.As(err, &unavailable) {
+		return unavailable.RetryAfter, true
+	}
+	var rateLimited ErrRateLimited
+	if errors.As(err, &rateLimited) {
+		return rateLimited.RetryAfter, true
+	}
+	return 0, false
+}
+
 // ErrGithubAPI describes any error responded by the Github API.
 type ErrGithubAPI struct {
 	ResponseStatus int    `json:"-"`
@@ -66,3 +122,16 @@ func (e ErrGithubAPI) Error() string {
 	return fmt.Sprintf("github api responded error with status %d, message: %s, errors: %+v",
 		e.ResponseStatus, e.Message, e.Errors)
 }
+
+// ErrSchemaTooNew indicates that the storage has been migrated by the newer
+// version of the application and can't be safely used by the current one.
+type ErrSchemaTooNew struct {
+	Current   int
+	Supported int
+}
+
+// Error returns the string representation of the error.
+func (e ErrSchemaTooNew) Error() string {
+	return fmt.Sprintf("storage schema version %d is newer than the latest supported %d, upgrade the application",
+		e.Current, e.Supported)
+}
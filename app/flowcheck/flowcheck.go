@@ -0,0 +1,343 @@
+// Package flowcheck inspects the flow configuration for the errors, which
+// otherwise would surface only at run time, and for suspicious constructions.
+package flowcheck
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/antonmedv/expr"
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/app/tracker"
+	"github.com/cappuccinotm/dastracker/lib"
+)
+
+// Issue describes a single problem in the configuration.
+type Issue struct {
+	Where string // location of the problem, e.g. job "name", step 1.2
+	Msg   string
+}
+
+// String returns the string representation of the issue.
+func (i Issue) String() string { return i.Where + ": " + i.Msg }
+
+// flow contains the whole configuration.
+type flow struct {
+	trackers []store.Tracker
+	triggers []store.Trigger
+	jobs     []store.Job
+}
+
+func load(ctx context.Context, f engine.Flow) (res flow, err error) {
+	if res.trackers, err = f.ListTrackers(ctx); err != nil {
+		return flow{}, fmt.Errorf("list trackers: %w", err)
+	}
+	if res.triggers, err = f.ListTriggers(ctx); err != nil {
+		return flow{}, fmt.Errorf("list triggers: %w", err)
+	}
+	if res.jobs, err = f.ListJobs(ctx); err != nil {
+		return flow{}, fmt.Errorf("list jobs: %w", err)
+	}
+	return res, nil
+}
+
+// Validate checks that the configuration might be run: drivers of trackers are
+// supported and their required variables are set, methods of actions are
+// supported by the trackers, conditions and templates of variables compile.
+// Methods of plugins are checked only if their descriptions are provided
+// in plugins, by the names of trackers, see ValidatePlugins.
+func Validate(ctx context.Context, f engine.Flow, plugins map[string]lib.Description) ([]Issue, error) {
+	fl, err := load(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Issue
+	drivers := map[string]string{} // map[tracker name]driver
+
+	for _, trk := range fl.trackers {
+		where := fmt.Sprintf("tracker %q", trk.Name)
+		drivers[trk.Name] = trk.Driver
+
+		drv, ok := tracker.Drivers[trk.Driver]
+		if !ok {
+			res = append(res, Issue{Where: where, Msg: fmt.Sprintf("unsupported driver %q", trk.Driver)})
+		}
+		for _, name := range drv.Vars {
+			if strings.TrimSpace(trk.With.Get(name)) == "" {
+				res = append(res, Issue{Where: where, Msg: fmt.Sprintf("required variable %q is not set", name)})
+			}
+		}
+		if len(drv.AnyOf) > 0 && !anySet(trk.With, drv.AnyOf) {
+			res = append(res, Issue{Where: where, Msg: fmt.Sprintf("one of variables %q must be set", drv.AnyOf)})
+		}
+		res = append(res, validateVars(where, trk.With)...)
+	}
+
+	for _, trigger := range fl.triggers {
+		res = append(res, validateVars(fmt.Sprintf("trigger %q", trigger.Name), trigger.With)...)
+	}
+
+	for _, job := range fl.jobs {
+		walk(job.Actions, "", func(path string, step store.Step) bool {
+			where := fmt.Sprintf("job %q, step %s", job.Name, path)
+			switch step := step.(type) {
+			case store.Action:
+				res = append(res, validateVars(where, step.With)...)
+
+				trk, method := step.Path()
+				methods := tracker.Drivers[drivers[trk]].Methods
+				if len(methods) > 0 && !contains(methods, method) {
+					res = append(res, Issue{Where: where, Msg: fmt.Sprintf("method %q is not supported by tracker %q, "+
+						"supported methods: %s", method, trk, strings.Join(methods, ", "))})
+				}
+			case store.If:
+				if _, err := step.Compile(); err != nil {
+					res = append(res, Issue{Where: where, Msg: err.Error()})
+				}
+			}
+			return true
+		})
+	}
+
+	return append(res, validatePlugins(fl, plugins)...), nil
+}
+
+// ValidatePlugins checks the usage of plugins, described in plugins by the
+// names of their trackers: methods of actions are provided by the plugins and
+// required variables are set, triggers refer to plugins with subscriptions.
+func ValidatePlugins(ctx context.Context, f engine.Flow, plugins map[string]lib.Description) ([]Issue, error) {
+	fl, err := load(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	return validatePlugins(fl, plugins), nil
+}
+
+func validatePlugins(fl flow, plugins map[string]lib.Description) []Issue {
+	if len(plugins) == 0 {
+		return nil
+	}
+
+	var res []Issue
+
+	for _, trigger := range fl.triggers {
+		if desc, ok := plugins[trigger.Tracker]; ok && !desc.Subscriptions {
+			res = append(res, Issue{Where: fmt.Sprintf("trigger %q", trigger.Name),
+				Msg: fmt.Sprintf("tracker %q doesn't support subscriptions", trigger.Tracker)})
+		}
+	}
+
+	for _, job := range fl.jobs {
+		walk(job.Actions, "", func(path string, step store.Step) bool {
+			act, ok := step.(store.Action)
+			if !ok {
+				return true
+			}
+
+			trk, method := act.Path()
+			desc, ok := plugins[trk]
+			if !ok {
+				return true
+			}
+
+			where := fmt.Sprintf("job %q, step %s", job.Name, path)
+			spec, ok := desc.Method(method)
+			if !ok {
+				res = append(res, Issue{Where: where, Msg: fmt.Sprintf("method %q is not provided by plugin %q, "+
+					"available methods: %s", method, trk, strings.Join(desc.MethodNames(), ", "))})
+				return true
+			}
+
+			for _, v := range spec.Vars {
+				val, present := act.With[v.Name]
+				typ := lib.TypeOf(val)
+				switch {
+				case !present && v.Required:
+					res = append(res, Issue{Where: where, Msg: fmt.Sprintf("required variable %q is not set", v.Name)})
+				// strings are templates, their type is known only after evaluation
+				case present && v.Type != lib.TypeAny && typ != lib.TypeString && typ != v.Type:
+					res = append(res, Issue{Where: where, Msg: fmt.Sprintf("variable %q must be of type %s, got %s",
+						v.Name, v.Type, typ)})
+				}
+			}
+			return true
+		})
+	}
+
+	return res
+}
+
+// Lint looks for suspicious constructions in the configuration: duplicate
+// names, unused trackers and triggers, jobs without reachable actions and
+// detached actions, whose tasks are used by the following steps.
+func Lint(ctx context.Context, f engine.Flow) ([]Issue, error) {
+	fl, err := load(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Issue
+
+	usedTrackers := map[string]bool{}
+	usedTriggers := map[string]bool{}
+	for _, trigger := range fl.triggers {
+		usedTrackers[trigger.Tracker] = true
+	}
+
+	for _, job := range fl.jobs {
+		usedTriggers[job.TriggerName] = true
+		walk(job.Actions, "", func(_ string, step store.Step) bool {
+			if act, ok := step.(store.Action); ok {
+				trk, _ := act.Path()
+				usedTrackers[trk] = true
+			}
+			return true
+		})
+	}
+
+	names := func(kind string, l []string) {
+		seen := map[string]bool{}
+		for _, name := range l {
+			if seen[name] {
+				res = append(res, Issue{Where: fmt.Sprintf("%s %q", kind, name), Msg: "duplicate name"})
+			}
+			seen[name] = true
+		}
+	}
+
+	trackers := make([]string, 0, len(fl.trackers))
+	for _, trk := range fl.trackers {
+		trackers = append(trackers, trk.Name)
+		if !usedTrackers[trk.Name] {
+			res = append(res, Issue{Where: fmt.Sprintf("tracker %q", trk.Name),
+				Msg: "tracker is not used by any trigger or action"})
+		}
+	}
+	names("tracker", trackers)
+
+	triggers := make([]string, 0, len(fl.triggers))
+	for _, trigger := range fl.triggers {
+		triggers = append(triggers, trigger.Name)
+		if !usedTriggers[trigger.Name] {
+			res = append(res, Issue{Where: fmt.Sprintf("trigger %q", trigger.Name),
+				Msg: "trigger is not used by any job"})
+		}
+	}
+	names("trigger", triggers)
+
+	jobs := make([]string, 0, len(fl.jobs))
+	for _, job := range fl.jobs {
+		jobs = append(jobs, job.Name)
+		res = append(res, lintJob(job)...)
+	}
+	names("job", jobs)
+
+	return res, nil
+}
+
+func lintJob(job store.Job) []Issue {
+	var res []Issue
+
+	reachable := false
+	detached := map[string]string{} // map[tracker]name of the detached action
+	walk(job.Actions, "", func(path string, step store.Step) bool {
+		where := fmt.Sprintf("job %q, step %s", job.Name, path)
+		switch step := step.(type) {
+		case store.Action:
+			reachable = true
+
+			for _, trk := range variationRefs(step.With) {
+				if name, ok := detached[trk]; ok {
+					res = append(res, Issue{Where: where, Msg: fmt.Sprintf("variables refer to the task in %q, "+
+						"that is changed by the detached action %q", trk, name)})
+				}
+			}
+
+			trk, _ := step.Path()
+			if name, ok := detached[trk]; ok {
+				res = append(res, Issue{Where: where, Msg: fmt.Sprintf("action calls the task, "+
+					"that is changed by the detached action %q", name)})
+			}
+
+			if step.Detached {
+				detached[trk] = step.Name
+			}
+		case store.If:
+			if alwaysFalse(step.Condition) {
+				res = append(res, Issue{Where: where, Msg: "condition is always false"})
+				return false
+			}
+		}
+		return true
+	})
+
+	if !reachable {
+		res = append(res, Issue{Where: fmt.Sprintf("job %q", job.Name), Msg: "job has no reachable actions"})
+	}
+
+	return res
+}
+
+// walk calls fn for each step of the sequence in order of their execution
+// with the path of the step, e.g. "1.2" for the second step in the first
+// condition. fn returns false to skip the nested steps of the condition.
+func walk(seq store.Sequence, prefix string, fn func(path string, step store.Step) bool) {
+	for idx, step := range seq {
+		path := fmt.Sprintf("%s%d", prefix, idx+1)
+		if !fn(path, step) {
+			continue
+		}
+		if cond, ok := step.(store.If); ok {
+			walk(cond.Actions, path+".", fn)
+		}
+	}
+}
+
+// alwaysFalse returns true if the condition doesn't depend on
+// the update and the environment and evaluates to false.
+func alwaysFalse(cond string) bool {
+	prg, err := expr.Compile(cond, expr.Env(map[string]interface{}{}), expr.AsBool())
+	if err != nil {
+		return false
+	}
+	v, err := expr.Run(prg, map[string]interface{}{})
+	return err == nil && v == false
+}
+
+func validateVars(where string, vars lib.Vars) []Issue {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var res []Issue
+	for _, key := range keys {
+		if _, err := store.CompileVars(lib.Vars{key: vars[key]}); err != nil {
+			res = append(res, Issue{Where: where, Msg: err.Error()})
+		}
+	}
+	return res
+}
+
+func anySet(vars lib.Vars, names []string) bool {
+	for _, name := range names {
+		if strings.TrimSpace(vars.Get(name)) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(l []string, s string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
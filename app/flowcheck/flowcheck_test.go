@@ -0,0 +1,191 @@
+package flowcheck
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("valid configuration", func(t *testing.T) {
+		issues, err := Validate(context.Background(), prepareFlow(
+			[]store.Tracker{
+				{Name: "gh", Driver: "github", With: lib.Vars{
+					"owner": "cappuccinotm", "name": "dastracker",
+					"user": `{{ env "GITHUB_USER" }}`, "access_token": `{{ env "GITHUB_TOKEN" }}`,
+				}},
+				{Name: "rpc", Driver: "rpc", With: lib.Vars{"address": "localhost:9000"}},
+			},
+			[]store.Trigger{{Name: "trigger", Tracker: "gh", With: lib.Vars{"events": "issues"}}},
+			[]store.Job{{Name: "job", TriggerName: "trigger", Actions: store.Sequence{
+				store.If{Condition: `env("MODE") == "sync" && Update.Fields["status"] != ""`, Actions: store.Sequence{
+					store.Action{Name: "gh/UpdateOrCreateIssue", With: lib.Vars{"title": "{{ .Update.Title }}"}},
+				}},
+				store.Action{Name: "rpc/AnyMethod"},
+			}}},
+		), nil)
+		require.NoError(t, err)
+		assert.Empty(t, issues)
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		issues, err := Validate(context.Background(), prepareFlow(
+			[]store.Tracker{
+				{Name: "gh", Driver: "github", With: lib.Vars{"owner": "cappuccinotm", "name": "{{ .Update"}},
+				{Name: "jira", Driver: "jira"},
+				{Name: "rpc", Driver: "rpc"},
+			},
+			[]store.Trigger{{Name: "trigger", Tracker: "gh", With: lib.Vars{"events": "{{ unknown }}"}}},
+			[]store.Job{{Name: "job", TriggerName: "trigger", Actions: store.Sequence{
+				store.If{Condition: `Update.Titel == ""`, Actions: store.Sequence{
+					store.Action{Name: "gh/DeleteIssue", With: lib.Vars{"title": "{{ .Update.Title"}},
+				}},
+			}}},
+		), nil)
+		require.NoError(t, err)
+
+		where := make([]string, 0, len(issues))
+		for _, issue := range issues {
+			where = append(where, issue.Where)
+		}
+		assert.Equal(t, []string{
+			`tracker "gh"`, `tracker "gh"`, `tracker "gh"`,
+			`tracker "jira"`,
+			`tracker "rpc"`,
+			`trigger "trigger"`,
+			`job "job", step 1`,
+			`job "job", step 1.1`, `job "job", step 1.1`,
+		}, where)
+
+		assert.Equal(t, `required variable "user" is not set`, issues[0].Msg)
+		assert.Equal(t, `required variable "access_token" is not set`, issues[1].Msg)
+		assert.Contains(t, issues[2].Msg, `parse "name" variable`)
+		assert.Equal(t, `unsupported driver "jira"`, issues[3].Msg)
+		assert.Equal(t, `one of variables ["address" "command"] must be set`, issues[4].Msg)
+		assert.Contains(t, issues[5].Msg, `parse "events" variable`)
+		assert.Contains(t, issues[6].Msg, `compile expression`)
+		assert.Contains(t, issues[7].Msg, `parse "title" variable`)
+		assert.Equal(t, `method "DeleteIssue" is not supported by tracker "gh", `+
+			`supported methods: UpdateOrCreateIssue`, issues[8].Msg)
+	})
+}
+
+func TestValidatePlugins(t *testing.T) {
+	plugins := map[string]lib.Description{"rpc": {
+		Methods: []lib.Method{
+			{Name: "Create", Vars: []lib.Var{
+				{Name: "title", Type: lib.TypeString, Required: true},
+				{Name: "labels", Type: lib.TypeList},
+				{Name: "estimate", Type: lib.TypeNumber},
+			}},
+			{Name: "Close"},
+		},
+	}}
+
+	flow := prepareFlow(
+		[]store.Tracker{{Name: "rpc", Driver: "rpc"}, {Name: "legacy", Driver: "rpc"}},
+		[]store.Trigger{{Name: "trigger", Tracker: "rpc"}, {Name: "legacy", Tracker: "legacy"}},
+		[]store.Job{{Name: "job", TriggerName: "trigger", Actions: store.Sequence{
+			store.Action{Name: "rpc/Create", With: lib.Vars{
+				"title": "{{ .Update.Title }}", "labels": []interface{}{"bug"}, "estimate": "{{ .Update.Fields.estimate }}",
+			}},
+			store.If{Condition: "true", Actions: store.Sequence{
+				store.Action{Name: "rpc/Create", With: lib.Vars{"labels": "bug", "estimate": true}},
+			}},
+			store.Action{Name: "rpc/Delete"},
+			store.Action{Name: "legacy/Anything"},
+		}}},
+	)
+
+	issues, err := ValidatePlugins(context.Background(), flow, plugins)
+	require.NoError(t, err)
+	assert.Equal(t, []Issue{
+		{Where: `trigger "trigger"`, Msg: `tracker "rpc" doesn't support subscriptions`},
+		{Where: `job "job", step 2.1`, Msg: `required variable "title" is not set`},
+		{Where: `job "job", step 2.1`, Msg: `variable "estimate" must be of type number, got bool`},
+		{Where: `job "job", step 3`, Msg: `method "Delete" is not provided by plugin "rpc", ` +
+			`available methods: Create, Close`},
+	}, issues)
+
+	issues, err = ValidatePlugins(context.Background(), flow, nil)
+	require.NoError(t, err)
+	assert.Empty(t, issues, "nothing to check without descriptions")
+}
+
+func TestLint(t *testing.T) {
+	t.Run("clean configuration", func(t *testing.T) {
+		issues, err := Lint(context.Background(), prepareFlow(
+			[]store.Tracker{{Name: "gh"}, {Name: "rpc"}},
+			[]store.Trigger{{Name: "trigger", Tracker: "gh"}},
+			[]store.Job{{Name: "job", TriggerName: "trigger", Actions: store.Sequence{
+				store.Action{Name: "rpc/Notify", Detached: true},
+				store.Action{Name: "gh/Update", With: lib.Vars{"title": "{{ .Ticket.Variations.gh.Title }}"}},
+			}}},
+		))
+		require.NoError(t, err)
+		assert.Empty(t, issues)
+	})
+
+	t.Run("suspicious configuration", func(t *testing.T) {
+		issues, err := Lint(context.Background(), prepareFlow(
+			[]store.Tracker{{Name: "gh"}, {Name: "rpc"}, {Name: "unused"}, {Name: "gh"}},
+			[]store.Trigger{{Name: "trigger", Tracker: "gh"}, {Name: "unused"}},
+			[]store.Job{
+				{Name: "job", TriggerName: "trigger", Actions: store.Sequence{
+					store.Action{Name: "rpc/Create", Detached: true},
+					store.If{Condition: "true", Actions: store.Sequence{
+						store.Action{Name: "gh/Update", With: lib.Vars{
+							"v1": "{{ .Ticket.Variations.rpc.ID }}",
+							"v2": `{{ (index .Ticket.Variations "rpc").Title }}`,
+						}},
+						store.Action{Name: "rpc/Update"},
+					}},
+				}},
+				{Name: "job", TriggerName: "trigger", Actions: store.Sequence{
+					store.If{Condition: "1 > 2", Actions: store.Sequence{store.Action{Name: "gh/Update"}}},
+					store.If{Condition: `Update.Title == ""`},
+				}},
+			},
+		))
+		require.NoError(t, err)
+
+		strs := make([]string, 0, len(issues))
+		for _, issue := range issues {
+			strs = append(strs, issue.String())
+		}
+		assert.Equal(t, []string{
+			`tracker "unused": tracker is not used by any trigger or action`,
+			`tracker "gh": duplicate name`,
+			`trigger "unused": trigger is not used by any job`,
+			`job "job", step 2.1: variables refer to the task in "rpc", that is changed by the detached action "rpc/Create"`,
+			`job "job", step 2.2: action calls the task, that is changed by the detached action "rpc/Create"`,
+			`job "job", step 1: condition is always false`,
+			`job "job": job has no reachable actions`,
+			`job "job": duplicate name`,
+		}, strs)
+	})
+}
+
+func TestVariationRefs(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c", "d"}, variationRefs(lib.Vars{
+		"v1": "{{ .Ticket.Variations.a.ID }}",
+		"v2": `{{ if .Update.Title }}{{ (index .Ticket.Variations "b").Title }}{{ end }}`,
+		"v3": `{{ (.Ticket.Variations.Get "c").ID }}`,
+		"v4": `{{ with .Ticket.Variations.d }}{{ .ID }}{{ end }}`,
+		"v5": `{{ .Ticket.ID }} {{ .Update.Title }}`,
+		"v6": `{{ invalid`,
+	}))
+}
+
+func prepareFlow(trackers []store.Tracker, triggers []store.Trigger, jobs []store.Job) engine.Flow {
+	return &engine.FlowMock{
+		ListTrackersFunc: func(context.Context) ([]store.Tracker, error) { return trackers, nil },
+		ListTriggersFunc: func(context.Context) ([]store.Trigger, error) { return triggers, nil },
+		ListJobsFunc:     func(context.Context) ([]store.Job, error) { return jobs, nil },
+	}
+}
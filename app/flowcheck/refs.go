@@ -0,0 +1,143 @@
+package flowcheck
+
+import (
+	"sort"
+	"text/template/parse"
+
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/lib"
+)
+
+// variationRefs returns the names of trackers, whose tasks are referred
+// by the templates of variables either as .Ticket.Variations.<tracker>
+// or via index and Get functions. Invalid templates are skipped, as
+// they are reported by Validate.
+func variationRefs(vars lib.Vars) []string {
+	refs := map[string]struct{}{}
+	for _, v := range vars {
+		for _, s := range stringLeaves(v) {
+			tmpl, err := store.ParseTemplate(s)
+			if err != nil || tmpl.Tree == nil {
+				continue
+			}
+			walkNodes(tmpl.Tree.Root, func(n parse.Node) {
+				if trk, ok := variationRef(n); ok {
+					refs[trk] = struct{}{}
+				}
+			})
+		}
+	}
+
+	res := make([]string, 0, len(refs))
+	for trk := range refs {
+		res = append(res, trk)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// stringLeaves returns all strings in the value, including
+// the ones in nested lists and maps.
+func stringLeaves(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var res []string
+		for _, vv := range v {
+			res = append(res, stringLeaves(vv)...)
+		}
+		return res
+	case map[string]interface{}:
+		var res []string
+		for _, vv := range v {
+			res = append(res, stringLeaves(vv)...)
+		}
+		return res
+	default:
+		return nil
+	}
+}
+
+// variationRef checks whether the node refers to the task of the tracker.
+func variationRef(n parse.Node) (string, bool) {
+	switch n := n.(type) {
+	case *parse.FieldNode:
+		// .Ticket.Variations.tracker
+		if len(n.Ident) > 2 && isVariations(n.Ident[:2]) && n.Ident[2] != "Get" {
+			return n.Ident[2], true
+		}
+	case *parse.CommandNode:
+		if len(n.Args) < 2 {
+			return "", false
+		}
+		// index .Ticket.Variations "tracker"
+		if id, ok := n.Args[0].(*parse.IdentifierNode); ok && id.Ident == "index" && len(n.Args) > 2 {
+			if f, ok := n.Args[1].(*parse.FieldNode); ok && isVariations(f.Ident) {
+				if s, ok := n.Args[2].(*parse.StringNode); ok {
+					return s.Text, true
+				}
+			}
+		}
+		// .Ticket.Variations.Get "tracker"
+		if f, ok := n.Args[0].(*parse.FieldNode); ok && len(f.Ident) == 3 &&
+			isVariations(f.Ident[:2]) && f.Ident[2] == "Get" {
+			if s, ok := n.Args[1].(*parse.StringNode); ok {
+				return s.Text, true
+			}
+		}
+	}
+	return "", false
+}
+
+func isVariations(ident []string) bool {
+	return len(ident) == 2 && ident[0] == "Ticket" && ident[1] == "Variations"
+}
+
+// walkNodes calls fn for each node of the template tree.
+func walkNodes(n parse.Node, fn func(parse.Node)) {
+	if n == nil {
+		return
+	}
+
+	fn(n)
+
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, nn := range n.Nodes {
+			walkNodes(nn, fn)
+		}
+	case *parse.ActionNode:
+		walkNodes(n.Pipe, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			walkNodes(cmd, fn)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			walkNodes(arg, fn)
+		}
+	case *parse.ChainNode:
+		walkNodes(n.Node, fn)
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		walkNodes(n.Pipe, fn)
+	}
+}
+
+func walkBranch(n *parse.BranchNode, fn func(parse.Node)) {
+	walkNodes(n.Pipe, fn)
+	walkNodes(n.List, fn)
+	walkNodes(n.ElseList, fn)
+}
@@ -0,0 +1,48 @@
+package flowtest
+
+import "strings"
+
+// diff returns the line-by-line difference between the expected and actual
+// texts, lines missing in actual are prefixed with "-", unexpected lines
+// are prefixed with "+". Results are small, so the plain LCS is enough.
+func diff(expected, actual string) string {
+	exp := strings.Split(strings.TrimSuffix(expected, "\n"), "\n")
+	act := strings.Split(strings.TrimSuffix(actual, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of exp[i:] and act[j:]
+	lcs := make([][]int, len(exp)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(act)+1)
+	}
+	for i := len(exp) - 1; i >= 0; i-- {
+		for j := len(act) - 1; j >= 0; j-- {
+			switch {
+			case exp[i] == act[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	sb := &strings.Builder{}
+	i, j := 0, 0
+	for i < len(exp) || j < len(act) {
+		switch {
+		case i < len(exp) && j < len(act) && exp[i] == act[j]:
+			sb.WriteString("  " + exp[i] + "\n")
+			i++
+			j++
+		case j == len(act) || (i < len(exp) && lcs[i+1][j] >= lcs[i][j+1]):
+			sb.WriteString("- " + exp[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+ " + act[j] + "\n")
+			j++
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,238 @@
+// Package flowtest runs the flow configuration offline over the recorded
+// updates with fake trackers, so that the changes of the rules might be
+// reviewed without any live tracker.
+package flowtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/app/store/engine/memory"
+	"github.com/cappuccinotm/dastracker/app/store/service"
+	"github.com/cappuccinotm/dastracker/app/tracker"
+	"github.com/cappuccinotm/dastracker/lib"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+)
+
+// goldenSuffix is appended to the name of the fixture without
+// extension to make the name of its golden file.
+const goldenSuffix = ".golden.json"
+
+// Fixture describes the recorded update and the state of the storage
+// at the moment when the update is received.
+type Fixture struct {
+	Trigger string         `json:"trigger"`
+	Update  store.Update   `json:"update"`
+	Tickets []store.Ticket `json:"tickets,omitempty"`
+	// Responses are returned by fake trackers, map[action name]task.
+	// If the response is not specified, the task with the requested
+	// or generated ID is returned.
+	Responses map[string]store.Task `json:"responses,omitempty"`
+}
+
+// Result describes the outcome of the fixture.
+type Result struct {
+	Jobs    []JobResult    `json:"jobs"`
+	Calls   []Call         `json:"calls"`
+	Tickets []store.Ticket `json:"tickets"`
+}
+
+// JobResult describes the outcome of a single job.
+type JobResult struct {
+	Name   string `json:"name"`
+	DryRun bool   `json:"dry_run,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Call describes the request to the fake tracker.
+type Call struct {
+	Tracker string   `json:"tracker"`
+	Method  string   `json:"method"`
+	TaskID  string   `json:"task_id,omitempty"`
+	Vars    lib.Vars `json:"vars,omitempty"`
+}
+
+// Report describes the comparison of the result of the fixture with its golden file.
+type Report struct {
+	Fixture string
+	Diff    string // empty if the result matches the golden file
+	Updated bool   // golden file has been written
+}
+
+// Runner runs fixtures over the flow.
+type Runner struct {
+	Flow engine.Flow
+	Log  logx.Logger
+}
+
+// Run feeds the update of the fixture to all jobs, subscribed to its trigger,
+// one by one in order of their declaration.
+func (r *Runner) Run(ctx context.Context, f Fixture) (Result, error) {
+	if f.Update.ReceivedFrom.Empty() {
+		return Result{}, fmt.Errorf("update must contain the locator of the task")
+	}
+
+	trackers, err := r.Flow.ListTrackers(ctx)
+	if err != nil {
+		return Result{}, fmt.Errorf("list trackers: %w", err)
+	}
+
+	res := Result{Calls: []Call{}, Jobs: []JobResult{}}
+
+	fakes := map[string]tracker.Interface{}
+	for _, trk := range trackers {
+		fakes[trk.Name] = &fakeTracker{name: trk.Name, responses: f.Responses, calls: &res.Calls}
+	}
+
+	tickets := &seqTickets{Tickets: memory.NewTickets()}
+	for _, tkt := range f.Tickets {
+		if err = tickets.Update(ctx, tkt); err != nil {
+			return Result{}, fmt.Errorf("put ticket %s: %w", tkt.ID, err)
+		}
+	}
+
+	actor := &service.Actor{Trackers: fakes, TicketsStore: tickets, Flow: r.Flow, Log: r.Log}
+
+	jobs, err := r.Flow.ListSubscribedJobs(ctx, f.Trigger)
+	if err != nil {
+		return Result{}, fmt.Errorf("list jobs of trigger %q: %w", f.Trigger, err)
+	}
+
+	for _, job := range jobs {
+		run, err := actor.RunJob(ctx, job.Name, f.Update, false)
+		if err != nil {
+			return Result{}, fmt.Errorf("run job %q: %w", job.Name, err)
+		}
+		res.Jobs = append(res.Jobs, JobResult{Name: job.Name, DryRun: run.DryRun, Error: run.Error})
+	}
+
+	if res.Tickets, err = tickets.List(ctx, engine.ListRequest{}); err != nil {
+		return Result{}, fmt.Errorf("list tickets: %w", err)
+	}
+
+	if res.Tickets == nil {
+		res.Tickets = []store.Ticket{}
+	}
+
+	return res, nil
+}
+
+// Check runs the fixtures, located by the path, and compares their results
+// with golden files. Path might point either to a single fixture or to
+// a directory with fixtures. If update is set, golden files are rewritten.
+func (r *Runner) Check(ctx context.Context, path string, update bool) ([]Report, error) {
+	files, err := fixtureFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("find fixtures: %w", err)
+	}
+
+	reports := make([]Report, 0, len(files))
+	for _, file := range files {
+		report, err := r.check(ctx, file, update)
+		if err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", file, err)
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func (r *Runner) check(ctx context.Context, file string, update bool) (Report, error) {
+	bts, err := os.ReadFile(file) //nolint:gosec // path is set by the user
+	if err != nil {
+		return Report{}, fmt.Errorf("read fixture: %w", err)
+	}
+
+	var f Fixture
+	if err = json.Unmarshal(bts, &f); err != nil {
+		return Report{}, fmt.Errorf("unmarshal fixture: %w", err)
+	}
+
+	res, err := r.Run(ctx, f)
+	if err != nil {
+		return Report{}, fmt.Errorf("run: %w", err)
+	}
+
+	actual, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return Report{}, fmt.Errorf("marshal result: %w", err)
+	}
+	actual = append(actual, '\n')
+
+	goldenFile := strings.TrimSuffix(file, filepath.Ext(file)) + goldenSuffix
+	report := Report{Fixture: file}
+
+	if update {
+		if err = os.WriteFile(goldenFile, actual, 0o600); err != nil {
+			return Report{}, fmt.Errorf("write golden file: %w", err)
+		}
+		report.Updated = true
+		return report, nil
+	}
+
+	expected, err := os.ReadFile(goldenFile) //nolint:gosec // path is set by the user
+	if err != nil {
+		return Report{}, fmt.Errorf("read golden file: %w", err)
+	}
+
+	if !bytes.Equal(expected, actual) {
+		report.Diff = diff(string(expected), string(actual))
+	}
+
+	return report, nil
+}
+
+// fixtureFiles returns the list of fixture files, sorted by name.
+func fixtureFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(files))
+	for _, file := range files {
+		if !strings.HasSuffix(file, goldenSuffix) {
+			res = append(res, file)
+		}
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// seqTickets generates sequential IDs for the created tickets,
+// so that the results are reproducible.
+type seqTickets struct {
+	*memory.Tickets
+	seq int
+}
+
+// Create creates ticket with the next ID in the sequence.
+func (s *seqTickets) Create(ctx context.Context, ticket store.Ticket) (string, error) {
+	s.seq++
+	ticket.ID = fmt.Sprintf("new-ticket-%d", s.seq)
+
+	if err := s.Tickets.Update(ctx, ticket); err != nil {
+		return "", fmt.Errorf("put ticket into storage: %w", err)
+	}
+
+	return ticket.ID, nil
+}
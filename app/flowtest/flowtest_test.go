@@ -0,0 +1,115 @@
+package flowtest
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine/static"
+	"github.com/cappuccinotm/dastracker/lib"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_Run(t *testing.T) {
+	svc := prepareRunner(t)
+
+	res, err := svc.Run(context.Background(), Fixture{
+		Trigger: "issue_updated",
+		Update: store.Update{
+			ReceivedFrom: store.Locator{Tracker: "github", ID: "42"},
+			Content:      store.Content{Title: "title", Fields: store.TicketFields{"status": "open"}},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, Result{
+		Jobs:  []JobResult{{Name: "mirror issue"}, {Name: "close issue"}},
+		Calls: []Call{{Tracker: "rpc", Method: "Upsert", Vars: lib.Vars{"title": "title", "issue": "42"}}},
+		Tickets: []store.Ticket{
+			{ID: "new-ticket-1", Variations: store.Variations{
+				"github": {ID: "42", Content: store.Content{Title: "title", Fields: store.TicketFields{"status": "open"}}},
+				"rpc":    {ID: "new-task-1"},
+			}},
+			{ID: "new-ticket-2", Variations: store.Variations{
+				"github": {ID: "42", Content: store.Content{Title: "title", Fields: store.TicketFields{"status": "open"}}},
+			}},
+		},
+	}, res)
+
+	_, err = svc.Run(context.Background(), Fixture{Trigger: "issue_updated"})
+	assert.Error(t, err, "update without locator must be refused")
+
+	_, err = svc.Run(context.Background(), Fixture{
+		Trigger: "unknown",
+		Update:  store.Update{ReceivedFrom: store.Locator{Tracker: "github", ID: "42"}},
+	})
+	assert.Error(t, err)
+}
+
+func TestRunner_Check(t *testing.T) {
+	svc := prepareRunner(t)
+
+	reports, err := svc.Check(context.Background(), "testdata/fixtures", false)
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	for _, r := range reports {
+		assert.Empty(t, r.Diff, "fixture %s doesn't match the golden file", r.Fixture)
+		assert.False(t, r.Updated)
+	}
+
+	t.Run("mismatch and update", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "test_dastracker")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		bts, err := os.ReadFile("testdata/fixtures/new_issue.json")
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "new_issue.json"), bts, 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "new_issue.golden.json"), []byte("{}\n"), 0o600))
+
+		reports, err := svc.Check(context.Background(), filepath.Join(dir, "new_issue.json"), false)
+		require.NoError(t, err)
+		require.Len(t, reports, 1)
+		assert.Contains(t, reports[0].Diff, "- {}")
+		assert.Contains(t, reports[0].Diff, `+   "jobs": [`)
+
+		reports, err = svc.Check(context.Background(), dir, true)
+		require.NoError(t, err)
+		require.Len(t, reports, 1)
+		assert.True(t, reports[0].Updated)
+
+		reports, err = svc.Check(context.Background(), dir, false)
+		require.NoError(t, err)
+		require.Len(t, reports, 1)
+		assert.Empty(t, reports[0].Diff)
+	})
+
+	t.Run("golden file is missing", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "test_dastracker")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		bts, err := os.ReadFile("testdata/fixtures/new_issue.json")
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "new_issue.json"), bts, 0o600))
+
+		_, err = svc.Check(context.Background(), dir, false)
+		assert.Error(t, err)
+	})
+}
+
+func TestDiff(t *testing.T) {
+	assert.Equal(t, "  a\n- b\n+ c\n  d\n+ e\n", diff("a\nb\nd\n", "a\nc\nd\ne\n"))
+	assert.Equal(t, "  a\n", diff("a\n", "a\n"))
+}
+
+func prepareRunner(t *testing.T) *Runner {
+	flow, err := static.NewStatic("testdata/flow.yml")
+	require.NoError(t, err)
+	return &Runner{Flow: flow, Log: logx.Nop()}
+}
@@ -0,0 +1,59 @@
+package flowtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/tracker"
+)
+
+// fakeTracker records calls and returns the predefined responses.
+// Jobs are run one by one, thus it is not thread-safe.
+type fakeTracker struct {
+	name      string
+	responses map[string]store.Task // map[action name]task
+	calls     *[]Call
+	created   int
+}
+
+// Name returns the name of the tracker.
+func (f *fakeTracker) Name() string { return f.name }
+
+// Call records the request and returns the predefined response.
+func (f *fakeTracker) Call(_ context.Context, req tracker.Request) (tracker.Response, error) {
+	*f.calls = append(*f.calls, Call{Tracker: f.name, Method: req.Method, TaskID: req.TaskID, Vars: req.Vars})
+
+	if task, ok := f.responses[f.name+"/"+req.Method]; ok {
+		return tracker.Response{Task: task}, nil
+	}
+
+	if req.TaskID != "" {
+		return tracker.Response{Task: store.Task{ID: req.TaskID}}, nil
+	}
+
+	f.created++
+	return tracker.Response{Task: store.Task{ID: fmt.Sprintf("new-task-%d", f.created)}}, nil
+}
+
+// Subscribe is not supported in tests.
+func (f *fakeTracker) Subscribe(context.Context, tracker.SubscribeReq) (tracker.SubscribeResp, error) {
+	return tracker.SubscribeResp{}, errors.New("subscriptions are not supported by fake tracker")
+}
+
+// Unsubscribe is not supported in tests.
+func (f *fakeTracker) Unsubscribe(context.Context, tracker.UnsubscribeReq) error {
+	return errors.New("subscriptions are not supported by fake tracker")
+}
+
+// HandleWebhook is not supported in tests.
+func (f *fakeTracker) HandleWebhook(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Listen is not supported in tests.
+func (f *fakeTracker) Listen(context.Context, tracker.Handler) error {
+	return errors.New("listening is not supported by fake tracker")
+}
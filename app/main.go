@@ -7,6 +7,7 @@ import (
 
 	"errors"
 	"github.com/cappuccinotm/dastracker/app/cmd"
+	"github.com/cappuccinotm/dastracker/app/secrets"
 	"github.com/cappuccinotm/dastracker/pkg/logx"
 	"github.com/hashicorp/logutils"
 	"github.com/jessevdk/go-flags"
@@ -14,8 +15,14 @@ import (
 
 // Opts describes cli commands, arguments and flags of the application.
 type Opts struct {
-	Run   cmd.Run `command:"run"`
-	Debug bool    `long:"dbg" env:"DEBUG" description:"turn on debug mode"`
+	Run      cmd.Run      `command:"run"`
+	Store    cmd.Store    `command:"store" description:"maintain the storage"`
+	Runs     cmd.Runs     `command:"runs" description:"inspect the history of job runs"`
+	Test     cmd.Test     `command:"test" description:"check the flow over the recorded updates"`
+	Validate cmd.Validate `command:"validate" description:"check that the configuration might be run"`
+	Lint     cmd.Lint     `command:"lint" description:"look for suspicious constructions in the configuration"`
+	Secrets  cmd.Secrets  `command:"secrets" description:"maintain the encrypted file with secrets"`
+	Debug    bool         `long:"dbg" env:"DEBUG" description:"turn on debug mode"`
 }
 
 var version = "unknown"
@@ -26,13 +33,15 @@ func main() {
 	var opts Opts
 	p := flags.NewParser(&opts, flags.Default)
 	p.CommandHandler = func(command flags.Commander, args []string) error {
-		logger := setupLog(opts.Debug)
+		redactor := &secrets.Redactor{}
+		logger := setupLog(opts.Debug, redactor)
 
 		// commands implement CommonOptionsCommander to allow passing set of extra options defined for all commands
 		c := command.(cmd.CommonOptionsCommander)
 		c.SetCommon(cmd.CommonOpts{
-			Version: version,
-			Logger:  logger,
+			Version:  version,
+			Logger:   logger,
+			Redactor: redactor,
 		})
 
 		if err := command.Execute(args); err != nil && !errors.Is(err, cmd.ErrInterrupted) {
@@ -51,11 +60,11 @@ func main() {
 	}
 }
 
-func setupLog(dbg bool) logx.Logger {
+func setupLog(dbg bool, redactor *secrets.Redactor) logx.Logger {
 	filter := &logutils.LevelFilter{
 		Levels:   []logutils.LogLevel{"DEBUG", "INFO", "WARN", "ERROR"},
 		MinLevel: "INFO",
-		Writer:   os.Stdout,
+		Writer:   redactor.Writer(os.Stdout),
 	}
 
 	logFlags := log.Ldate | log.Ltime
@@ -0,0 +1,346 @@
+// Package rest provides the admin HTTP API to inspect and control
+// the running instance of dastracker.
+package rest
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+	"github.com/gorilla/mux"
+)
+
+// defaultLimit is the number of entries returned by list
+// endpoints, if the limit is not specified in the request.
+const defaultLimit = 50
+
+// runJobTimeout limits the time of the job, run manually.
+const runJobTimeout = 5 * time.Minute
+
+// JobRunner runs jobs manually.
+type JobRunner interface {
+	RunJob(ctx context.Context, jobName string, upd store.Update, dryRun bool) (store.JobRun, error)
+}
+
+// Admin serves the admin API. All endpoints require the bearer token.
+type Admin struct {
+	Addr          string
+	Token         string
+	Flow          engine.Flow
+	Tickets       engine.Tickets
+	Subscriptions engine.Subscriptions
+	Runs          engine.Runs
+	Runner        JobRunner
+	Logger        logx.Logger
+}
+
+// Listen starts the admin server. Blocking call.
+func (a *Admin) Listen(ctx context.Context) error {
+	if a.Token == "" {
+		return errors.New("admin token is not set")
+	}
+
+	srv := &http.Server{Addr: a.Addr, Handler: a.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		if err := srv.Shutdown(context.Background()); err != nil {
+			a.Logger.Printf("[WARN] failed to shutdown admin server: %v", err)
+		}
+	}()
+
+	a.Logger.Printf("[INFO] starting admin server at %s", a.Addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Handler returns the handler with all routes of the admin API.
+func (a *Admin) Handler() http.Handler {
+	r := mux.NewRouter()
+	r.Use(a.authMiddleware)
+
+	api := r.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/trackers", a.listTrackers).Methods(http.MethodGet)
+	api.HandleFunc("/triggers", a.listTriggers).Methods(http.MethodGet)
+	api.HandleFunc("/jobs", a.listJobs).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/{name}/run", a.runJob).Methods(http.MethodPost)
+	api.HandleFunc("/subscriptions", a.listSubscriptions).Methods(http.MethodGet)
+	api.HandleFunc("/tickets", a.listTickets).Methods(http.MethodGet)
+	api.HandleFunc("/tickets/{id}", a.getTicket).Methods(http.MethodGet)
+	api.HandleFunc("/tickets/{tracker}/{task_id}", a.getTicket).Methods(http.MethodGet)
+	api.HandleFunc("/runs", a.listRuns).Methods(http.MethodGet)
+	api.HandleFunc("/runs/{id}", a.getRun).Methods(http.MethodGet)
+
+	return r
+}
+
+// GET /api/v1/trackers
+func (a *Admin) listTrackers(w http.ResponseWriter, r *http.Request) {
+	trackers, err := a.Flow.ListTrackers(r.Context())
+	if err != nil {
+		a.renderError(w, r, fmt.Errorf("list trackers: %w", err))
+		return
+	}
+	a.render(w, r, http.StatusOK, trackers)
+}
+
+// GET /api/v1/triggers
+func (a *Admin) listTriggers(w http.ResponseWriter, r *http.Request) {
+	triggers, err := a.Flow.ListTriggers(r.Context())
+	if err != nil {
+		a.renderError(w, r, fmt.Errorf("list triggers: %w", err))
+		return
+	}
+	a.render(w, r, http.StatusOK, triggers)
+}
+
+// GET /api/v1/jobs
+func (a *Admin) listJobs(w http.ResponseWriter, r *http.Request) {
+	jobs, err := a.Flow.ListJobs(r.Context())
+	if err != nil {
+		a.renderError(w, r, fmt.Errorf("list jobs: %w", err))
+		return
+	}
+	a.render(w, r, http.StatusOK, jobs)
+}
+
+// POST /api/v1/jobs/{name}/run?dry_run=, body - store.Update
+func (a *Admin) runJob(w http.ResponseWriter, r *http.Request) {
+	var dryRun bool
+	if s := r.URL.Query().Get("dry_run"); s != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(s); err != nil {
+			a.renderError(w, r, errBadRequest{fmt.Errorf("parse dry run flag: %w", err)})
+			return
+		}
+	}
+
+	var upd store.Update
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		a.renderError(w, r, errBadRequest{fmt.Errorf("decode update: %w", err)})
+		return
+	}
+
+	if upd.ReceivedFrom.Empty() {
+		a.renderError(w, r, errBadRequest{errors.New("update must contain the locator of the task")})
+		return
+	}
+
+	// the job must not be interrupted halfway, if the client disconnects,
+	// thus it is run under its own context, detached from the request
+	ctx, cancel := context.WithTimeout(context.Background(), runJobTimeout)
+	defer cancel()
+
+	run, err := a.Runner.RunJob(ctx, mux.Vars(r)["name"], upd, dryRun)
+	if err != nil {
+		a.renderError(w, r, fmt.Errorf("run job: %w", err))
+		return
+	}
+	a.render(w, r, http.StatusOK, run)
+}
+
+// GET /api/v1/subscriptions?tracker=
+func (a *Admin) listSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := a.Subscriptions.List(r.Context(), r.URL.Query().Get("tracker"))
+	if err != nil {
+		a.renderError(w, r, fmt.Errorf("list subscriptions: %w", err))
+		return
+	}
+	a.render(w, r, http.StatusOK, subs)
+}
+
+// GET /api/v1/tickets?tracker=&field=name:value&updated_since=&offset=&limit=
+func (a *Admin) listTickets(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := engine.ListRequest{Tracker: q.Get("tracker")}
+
+	for _, f := range q["field"] {
+		name, value, ok := cut(f, ":")
+		if !ok {
+			a.renderError(w, r, errBadRequest{fmt.Errorf("field filter %q must be in form of name:value", f)})
+			return
+		}
+		if req.Fields == nil {
+			req.Fields = store.TicketFields{}
+		}
+		req.Fields[name] = value
+	}
+
+	var err error
+	if req.UpdatedSince, err = parseTime(q.Get("updated_since")); err != nil {
+		a.renderError(w, r, err)
+		return
+	}
+
+	if req.Offset, req.Limit, err = parsePage(q.Get("offset"), q.Get("limit")); err != nil {
+		a.renderError(w, r, err)
+		return
+	}
+
+	tickets, err := a.Tickets.List(r.Context(), req)
+	if err != nil {
+		a.renderError(w, r, fmt.Errorf("list tickets: %w", err))
+		return
+	}
+	a.render(w, r, http.StatusOK, tickets)
+}
+
+// GET /api/v1/tickets/{id} or /api/v1/tickets/{tracker}/{task_id}
+func (a *Admin) getTicket(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	req := engine.GetRequest{
+		TicketID: vars["id"],
+		Locator:  store.Locator{Tracker: vars["tracker"], ID: vars["task_id"]},
+	}
+
+	ticket, err := a.Tickets.Get(r.Context(), req)
+	if err != nil {
+		a.renderError(w, r, fmt.Errorf("get ticket: %w", err))
+		return
+	}
+	a.render(w, r, http.StatusOK, ticket)
+}
+
+// GET /api/v1/runs?job=&trigger=&tracker=&task_id=&ticket_id=&failed=&since=&offset=&limit=
+func (a *Admin) listRuns(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := engine.ListRunsRequest{
+		JobName:     q.Get("job"),
+		TriggerName: q.Get("trigger"),
+		Update:      store.Locator{Tracker: q.Get("tracker"), ID: q.Get("task_id")},
+		TicketID:    q.Get("ticket_id"),
+	}
+
+	var err error
+	if s := q.Get("failed"); s != "" {
+		if req.OnlyFailed, err = strconv.ParseBool(s); err != nil {
+			a.renderError(w, r, errBadRequest{fmt.Errorf("parse failed flag: %w", err)})
+			return
+		}
+	}
+
+	if req.Since, err = parseTime(q.Get("since")); err != nil {
+		a.renderError(w, r, err)
+		return
+	}
+
+	if req.Offset, req.Limit, err = parsePage(q.Get("offset"), q.Get("limit")); err != nil {
+		a.renderError(w, r, err)
+		return
+	}
+
+	runs, err := a.Runs.List(r.Context(), req)
+	if err != nil {
+		a.renderError(w, r, fmt.Errorf("list runs: %w", err))
+		return
+	}
+	a.render(w, r, http.StatusOK, runs)
+}
+
+// GET /api/v1/runs/{id}
+func (a *Admin) getRun(w http.ResponseWriter, r *http.Request) {
+	run, err := a.Runs.Get(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		a.renderError(w, r, fmt.Errorf("get run: %w", err))
+		return
+	}
+	a.render(w, r, http.StatusOK, run)
+}
+
+func (a *Admin) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const prefix = "Bearer "
+		header := r.Header.Get("Authorization")
+		if a.Token == "" || !strings.HasPrefix(header, prefix) ||
+			subtle.ConstantTimeCompare([]byte(header[len(prefix):]), []byte(a.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			a.render(w, r, http.StatusUnauthorized, errResponse{Error: "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// errBadRequest wraps errors, caused by the invalid request.
+type errBadRequest struct{ error }
+
+// Unwrap returns the wrapped error.
+func (e errBadRequest) Unwrap() error { return e.error }
+
+type errResponse struct {
+	Error string `json:"error"`
+}
+
+func (a *Admin) renderError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+
+	var badReq errBadRequest
+	switch {
+	case errors.As(err, &badReq):
+		status = http.StatusBadRequest
+	case errors.Is(err, errs.ErrNotFound):
+		status = http.StatusNotFound
+	default:
+		a.Logger.Printf("[WARN] failed to handle admin request %s %s: %v", r.Method, r.URL.Path, err)
+	}
+
+	a.render(w, r, status, errResponse{Error: err.Error()})
+}
+
+func (a *Admin) render(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		a.Logger.Printf("[WARN] failed to write response to admin request %s %s: %v", r.Method, r.URL.Path, err)
+	}
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, errBadRequest{fmt.Errorf("parse time %q: %w", s, err)}
+	}
+
+	return t, nil
+}
+
+func parsePage(offsetStr, limitStr string) (offset, limit int, err error) {
+	limit = defaultLimit
+
+	if offsetStr != "" {
+		if offset, err = strconv.Atoi(offsetStr); err != nil || offset < 0 {
+			return 0, 0, errBadRequest{fmt.Errorf("invalid offset %q", offsetStr)}
+		}
+	}
+
+	if limitStr != "" {
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit < 0 {
+			return 0, 0, errBadRequest{fmt.Errorf("invalid limit %q", limitStr)}
+		}
+	}
+
+	return offset, limit, nil
+}
+
+// cut is a copy of strings.Cut, which is not available in go1.17.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
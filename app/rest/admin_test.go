@@ -0,0 +1,284 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/app/store/engine/memory"
+	"github.com/cappuccinotm/dastracker/lib"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "secret-token"
+
+func TestAdmin_Auth(t *testing.T) {
+	ts := httptest.NewServer(prepareAdmin(t).Handler())
+	defer ts.Close()
+
+	for _, header := range []string{"", "Bearer wrong", testToken, "Basic " + testToken} {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/jobs", nil)
+		require.NoError(t, err)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, header)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	resp := request(t, ts, http.MethodGet, "/api/v1/jobs", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, resp.Body.Close())
+}
+
+func TestAdmin_Flow(t *testing.T) {
+	ts := httptest.NewServer(prepareAdmin(t).Handler())
+	defer ts.Close()
+
+	var trackers []map[string]interface{}
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/trackers", ""), http.StatusOK, &trackers)
+	assert.Equal(t, []map[string]interface{}{{"name": "tracker", "driver": "github"}}, trackers,
+		"tracker variables must not be exposed")
+
+	var triggers []store.Trigger
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/triggers", ""), http.StatusOK, &triggers)
+	assert.Equal(t, []store.Trigger{{Name: "trigger", Tracker: "tracker", With: lib.Vars{"k": "v"}}}, triggers)
+
+	var jobs []map[string]interface{}
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/jobs", ""), http.StatusOK, &jobs)
+	assert.Equal(t, []map[string]interface{}{{
+		"name": "job",
+		"on":   "trigger",
+		"do": []interface{}{
+			map[string]interface{}{"if": "true", "do": []interface{}{
+				map[string]interface{}{"action": "tracker/method"},
+			}},
+		},
+	}}, jobs)
+}
+
+func TestAdmin_Tickets(t *testing.T) {
+	svc := prepareAdmin(t)
+	ts := httptest.NewServer(svc.Handler())
+	defer ts.Close()
+
+	for _, tkt := range []store.Ticket{
+		{ID: "ticket-1", Variations: store.Variations{"tracker": {ID: "task-1", Content: store.Content{
+			Fields: store.TicketFields{"status": "open"},
+		}}}},
+		{ID: "ticket-2", Variations: store.Variations{"tracker": {ID: "task-2"}}},
+	} {
+		require.NoError(t, svc.Tickets.Update(context.Background(), tkt))
+	}
+
+	var tickets []store.Ticket
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/tickets?field=status:open", ""), http.StatusOK, &tickets)
+	require.Len(t, tickets, 1)
+	assert.Equal(t, "ticket-1", tickets[0].ID)
+
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/tickets?offset=1", ""), http.StatusOK, &tickets)
+	require.Len(t, tickets, 1)
+	assert.Equal(t, "ticket-2", tickets[0].ID)
+
+	var ticket store.Ticket
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/tickets/ticket-2", ""), http.StatusOK, &ticket)
+	assert.Equal(t, "ticket-2", ticket.ID)
+
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/tickets/tracker/task-1", ""), http.StatusOK, &ticket)
+	assert.Equal(t, "ticket-1", ticket.ID)
+
+	var errResp errResponse
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/tickets/unknown", ""), http.StatusNotFound, &errResp)
+	assert.NotEmpty(t, errResp.Error)
+
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/tickets?field=status", ""), http.StatusBadRequest, &errResp)
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/tickets?limit=-1", ""), http.StatusBadRequest, &errResp)
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/tickets?updated_since=yesterday", ""),
+		http.StatusBadRequest, &errResp)
+}
+
+func TestAdmin_Runs(t *testing.T) {
+	svc := prepareAdmin(t)
+	ts := httptest.NewServer(svc.Handler())
+	defer ts.Close()
+
+	base := time.Date(2021, 11, 3, 12, 0, 0, 0, time.UTC)
+	okID, err := svc.Runs.Create(context.Background(), store.JobRun{JobName: "job", StartedAt: base})
+	require.NoError(t, err)
+	failedID, err := svc.Runs.Create(context.Background(), store.JobRun{
+		JobName: "job", StartedAt: base.Add(time.Minute), Error: "failed",
+	})
+	require.NoError(t, err)
+
+	var runs []store.JobRun
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/runs?job=job", ""), http.StatusOK, &runs)
+	require.Len(t, runs, 2)
+	assert.Equal(t, failedID, runs[0].ID)
+	assert.Equal(t, okID, runs[1].ID)
+
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/runs?failed=true", ""), http.StatusOK, &runs)
+	require.Len(t, runs, 1)
+	assert.Equal(t, failedID, runs[0].ID)
+
+	var run store.JobRun
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/runs/"+okID, ""), http.StatusOK, &run)
+	assert.Equal(t, okID, run.ID)
+
+	var errResp errResponse
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/runs/unknown", ""), http.StatusNotFound, &errResp)
+}
+
+func TestAdmin_RunJob(t *testing.T) {
+	svc := prepareAdmin(t)
+	svc.Runner = runnerFunc(func(_ context.Context, jobName string, upd store.Update, dryRun bool) (store.JobRun, error) {
+		if jobName != "job" {
+			return store.JobRun{}, errs.ErrNotFound
+		}
+		assert.Equal(t, store.Update{
+			ReceivedFrom: store.Locator{Tracker: "tracker", ID: "task-id"},
+			Content:      store.Content{Title: "title"},
+		}, upd)
+		return store.JobRun{ID: "run-id", JobName: jobName, Update: upd.ReceivedFrom, DryRun: dryRun}, nil
+	})
+
+	ts := httptest.NewServer(svc.Handler())
+	defer ts.Close()
+
+	body := `{"received_from": {"tracker": "tracker", "id": "task-id"}, "title": "title"}`
+
+	var run store.JobRun
+	decode(t, request(t, ts, http.MethodPost, "/api/v1/jobs/job/run", body), http.StatusOK, &run)
+	assert.Equal(t, "run-id", run.ID)
+	assert.False(t, run.DryRun)
+
+	decode(t, request(t, ts, http.MethodPost, "/api/v1/jobs/job/run?dry_run=true", body), http.StatusOK, &run)
+	assert.True(t, run.DryRun)
+
+	var errResp errResponse
+	decode(t, request(t, ts, http.MethodPost, "/api/v1/jobs/unknown/run", body), http.StatusNotFound, &errResp)
+	decode(t, request(t, ts, http.MethodPost, "/api/v1/jobs/job/run", `{"title": "title"}`),
+		http.StatusBadRequest, &errResp)
+	decode(t, request(t, ts, http.MethodPost, "/api/v1/jobs/job/run", `not a json`), http.StatusBadRequest, &errResp)
+	decode(t, request(t, ts, http.MethodPost, "/api/v1/jobs/job/run?dry_run=maybe", body),
+		http.StatusBadRequest, &errResp)
+}
+
+func TestAdmin_RunJob_ClientGone(t *testing.T) {
+	svc := prepareAdmin(t)
+	started, result := make(chan struct{}), make(chan error, 1)
+	svc.Runner = runnerFunc(func(ctx context.Context, _ string, upd store.Update, _ bool) (store.JobRun, error) {
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok, "job must be limited in time")
+		assert.WithinDuration(t, time.Now().Add(runJobTimeout), deadline, time.Second)
+
+		close(started)
+		select {
+		case <-ctx.Done():
+			result <- ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+			result <- nil
+		}
+		return store.JobRun{}, nil
+	})
+
+	ts := httptest.NewServer(svc.Handler())
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL+"/api/v1/jobs/job/run",
+		strings.NewReader(`{"received_from": {"tracker": "tracker", "id": "task-id"}}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+
+	go func() {
+		<-started
+		cancel()
+	}()
+	_, err = http.DefaultClient.Do(req)
+	require.ErrorIs(t, err, context.Canceled)
+
+	assert.NoError(t, <-result, "job must not be interrupted by the client")
+}
+
+func TestAdmin_Listen(t *testing.T) {
+	svc := prepareAdmin(t)
+	svc.Addr = "127.0.0.1:0"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- svc.Listen(ctx) }()
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err, "graceful shutdown is not an error")
+	case <-time.After(5 * time.Second):
+		t.Fatal("admin server is not stopped")
+	}
+}
+
+type runnerFunc func(ctx context.Context, jobName string, upd store.Update, dryRun bool) (store.JobRun, error)
+
+func (f runnerFunc) RunJob(ctx context.Context, jobName string, upd store.Update, dryRun bool) (store.JobRun, error) {
+	return f(ctx, jobName, upd, dryRun)
+}
+
+func prepareAdmin(t *testing.T) *Admin {
+	flow := &engine.FlowMock{
+		ListTrackersFunc: func(context.Context) ([]store.Tracker, error) {
+			return []store.Tracker{{Name: "tracker", Driver: "github", With: lib.Vars{"token": "secret"}}}, nil
+		},
+		ListTriggersFunc: func(context.Context) ([]store.Trigger, error) {
+			return []store.Trigger{{Name: "trigger", Tracker: "tracker", With: lib.Vars{"k": "v"}}}, nil
+		},
+		ListJobsFunc: func(context.Context) ([]store.Job, error) {
+			return []store.Job{{Name: "job", TriggerName: "trigger", Actions: store.Sequence{
+				store.If{Condition: "true", Actions: store.Sequence{store.Action{Name: "tracker/method"}}},
+			}}}, nil
+		},
+	}
+
+	return &Admin{
+		Token:         testToken,
+		Flow:          flow,
+		Tickets:       memory.NewTickets(),
+		Subscriptions: memory.NewSubscriptions(),
+		Runs:          memory.NewRuns(),
+		Logger:        logx.Nop(),
+	}
+}
+
+func request(t *testing.T, ts *httptest.Server, method, uri, body string) *http.Response {
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, ts.URL+uri, r)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func decode(t *testing.T, resp *http.Response, status int, v interface{}) {
+	defer resp.Body.Close()
+	require.Equal(t, status, resp.StatusCode)
+	assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+}
@@ -0,0 +1,111 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// KeySize is the size of the key to encrypt the file with secrets.
+const KeySize = 32
+
+// NewKey generates a new random key, encoded in base64.
+func NewKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", fmt.Errorf("read random bytes: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Encrypt encrypts the secrets with AES-256-GCM by the base64-encoded key.
+// The result is the base64-encoded nonce followed by the ciphertext of
+// the secrets, serialized to JSON.
+func Encrypt(key string, secrets map[string]string) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, fmt.Errorf("marshal secrets: %w", err)
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("make nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, plain, nil)
+	res := make([]byte, base64.StdEncoding.EncodedLen(len(sealed)))
+	base64.StdEncoding.Encode(res, sealed)
+	return append(res, '\n'), nil
+}
+
+// Decrypt decrypts the secrets, encrypted by Encrypt.
+func Decrypt(key string, data []byte) (Static, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("decode base64: %w", err)
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted data is too short")
+	}
+
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+
+	res := Static{}
+	if err = json.Unmarshal(plain, &res); err != nil {
+		return nil, fmt.Errorf("unmarshal secrets: %w", err)
+	}
+
+	return res, nil
+}
+
+// LoadEncrypted reads and decrypts the file with secrets.
+func LoadEncrypted(path, key string) (Static, error) {
+	bts, err := os.ReadFile(path) //nolint:gosec // path is set by the user
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	return Decrypt(key, bts)
+}
+
+func newAEAD(key string) (cipher.AEAD, error) {
+	k, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("decode key: %w", err)
+	}
+	if len(k) != KeySize {
+		return nil, fmt.Errorf("key must contain %d bytes, got %d", KeySize, len(k))
+	}
+
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, fmt.Errorf("make cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("make gcm: %w", err)
+	}
+
+	return aead, nil
+}
@@ -0,0 +1,47 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncrypt(t *testing.T) {
+	key, err := NewKey()
+	require.NoError(t, err)
+
+	enc, err := Encrypt(key, map[string]string{"token": "value"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(enc), "value")
+
+	file := filepath.Join(t.TempDir(), "secrets.enc")
+	require.NoError(t, os.WriteFile(file, enc, 0o600))
+
+	s, err := LoadEncrypted(file, key)
+	require.NoError(t, err)
+	assert.Equal(t, Static{"token": "value"}, s)
+
+	otherKey, err := NewKey()
+	require.NoError(t, err)
+	_, err = Decrypt(otherKey, enc)
+	assert.Error(t, err, "wrong key")
+
+	_, err = Decrypt(key, []byte("not base64"))
+	assert.Error(t, err)
+
+	_, err = Decrypt(key, []byte(base64.StdEncoding.EncodeToString([]byte("short"))))
+	assert.Error(t, err)
+
+	_, err = Encrypt(base64.StdEncoding.EncodeToString([]byte("short key")), nil)
+	assert.Error(t, err)
+
+	_, err = Encrypt("not base64", nil)
+	assert.Error(t, err)
+
+	_, err = LoadEncrypted(filepath.Join(t.TempDir(), "unknown"), key)
+	assert.Error(t, err)
+}
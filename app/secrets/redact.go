@@ -0,0 +1,140 @@
+package secrets
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cappuccinotm/dastracker/lib"
+)
+
+// mask replaces the values of secrets.
+const mask = "*****"
+
+// Redactor keeps the revealed values of secrets and replaces
+// them in strings. Nil Redactor returns strings as is.
+type Redactor struct {
+	mu       sync.RWMutex
+	values   map[string]struct{}
+	replacer *strings.Replacer
+}
+
+// Add remembers the value to redact.
+func (r *Redactor) Add(value string) {
+	if r == nil || value == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.values[value]; ok {
+		return
+	}
+
+	if r.values == nil {
+		r.values = map[string]struct{}{}
+	}
+	r.values[value] = struct{}{}
+
+	// longer values go first, so that the secret, which contains
+	// another one, is replaced entirely
+	values := make([]string, 0, len(r.values))
+	for v := range r.values {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	pairs := make([]string, 0, len(values)*2)
+	for _, v := range values {
+		pairs = append(pairs, v, mask)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact replaces the known values of secrets in the string.
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.replacer == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
+
+// RedactVars returns the copy of variables with the known values of
+// secrets replaced, including the ones in nested lists and maps.
+func (r *Redactor) RedactVars(vars lib.Vars) lib.Vars {
+	if r == nil || vars == nil {
+		return vars
+	}
+
+	res := make(lib.Vars, len(vars))
+	for k, v := range vars {
+		res[k] = r.redactValue(v)
+	}
+	return res
+}
+
+func (r *Redactor) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.Redact(v)
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, vv := range v {
+			res[i] = r.redactValue(vv)
+		}
+		return res
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for k, vv := range v {
+			res[k] = r.redactValue(vv)
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+// Wrap returns the provider, that remembers each returned value.
+func (r *Redactor) Wrap(p Provider) Provider {
+	return revealing{Provider: p, r: r}
+}
+
+type revealing struct {
+	Provider
+	r *Redactor
+}
+
+// Get returns the value of the secret and remembers it to redact.
+func (p revealing) Get(name string) (string, error) {
+	v, err := p.Provider.Get(name)
+	if err != nil {
+		return "", err
+	}
+	p.r.Add(v)
+	return v, nil
+}
+
+// Writer returns the writer, that redacts the known values of secrets
+// before writing to w. Each call to Write is expected to contain whole
+// lines, as the log package does.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		if _, err := io.WriteString(w, r.Redact(string(p))); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	})
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
@@ -0,0 +1,70 @@
+package secrets
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/cappuccinotm/dastracker/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor_Redact(t *testing.T) {
+	r := &Redactor{}
+	assert.Equal(t, "token value", r.Redact("token value"))
+
+	r.Add("value")
+	r.Add("long value")
+	r.Add("")
+	assert.Equal(t, "token *****, *****", r.Redact("token value, long value"))
+
+	assert.Equal(t, "value", (*Redactor)(nil).Redact("value"))
+	assert.NotPanics(t, func() { (*Redactor)(nil).Add("value") })
+}
+
+func TestRedactor_RedactVars(t *testing.T) {
+	r := &Redactor{}
+	r.Add("secret")
+
+	vars := lib.Vars{
+		"str":  "token secret",
+		"num":  float64(1),
+		"list": []interface{}{"secret"},
+		"map":  map[string]interface{}{"k": "secret"},
+	}
+	assert.Equal(t, lib.Vars{
+		"str":  "token *****",
+		"num":  float64(1),
+		"list": []interface{}{"*****"},
+		"map":  map[string]interface{}{"k": "*****"},
+	}, r.RedactVars(vars))
+	assert.Equal(t, "token secret", vars["str"], "original variables must be left intact")
+
+	assert.Equal(t, vars, (*Redactor)(nil).RedactVars(vars))
+}
+
+func TestRedactor_Wrap(t *testing.T) {
+	r := &Redactor{}
+	p := r.Wrap(Static{"token": "value"})
+
+	assert.Equal(t, "value", r.Redact("value"), "secret is not revealed yet")
+
+	v, err := p.Get("token")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+	assert.Equal(t, "*****", r.Redact("value"))
+
+	_, err = p.Get("unknown")
+	assert.Error(t, err)
+}
+
+func TestRedactor_Writer(t *testing.T) {
+	r := &Redactor{}
+	buf := &bytes.Buffer{}
+	l := log.New(r.Writer(buf), "", 0)
+
+	r.Add("value")
+	l.Printf("[DEBUG] running action with vars map[token:value]")
+	assert.Equal(t, "[DEBUG] running action with vars map[token:*****]\n", buf.String())
+}
@@ -0,0 +1,86 @@
+// Package secrets provides the values of secrets for templates from
+// different sources and keeps track of the revealed values in order
+// to redact them from the output.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+)
+
+// Provider provides the values of secrets.
+type Provider interface {
+	// Get returns the value of the secret by its name,
+	// errs.ErrNotFound if there is no such secret.
+	Get(name string) (string, error)
+}
+
+// Chain looks for the secret in providers one by one.
+type Chain []Provider
+
+// Get returns the value from the first provider, that contains the secret.
+func (c Chain) Get(name string) (string, error) {
+	for _, p := range c {
+		v, err := p.Get(name)
+		if errors.Is(err, errs.ErrNotFound) {
+			continue
+		}
+		return v, err
+	}
+	return "", fmt.Errorf("secret %q: %w", name, errs.ErrNotFound)
+}
+
+// Static provides secrets from the map.
+type Static map[string]string
+
+// Get returns the value of the secret.
+func (s Static) Get(name string) (string, error) {
+	v, ok := s[name]
+	if !ok {
+		return "", fmt.Errorf("secret %q: %w", name, errs.ErrNotFound)
+	}
+	return v, nil
+}
+
+// Env provides secrets from environment variables with the prefix,
+// e.g. secret "token" is read from SECRET_token with "SECRET_" prefix.
+type Env struct {
+	Prefix string
+}
+
+// Get returns the value of the environment variable.
+func (e Env) Get(name string) (string, error) {
+	v, ok := os.LookupEnv(e.Prefix + name)
+	if !ok {
+		return "", fmt.Errorf("secret %q: %w", name, errs.ErrNotFound)
+	}
+	return v, nil
+}
+
+// Dir provides secrets from files in the directory, one file per secret,
+// as they are mounted by Docker and Kubernetes.
+type Dir struct {
+	Path string
+}
+
+// Get returns the content of the file without trailing newline.
+func (d Dir) Get(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+
+	bts, err := os.ReadFile(filepath.Join(d.Path, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("secret %q: %w", name, errs.ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("read secret %q: %w", name, err)
+	}
+
+	return strings.TrimRight(string(bts), "\r\n"), nil
+}
@@ -0,0 +1,65 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChain_Get(t *testing.T) {
+	c := Chain{Static{"a": "first"}, Static{"a": "second", "b": "second"}}
+
+	v, err := c.Get("a")
+	require.NoError(t, err)
+	assert.Equal(t, "first", v)
+
+	v, err = c.Get("b")
+	require.NoError(t, err)
+	assert.Equal(t, "second", v)
+
+	_, err = c.Get("c")
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+
+	_, err = Chain{Dir{Path: t.TempDir()}}.Get("../a")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, errs.ErrNotFound, "errors other than not found must be returned")
+}
+
+func TestEnv_Get(t *testing.T) {
+	t.Setenv("TEST_SECRET_token", "value")
+
+	v, err := Env{Prefix: "TEST_SECRET_"}.Get("token")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+
+	_, err = Env{Prefix: "TEST_SECRET_"}.Get("unknown")
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+}
+
+func TestDir_Get(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("value\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "multiline"), []byte("line1\nline2\r\n"), 0o600))
+
+	d := Dir{Path: dir}
+
+	v, err := d.Get("token")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+
+	v, err = d.Get("multiline")
+	require.NoError(t, err)
+	assert.Equal(t, "line1\nline2", v)
+
+	_, err = d.Get("unknown")
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+
+	for _, name := range []string{"", ".", "..", "../token", "sub/token", `sub\token`} {
+		_, err = d.Get(name)
+		assert.Error(t, err, name)
+	}
+}
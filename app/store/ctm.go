@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"fmt"
+	"github.com/antonmedv/expr/vm"
 	"github.com/cappuccinotm/dastracker/lib"
 	"gopkg.in/yaml.v3"
 )
@@ -52,30 +53,35 @@ type Step interface{}
 
 // Job describes a control sequence.
 type Job struct {
-	Name        string   `yaml:"name"`
-	TriggerName string   `yaml:"on"`
-	Actions     Sequence `yaml:"do"`
+	Name        string   `yaml:"name" json:"name"`
+	TriggerName string   `yaml:"on" json:"on"`
+	Actions     Sequence `yaml:"do" json:"do"`
+	DryRun      bool     `yaml:"dry_run" json:"dry_run,omitempty"` // means, that trackers are not called
 }
 
 // Trigger describes an action, which has to appear to trigger
 // a control flow.
 type Trigger struct {
-	Name    string   `yaml:"name"`
-	Tracker string   `yaml:"in"`
-	With    lib.Vars `yaml:"with"`
+	Name    string   `yaml:"name" json:"name"`
+	Tracker string   `yaml:"in" json:"in"`
+	With    lib.Vars `yaml:"with" json:"with,omitempty"`
 }
 
 // If describes a conditional flow.
 type If struct {
-	Condition string   `yaml:"if"`
-	Actions   Sequence `yaml:"do"`
+	Condition string   `yaml:"if" json:"if"`
+	Actions   Sequence `yaml:"do" json:"do"`
+
+	prg *vm.Program // compiled condition, nil if it has not been compiled in advance
 }
 
 // Action describes a single call to the tracker's method.
 type Action struct {
-	Name     string   `yaml:"action"`
-	With     lib.Vars `yaml:"with"`
-	Detached bool     `yaml:"detached"` // means, that the action could be run asynchronously
+	Name     string   `yaml:"action" json:"action"`
+	With     lib.Vars `yaml:"with" json:"with,omitempty"`
+	Detached bool     `yaml:"detached" json:"detached,omitempty"` // means, that the action could be run asynchronously
+
+	tmpl Templates // compiled variables, nil if they have not been compiled in advance
 }
 
 // Path parses the action name to the tracker name and its method.
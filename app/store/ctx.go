@@ -21,7 +21,8 @@ func GetSubscription(ctx context.Context) (Subscription, error) {
 }
 
 // PutSubscription puts the provided subscription information to the given context.
-// Should not be used out of tests outside of subscription package.
+// Should not be used out of tests outside of subscription package and trackers,
+// that deliver updates not via webhooks.
 func PutSubscription(ctx context.Context, wh Subscription) context.Context {
 	return context.WithValue(ctx, subKey{}, wh)
 }
@@ -0,0 +1,173 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	metaBktName      = "meta"
+	schemaVersionKey = "schema_version"
+)
+
+// migration describes a single change of the database schema.
+// Applied migrations must never be changed, only new ones appended.
+type migration struct {
+	engine.Migration
+	apply func(tx *bolt.Tx) error
+}
+
+var ticketsMigrations = []migration{
+	{
+		Migration: engine.Migration{Version: 1, Description: "create tickets and refs buckets"},
+		apply:     createBuckets(ticketsBktName, ticketRefsBktName),
+	},
+	{
+		Migration: engine.Migration{Version: 2, Description: "create ticket updates bucket"},
+		apply:     createBuckets(ticketUpdatesBktName),
+	},
+}
+
+var subscriptionsMigrations = []migration{
+	{
+		Migration: engine.Migration{Version: 1, Description: "create subscriptions and references buckets"},
+		apply:     createBuckets(subscriptionsBktName, trackerToSubsBktName, trackerTriggerRefToSubsBktName),
+	},
+}
+
+var runsMigrations = []migration{
+	{
+		Migration: engine.Migration{Version: 1, Description: "create runs and run ids buckets"},
+		apply:     createBuckets(runsBktName, runIDsBktName),
+	},
+}
+
+// MigrateTickets applies pending migrations to the tickets database and
+// returns them. If dryRun is set, migrations are only listed.
+func MigrateTickets(fileName string, options bolt.Options, dryRun bool) ([]engine.Migration, error) {
+	return migrateFile(fileName, options, ticketsMigrations, dryRun)
+}
+
+// MigrateSubscriptions applies pending migrations to the subscriptions database
+// and returns them. If dryRun is set, migrations are only listed.
+func MigrateSubscriptions(fileName string, options bolt.Options, dryRun bool) ([]engine.Migration, error) {
+	return migrateFile(fileName, options, subscriptionsMigrations, dryRun)
+}
+
+// MigrateRuns applies pending migrations to the job runs database
+// and returns them. If dryRun is set, migrations are only listed.
+func MigrateRuns(fileName string, options bolt.Options, dryRun bool) ([]engine.Migration, error) {
+	return migrateFile(fileName, options, runsMigrations, dryRun)
+}
+
+// migrateFile opens the database and migrates it. In case of dry run the
+// database is opened read-only and the missing file is reported with
+// errs.ErrNotFound instead of being created.
+func migrateFile(fileName string, options bolt.Options, migrations []migration, dryRun bool) ([]engine.Migration, error) {
+	if dryRun {
+		// dry run must neither create the file, nor lock it exclusively
+		if _, err := os.Stat(fileName); errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("database file %s doesn't exist: %w", fileName, errs.ErrNotFound)
+		}
+		options.ReadOnly = true
+	}
+
+	db, err := bolt.Open(fileName, 0600, &options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make boltdb for %s: %w", fileName, err)
+	}
+	defer db.Close()
+
+	return migrate(db, migrations, dryRun)
+}
+
+// migrate applies all pending migrations in a single transaction.
+// Databases created before the versioning are considered to be of version 0,
+// thus all migrations must be idempotent.
+func migrate(db *bolt.DB, migrations []migration, dryRun bool) ([]engine.Migration, error) {
+	var pending []engine.Migration
+
+	fn := func(tx *bolt.Tx) error {
+		version := schemaVersion(tx)
+
+		if version > len(migrations) {
+			return errs.ErrSchemaTooNew{Current: version, Supported: len(migrations)}
+		}
+
+		for _, m := range migrations[version:] {
+			pending = append(pending, m.Migration)
+			if dryRun {
+				continue
+			}
+
+			if err := m.apply(tx); err != nil {
+				return fmt.Errorf("apply migration %d: %w", m.Version, err)
+			}
+		}
+
+		if dryRun {
+			return nil
+		}
+
+		return putSchemaVersion(tx, len(migrations))
+	}
+
+	var err error
+	if dryRun {
+		err = db.View(fn)
+	} else {
+		err = db.Update(fn)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("migrate schema: %w", err)
+	}
+
+	return pending, nil
+}
+
+func schemaVersion(tx *bolt.Tx) int {
+	bkt := tx.Bucket([]byte(metaBktName))
+	if bkt == nil {
+		return 0
+	}
+
+	bts := bkt.Get([]byte(schemaVersionKey))
+	if len(bts) != 8 {
+		return 0
+	}
+
+	return int(binary.BigEndian.Uint64(bts))
+}
+
+func putSchemaVersion(tx *bolt.Tx, version int) error {
+	bkt, err := tx.CreateBucketIfNotExists([]byte(metaBktName))
+	if err != nil {
+		return fmt.Errorf("create meta bucket: %w", err)
+	}
+
+	bts := make([]byte, 8)
+	binary.BigEndian.PutUint64(bts, uint64(version))
+
+	if err = bkt.Put([]byte(schemaVersionKey), bts); err != nil {
+		return fmt.Errorf("put schema version: %w", err)
+	}
+
+	return nil
+}
+
+func createBuckets(names ...string) func(tx *bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
+		for _, name := range names {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return fmt.Errorf("failed to create top-level bucket %s: %w", name, err)
+			}
+		}
+		return nil
+	}
+}
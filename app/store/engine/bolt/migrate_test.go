@@ -0,0 +1,121 @@
+//go:build !race
+// +build !race
+
+// bolt itself thread-safe so there is no need in race-detector
+package bolt
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestMigrateTickets(t *testing.T) {
+	t.Run("legacy database", func(t *testing.T) {
+		fileName := prepareFile(t)
+
+		// the database, created before the versioning, has only first buckets
+		db, err := bolt.Open(fileName, 0600, &bolt.Options{})
+		require.NoError(t, err)
+		err = db.Update(func(tx *bolt.Tx) error {
+			require.NoError(t, createBuckets(ticketsBktName, ticketRefsBktName)(tx))
+			return tx.Bucket([]byte(ticketsBktName)).Put([]byte("id"), []byte("{}"))
+		})
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		pending, err := MigrateTickets(fileName, bolt.Options{}, true)
+		require.NoError(t, err)
+		assert.Equal(t, []engine.Migration{
+			{Version: 1, Description: "create tickets and refs buckets"},
+			{Version: 2, Description: "create ticket updates bucket"},
+		}, pending)
+
+		svc, err := NewTickets(fileName, bolt.Options{})
+		require.NoError(t, err)
+
+		err = svc.db.View(func(tx *bolt.Tx) error {
+			assert.NotNil(t, tx.Bucket([]byte(ticketUpdatesBktName)))
+			assert.Equal(t, []byte("{}"), tx.Bucket([]byte(ticketsBktName)).Get([]byte("id")))
+			assert.Equal(t, len(ticketsMigrations), schemaVersion(tx))
+			return nil
+		})
+		require.NoError(t, err)
+		require.NoError(t, svc.db.Close())
+
+		pending, err = MigrateTickets(fileName, bolt.Options{}, false)
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+
+	t.Run("dry run doesn't change the database", func(t *testing.T) {
+		fileName := prepareFile(t)
+
+		db, err := bolt.Open(fileName, 0600, &bolt.Options{})
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		pending, err := MigrateTickets(fileName, bolt.Options{}, true)
+		require.NoError(t, err)
+		assert.Len(t, pending, len(ticketsMigrations))
+
+		db, err = bolt.Open(fileName, 0600, &bolt.Options{})
+		require.NoError(t, err)
+		err = db.View(func(tx *bolt.Tx) error {
+			assert.Nil(t, tx.Bucket([]byte(ticketsBktName)))
+			assert.Zero(t, schemaVersion(tx))
+			return nil
+		})
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+	})
+
+	t.Run("dry run doesn't create the database", func(t *testing.T) {
+		fileName := prepareFile(t)
+
+		_, err := MigrateTickets(fileName, bolt.Options{}, true)
+		assert.ErrorIs(t, err, errs.ErrNotFound)
+
+		_, err = os.Stat(fileName)
+		assert.True(t, os.IsNotExist(err), "file must not be created")
+	})
+
+	t.Run("database is newer than the app", func(t *testing.T) {
+		fileName := prepareFile(t)
+
+		db, err := bolt.Open(fileName, 0600, &bolt.Options{})
+		require.NoError(t, err)
+		err = db.Update(func(tx *bolt.Tx) error { return putSchemaVersion(tx, len(ticketsMigrations)+1) })
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		_, err = NewTickets(fileName, bolt.Options{})
+		assert.ErrorAs(t, err, &errs.ErrSchemaTooNew{})
+	})
+}
+
+func TestMigrateSubscriptions(t *testing.T) {
+	fileName := prepareFile(t)
+
+	pending, err := MigrateSubscriptions(fileName, bolt.Options{}, false)
+	require.NoError(t, err)
+	assert.Len(t, pending, len(subscriptionsMigrations))
+
+	pending, err = MigrateSubscriptions(fileName, bolt.Options{}, true)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func prepareFile(t *testing.T) string {
+	loc, err := ioutil.TempDir("", "test_dastracker")
+	require.NoError(t, err, "failed to make temp dir")
+	t.Cleanup(func() { assert.NoError(t, os.RemoveAll(loc)) })
+	return path.Join(loc, "dastracker_test.db")
+}
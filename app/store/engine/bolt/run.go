@@ -0,0 +1,187 @@
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	runsBktName   = "runs"
+	runIDsBktName = "run_ids"
+)
+
+// Runs implements engine.Runs over BoltDB.
+// runs: key - start time in unix nanoseconds (big-endian) followed by runID, val - run
+// run_ids: key - runID, val - key of the run in the runs bucket
+type Runs struct {
+	fileName string
+	db       *bolt.DB
+}
+
+// NewRuns opens the database and applies pending schema migrations.
+func NewRuns(fileName string, options bolt.Options) (*Runs, error) {
+	db, err := bolt.Open(fileName, 0600, &options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make boltdb for %s: %w", fileName, err)
+	}
+
+	if _, err = migrate(db, runsMigrations, false); err != nil {
+		return nil, fmt.Errorf("failed to initialize boltdb buckets for %s: %w", fileName, err)
+	}
+
+	return &Runs{db: db, fileName: fileName}, nil
+}
+
+// Create puts the run to the storage.
+func (b *Runs) Create(_ context.Context, run store.JobRun) (string, error) {
+	if run.ID == "" {
+		run.ID = uuid.NewString()
+	}
+
+	bts, err := json.Marshal(run)
+	if err != nil {
+		return "", fmt.Errorf("marshal run: %w", err)
+	}
+
+	key := make([]byte, 8, 8+len(run.ID))
+	binary.BigEndian.PutUint64(key, uint64(run.StartedAt.UnixNano()))
+	key = append(key, run.ID...)
+
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket([]byte(runsBktName)).Put(key, bts); err != nil {
+			return fmt.Errorf("put run %s: %w", run.ID, err)
+		}
+
+		if err := tx.Bucket([]byte(runIDsBktName)).Put([]byte(run.ID), key); err != nil {
+			return fmt.Errorf("put reference to run %s: %w", run.ID, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("update storage: %w", err)
+	}
+
+	return run.ID, nil
+}
+
+// Get returns the run by its ID.
+func (b *Runs) Get(_ context.Context, runID string) (store.JobRun, error) {
+	var run store.JobRun
+	err := b.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket([]byte(runIDsBktName)).Get([]byte(runID))
+		if key == nil {
+			return errs.ErrNotFound
+		}
+
+		bts := tx.Bucket([]byte(runsBktName)).Get(key)
+		if bts == nil {
+			return errs.ErrNotFound
+		}
+
+		if err := json.Unmarshal(bts, &run); err != nil {
+			return fmt.Errorf("unmarshal run: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return store.JobRun{}, fmt.Errorf("get run %s: %w", runID, err)
+	}
+
+	return run, nil
+}
+
+// List returns runs, that match the given engine.ListRunsRequest,
+// the most recent first.
+func (b *Runs) List(_ context.Context, req engine.ListRunsRequest) ([]store.JobRun, error) {
+	var runs []store.JobRun
+	err := b.db.View(func(tx *bolt.Tx) error {
+		skipped := 0
+
+		c := tx.Bucket([]byte(runsBktName)).Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			if req.Limit > 0 && len(runs) >= req.Limit {
+				return nil
+			}
+
+			var run store.JobRun
+			if err := json.Unmarshal(v, &run); err != nil {
+				return fmt.Errorf("unmarshal run with key %x: %w", k, err)
+			}
+
+			// runs are ordered by start time, so there is no need to look further
+			if !req.Since.IsZero() && run.StartedAt.Before(req.Since) {
+				return nil
+			}
+
+			if !req.Match(run) {
+				continue
+			}
+
+			if skipped < req.Offset {
+				skipped++
+				continue
+			}
+
+			runs = append(runs, run)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("view storage: %w", err)
+	}
+
+	return runs, nil
+}
+
+// Cleanup removes runs according to the retention policy.
+func (b *Runs) Cleanup(_ context.Context, req engine.CleanupRunsRequest) (int, error) {
+	removed := 0
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		runsBkt := tx.Bucket([]byte(runsBktName))
+		idsBkt := tx.Bucket([]byte(runIDsBktName))
+
+		// keys can't be deleted while iterating the cursor,
+		// so they are collected first
+		var keys [][]byte
+		seen := 0
+
+		c := runsBkt.Cursor()
+		for k, _ := c.Last(); k != nil; k, _ = c.Prev() {
+			seen++
+			startedAt := int64(binary.BigEndian.Uint64(k[:8]))
+
+			if (req.Keep > 0 && seen > req.Keep) ||
+				(!req.Before.IsZero() && startedAt < req.Before.UnixNano()) {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+		}
+
+		for _, k := range keys {
+			if err := runsBkt.Delete(k); err != nil {
+				return fmt.Errorf("delete run with key %x: %w", k, err)
+			}
+
+			if err := idsBkt.Delete(k[8:]); err != nil {
+				return fmt.Errorf("delete reference to run %s: %w", k[8:], err)
+			}
+		}
+
+		removed = len(keys)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("update storage: %w", err)
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,59 @@
+//go:build !race
+// +build !race
+
+// bolt itself thread-safe so there is no need in race-detector
+package bolt
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/app/store/engine/enginetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestRuns(t *testing.T) {
+	enginetest.TestRuns(t, func(t *testing.T) engine.Runs { return prepareRuns(t) })
+}
+
+func TestRuns_Cleanup(t *testing.T) {
+	svc := prepareRuns(t)
+
+	id, err := svc.Create(context.Background(), store.JobRun{StartedAt: time.Unix(0, 100)})
+	require.NoError(t, err)
+
+	removed, err := svc.Cleanup(context.Background(), engine.CleanupRunsRequest{Keep: 1, Before: time.Unix(0, 200)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	// references to the removed runs must be removed as well
+	err = svc.db.View(func(tx *bolt.Tx) error {
+		assert.Nil(t, tx.Bucket([]byte(runIDsBktName)).Get([]byte(id)))
+		assert.Zero(t, tx.Bucket([]byte(runsBktName)).Stats().KeyN)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func prepareRuns(t *testing.T) *Runs {
+	loc, err := ioutil.TempDir("", "test_dastracker")
+	require.NoError(t, err, "failed to make temp dir")
+
+	svc, err := NewRuns(path.Join(loc, "dastracker_runs_test.db"), bolt.Options{})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		assert.NoError(t, svc.db.Close())
+		assert.NoError(t, os.RemoveAll(loc))
+	})
+
+	return svc
+}
@@ -31,22 +31,14 @@ type Subscriptions struct {
 	db       *bolt.DB
 }
 
-// NewSubscription creates buckets and initial data processing
+// NewSubscription opens the database and applies pending schema migrations.
 func NewSubscription(fileName string, options bolt.Options, log logx.Logger) (*Subscriptions, error) {
 	db, err := bolt.Open(fileName, 0600, &options)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make boltdb for %s: %w", fileName, err)
 	}
 
-	err = db.Update(func(tx *bolt.Tx) error {
-		for _, bktName := range []string{subscriptionsBktName, trackerToSubsBktName, trackerTriggerRefToSubsBktName} {
-			if _, err := tx.CreateBucketIfNotExists([]byte(bktName)); err != nil {
-				return fmt.Errorf("failed to create bucket %s: %w", bktName, err)
-			}
-		}
-		return nil
-	})
-	if err != nil {
+	if _, err = migrate(db, subscriptionsMigrations, false); err != nil {
 		return nil, fmt.Errorf("failed to initialize boltdb buckets for %s: %w", fileName, err)
 	}
 
@@ -94,7 +86,7 @@ func (b *Subscriptions) Get(_ context.Context, id string) (store.Subscription, e
 		return nil
 	})
 	if err != nil {
-		return store.Subscription{}, fmt.Errorf("view storage: %b", err)
+		return store.Subscription{}, fmt.Errorf("view storage: %w", err)
 	}
 
 	return sub, nil
@@ -15,12 +15,18 @@ import (
 	"time"
 
 	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/app/store/engine/enginetest"
 	"github.com/cappuccinotm/dastracker/pkg/logx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	bolt "go.etcd.io/bbolt"
 )
 
+func TestSubscriptions(t *testing.T) {
+	enginetest.TestSubscriptions(t, func(t *testing.T) engine.Subscriptions { return prepareSubscription(t) })
+}
+
 func TestSubscription_Create(t *testing.T) {
 	svc := prepareSubscription(t)
 	id, err := svc.Create(context.Background(), store.Subscription{
@@ -3,7 +3,9 @@ package bolt
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/cappuccinotm/dastracker/app/errs"
 	"github.com/cappuccinotm/dastracker/app/store"
@@ -13,37 +15,28 @@ import (
 )
 
 const (
-	ticketsBktName    = "tickets"
-	ticketRefsBktName = "refs"
+	ticketsBktName       = "tickets"
+	ticketRefsBktName    = "refs"
+	ticketUpdatesBktName = "ticket_updates"
 )
 
 // Tickets implements engine.Tickets over BoltDB.
 // tickets: key - ticketID, val - ticket
 // refs: key - reference, val - ticketID
+// ticket_updates: key - ticketID, val - time of the last update in RFC3339 nano
 type Tickets struct {
 	fileName string
 	db       *bolt.DB
 }
 
-// NewTickets creates buckets and initial data processing
+// NewTickets opens the database and applies pending schema migrations.
 func NewTickets(fileName string, options bolt.Options) (*Tickets, error) {
 	db, err := bolt.Open(fileName, 0600, &options)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make boltdb for %s: %w", fileName, err)
 	}
 
-	err = db.Update(func(tx *bolt.Tx) error {
-		if _, err := tx.CreateBucketIfNotExists([]byte(ticketsBktName)); err != nil {
-			return fmt.Errorf("failed to create top-level bucket %s: %w", ticketsBktName, err)
-		}
-
-		if _, err := tx.CreateBucketIfNotExists([]byte(ticketRefsBktName)); err != nil {
-			return fmt.Errorf("failed to create top-level bucket %s: %w", ticketRefsBktName, err)
-		}
-
-		return nil
-	})
-	if err != nil {
+	if _, err = migrate(db, ticketsMigrations, false); err != nil {
 		return nil, fmt.Errorf("failed to initialize boltdb buckets for %s: %w", fileName, err)
 	}
 
@@ -63,18 +56,15 @@ func (b *Tickets) Create(ctx context.Context, ticket store.Ticket) (string, erro
 
 // Update updates ticket by its ID.
 func (b *Tickets) Update(_ context.Context, ticket store.Ticket) error {
-	bts, err := json.Marshal(ticket)
-	if err != nil {
-		return fmt.Errorf("marshal ticket: %w", err)
-	}
-
-	err = b.db.Update(func(tx *bolt.Tx) error {
-		if err = tx.Bucket([]byte(ticketsBktName)).Put([]byte(ticket.ID), bts); err != nil {
-			return fmt.Errorf("put ticket to storage: %w", err)
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		// refs must be put before the ticket itself, as the previous
+		// version of the ticket is used to clean up outdated refs
+		if err := b.putTicketRefs(tx, ticket); err != nil {
+			return fmt.Errorf("put ticket refs: %w", err)
 		}
 
-		if err = b.putTicketRefs(tx, ticket); err != nil {
-			return fmt.Errorf("put ticket refs: %w", err)
+		if err := b.put(tx, ticket); err != nil {
+			return fmt.Errorf("put ticket to storage: %w", err)
 		}
 
 		return nil
@@ -89,7 +79,7 @@ func (b *Tickets) Update(_ context.Context, ticket store.Ticket) error {
 func (b *Tickets) Get(_ context.Context, req engine.GetRequest) (store.Ticket, error) {
 	ticketID := req.TicketID
 
-	var ticketBts []byte
+	var ticket store.Ticket
 	err := b.db.View(func(tx *bolt.Tx) error {
 		var err error
 
@@ -100,8 +90,8 @@ func (b *Tickets) Get(_ context.Context, req engine.GetRequest) (store.Ticket, e
 			}
 		}
 
-		if ticketBts = tx.Bucket([]byte(ticketsBktName)).Get([]byte(ticketID)); ticketBts == nil {
-			return fmt.Errorf("ticket %s not found: %w", ticketID, errs.ErrNotFound)
+		if ticket, err = b.get(tx, ticketID); err != nil {
+			return fmt.Errorf("get ticket %s: %w", ticketID, err)
 		}
 
 		return nil
@@ -110,25 +100,218 @@ func (b *Tickets) Get(_ context.Context, req engine.GetRequest) (store.Ticket, e
 		return store.Ticket{}, fmt.Errorf("view storage: %w", err)
 	}
 
-	var ticket store.Ticket
+	return ticket, nil
+}
+
+// List returns tickets, that match the given engine.ListRequest.
+func (b *Tickets) List(_ context.Context, req engine.ListRequest) ([]store.Ticket, error) {
+	var tickets []store.Ticket
+	err := b.db.View(func(tx *bolt.Tx) error {
+		updBkt := tx.Bucket([]byte(ticketUpdatesBktName))
+		skipped := 0
+
+		c := tx.Bucket([]byte(ticketsBktName)).Cursor()
+		k, v := c.First()
+		if req.AfterID != "" {
+			// keys are sorted, so the cursor is moved right after the given ID
+			if k, v = c.Seek([]byte(req.AfterID)); k != nil && string(k) == req.AfterID {
+				k, v = c.Next()
+			}
+		}
+
+		for ; k != nil; k, v = c.Next() {
+			if req.Limit > 0 && len(tickets) >= req.Limit {
+				return nil
+			}
+
+			var ticket store.Ticket
+			if err := json.Unmarshal(v, &ticket); err != nil {
+				return fmt.Errorf("unmarshal ticket %s: %w", k, err)
+			}
+
+			// tickets, stored before the update time has been introduced,
+			// are considered as updated at the zero time
+			var updatedAt time.Time
+			if bts := updBkt.Get(k); bts != nil {
+				var err error
+				if updatedAt, err = time.Parse(time.RFC3339Nano, string(bts)); err != nil {
+					return fmt.Errorf("parse update time of ticket %s: %w", k, err)
+				}
+			}
+
+			if !req.Match(ticket, updatedAt) {
+				continue
+			}
+
+			if skipped < req.Offset {
+				skipped++
+				continue
+			}
+
+			tickets = append(tickets, ticket)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("view storage: %w", err)
+	}
+	return tickets, nil
+}
+
+// Delete removes the ticket by its ID along with all its refs.
+func (b *Tickets) Delete(_ context.Context, ticketID string) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		ticket, err := b.get(tx, ticketID)
+		if err != nil {
+			return fmt.Errorf("get ticket %s: %w", ticketID, err)
+		}
+
+		if err = b.delete(tx, ticket); err != nil {
+			return fmt.Errorf("delete ticket %s: %w", ticketID, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("update storage: %w", err)
+	}
+	return nil
+}
+
+// Unlink removes the variation, located by the given locator, from its ticket.
+func (b *Tickets) Unlink(_ context.Context, locator store.Locator) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		ticketID, err := b.getTicketID(tx, locator)
+		if err != nil {
+			return fmt.Errorf("get ticket id by ref %q: %w", locator.String(), err)
+		}
 
-	if err = json.Unmarshal(ticketBts, &ticket); err != nil {
+		ticket, err := b.get(tx, ticketID)
+		if err != nil {
+			return fmt.Errorf("get ticket %s: %w", ticketID, err)
+		}
+
+		if err = b.deleteRef(tx, ticketID, locator); err != nil {
+			return fmt.Errorf("delete ref %q: %w", locator.String(), err)
+		}
+
+		// the ref is stale, the ticket itself is not changed
+		if task := ticket.Variations.Get(locator.Tracker); task.ID != locator.ID {
+			return nil
+		}
+		delete(ticket.Variations, locator.Tracker)
+
+		if len(ticket.Variations) == 0 {
+			if err = b.delete(tx, ticket); err != nil {
+				return fmt.Errorf("delete ticket %s without variations: %w", ticketID, err)
+			}
+			return nil
+		}
+
+		if err = b.put(tx, ticket); err != nil {
+			return fmt.Errorf("put ticket %s: %w", ticketID, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("update storage: %w", err)
+	}
+	return nil
+}
+
+func (b *Tickets) get(tx *bolt.Tx, ticketID string) (store.Ticket, error) {
+	bts := tx.Bucket([]byte(ticketsBktName)).Get([]byte(ticketID))
+	if bts == nil {
+		return store.Ticket{}, errs.ErrNotFound
+	}
+
+	var ticket store.Ticket
+	if err := json.Unmarshal(bts, &ticket); err != nil {
 		return store.Ticket{}, fmt.Errorf("unmarshal: %w", err)
 	}
 
 	return ticket, nil
 }
 
+func (b *Tickets) put(tx *bolt.Tx, ticket store.Ticket) error {
+	bts, err := json.Marshal(ticket)
+	if err != nil {
+		return fmt.Errorf("marshal ticket: %w", err)
+	}
+
+	if err = tx.Bucket([]byte(ticketsBktName)).Put([]byte(ticket.ID), bts); err != nil {
+		return fmt.Errorf("put ticket: %w", err)
+	}
+
+	updatedAt := []byte(time.Now().Format(time.RFC3339Nano))
+	if err = tx.Bucket([]byte(ticketUpdatesBktName)).Put([]byte(ticket.ID), updatedAt); err != nil {
+		return fmt.Errorf("put update time: %w", err)
+	}
+
+	return nil
+}
+
+func (b *Tickets) delete(tx *bolt.Tx, ticket store.Ticket) error {
+	for _, locator := range ticket.Variations.Locators() {
+		if err := b.deleteRef(tx, ticket.ID, locator); err != nil {
+			return fmt.Errorf("delete ref %q: %w", locator.String(), err)
+		}
+	}
+
+	if err := tx.Bucket([]byte(ticketUpdatesBktName)).Delete([]byte(ticket.ID)); err != nil {
+		return fmt.Errorf("delete update time: %w", err)
+	}
+
+	if err := tx.Bucket([]byte(ticketsBktName)).Delete([]byte(ticket.ID)); err != nil {
+		return fmt.Errorf("delete ticket itself: %w", err)
+	}
+
+	return nil
+}
+
 func (b *Tickets) putTicketRefs(tx *bolt.Tx, ticket store.Ticket) error {
 	locators := ticket.Variations.Locators()
+
+	prev, err := b.get(tx, ticket.ID)
+	switch {
+	case errors.Is(err, errs.ErrNotFound):
+	case err != nil:
+		return fmt.Errorf("get previous version of ticket: %w", err)
+	default:
+		actual := map[string]struct{}{}
+		for _, locator := range locators {
+			actual[taskRef(locator)] = struct{}{}
+		}
+
+		for _, locator := range prev.Variations.Locators() {
+			if _, ok := actual[taskRef(locator)]; ok {
+				continue
+			}
+			if err = b.deleteRef(tx, ticket.ID, locator); err != nil {
+				return fmt.Errorf("delete outdated ref %q: %w", locator.String(), err)
+			}
+		}
+	}
+
 	for _, locator := range locators {
-		if err := tx.Bucket([]byte(ticketRefsBktName)).Put([]byte(taskRef(locator)), []byte(ticket.ID)); err != nil {
+		if err = tx.Bucket([]byte(ticketRefsBktName)).Put([]byte(taskRef(locator)), []byte(ticket.ID)); err != nil {
 			return fmt.Errorf("put ref for %q on %s: %w", locator.String(), ticket.ID, err)
 		}
 	}
 	return nil
 }
 
+// deleteRef removes the reference only if it points to the given ticket.
+func (b *Tickets) deleteRef(tx *bolt.Tx, ticketID string, locator store.Locator) error {
+	bkt := tx.Bucket([]byte(ticketRefsBktName))
+	ref := []byte(taskRef(locator))
+	if string(bkt.Get(ref)) != ticketID {
+		return nil
+	}
+	return bkt.Delete(ref)
+}
+
 func (b *Tickets) getTicketID(tx *bolt.Tx, locator store.Locator) (string, error) {
 	ref := taskRef(locator)
 	ticketID := tx.Bucket([]byte(ticketRefsBktName)).Get([]byte(ref))
@@ -12,13 +12,29 @@ import (
 	"path"
 	"testing"
 
+	"github.com/cappuccinotm/dastracker/app/errs"
 	"github.com/cappuccinotm/dastracker/app/store"
 	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/app/store/engine/enginetest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	bolt "go.etcd.io/bbolt"
 )
 
+func TestTickets(t *testing.T) {
+	enginetest.TestTickets(t, func(t *testing.T) engine.Tickets { return prepareTickets(t) })
+}
+
+func TestTickets_StaleRef(t *testing.T) {
+	svc := prepareTickets(t)
+	enginetest.TestTicketsStaleRef(t, svc, func(locator store.Locator, ticketID string) {
+		err := svc.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte(ticketRefsBktName)).Put([]byte(taskRef(locator)), []byte(ticketID))
+		})
+		require.NoError(t, err)
+	})
+}
+
 func TestTickets_Create(t *testing.T) {
 	svc := prepareTickets(t)
 
@@ -93,6 +109,35 @@ func TestTickets_Update(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestTickets_Update_OutdatedRefs(t *testing.T) {
+	svc := prepareTickets(t)
+
+	tkt := store.Ticket{
+		ID: "id",
+		Variations: map[string]store.Task{
+			"tracker-1": {ID: "task-id-1"},
+			"tracker-2": {ID: "task-id-2"},
+		},
+	}
+	require.NoError(t, svc.Update(context.Background(), tkt))
+
+	tkt.Variations = map[string]store.Task{
+		"tracker-1": {ID: "task-id-1-new"},
+		"tracker-3": {ID: "task-id-3"},
+	}
+	require.NoError(t, svc.Update(context.Background(), tkt))
+
+	err := svc.db.View(func(tx *bolt.Tx) error {
+		refsBkt := tx.Bucket([]byte(ticketRefsBktName))
+		assert.Nil(t, refsBkt.Get([]byte(taskRef(store.Locator{Tracker: "tracker-1", ID: "task-id-1"}))))
+		assert.Nil(t, refsBkt.Get([]byte(taskRef(store.Locator{Tracker: "tracker-2", ID: "task-id-2"}))))
+		assert.Equal(t, []byte("id"), refsBkt.Get([]byte(taskRef(store.Locator{Tracker: "tracker-1", ID: "task-id-1-new"}))))
+		assert.Equal(t, []byte("id"), refsBkt.Get([]byte(taskRef(store.Locator{Tracker: "tracker-3", ID: "task-id-3"}))))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
 func TestTickets_Get(t *testing.T) {
 	expectedTkt := store.Ticket{
 		ID: "id",
@@ -150,11 +195,38 @@ func TestTickets_Get(t *testing.T) {
 	})
 }
 
+func TestTickets_Delete(t *testing.T) {
+	svc := prepareTickets(t)
+
+	tkt := store.Ticket{
+		ID: "id",
+		Variations: map[string]store.Task{
+			"tracker-1": {ID: "task-id-1"},
+			"tracker-2": {ID: "task-id-2"},
+		},
+	}
+	require.NoError(t, svc.Update(context.Background(), tkt))
+
+	require.NoError(t, svc.Delete(context.Background(), "id"))
+
+	err := svc.db.View(func(tx *bolt.Tx) error {
+		assert.Nil(t, tx.Bucket([]byte(ticketsBktName)).Get([]byte("id")))
+		assert.Nil(t, tx.Bucket([]byte(ticketUpdatesBktName)).Get([]byte("id")))
+		assert.Zero(t, tx.Bucket([]byte(ticketRefsBktName)).Stats().KeyN)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = svc.Delete(context.Background(), "id")
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+}
+
 func TestNewTickets(t *testing.T) {
 	svc := prepareTickets(t)
 	err := svc.db.View(func(tx *bolt.Tx) error {
 		assert.NotNil(t, tx.Bucket([]byte(ticketsBktName)))
 		assert.NotNil(t, tx.Bucket([]byte(ticketRefsBktName)))
+		assert.NotNil(t, tx.Bucket([]byte(ticketUpdatesBktName)))
 		return nil
 	})
 	require.NoError(t, err)
@@ -2,20 +2,29 @@ package engine
 
 import (
 	"context"
+	"time"
 
 	"github.com/cappuccinotm/dastracker/app/store"
 )
 
-//go:generate rm -f ticket_mock.go webhook_mock.go flow_mock.go
+//go:generate rm -f ticket_mock.go webhook_mock.go flow_mock.go runs_mock.go
 //go:generate moq -out ticket_mock.go -fmt goimports . Tickets
 //go:generate moq -out subscriptions_mock.go -fmt goimports . Subscriptions
 //go:generate moq -out flow_mock.go -fmt goimports . Flow
+//go:generate moq -out runs_mock.go -fmt goimports . Runs
 
 // Tickets describes methods each storage should implement.
 type Tickets interface {
 	Create(ctx context.Context, ticket store.Ticket) (ticketID string, err error)
 	Update(ctx context.Context, ticket store.Ticket) error
 	Get(ctx context.Context, req GetRequest) (store.Ticket, error)
+	// List returns tickets matching the given criteria, ordered by ticket ID.
+	List(ctx context.Context, req ListRequest) ([]store.Ticket, error)
+	// Delete removes the ticket and all references to its variations.
+	Delete(ctx context.Context, ticketID string) error
+	// Unlink removes the variation, pointed by the locator, from its ticket.
+	// If the ticket has no variations left, it is removed as well.
+	Unlink(ctx context.Context, locator store.Locator) error
 }
 
 // Subscriptions defines methods to store and load information about subscriptions.
@@ -31,12 +40,125 @@ type Subscriptions interface {
 // Flow defines methods to access the flow configuration.
 type Flow interface {
 	ListSubscribedJobs(ctx context.Context, triggerName string) ([]store.Job, error)
+	ListJobs(ctx context.Context) ([]store.Job, error)
 	ListTrackers(ctx context.Context) ([]store.Tracker, error)
 	ListTriggers(ctx context.Context) ([]store.Trigger, error)
 }
 
+// Runs defines methods to store the history of job runs.
+type Runs interface {
+	// Create puts the run to the storage, the ID is generated if not set.
+	Create(ctx context.Context, run store.JobRun) (runID string, err error)
+	Get(ctx context.Context, runID string) (store.JobRun, error)
+	// List returns runs matching the given criteria, the most recent first.
+	List(ctx context.Context, req ListRunsRequest) ([]store.JobRun, error)
+	// Cleanup removes runs according to the retention policy
+	// and returns the number of removed runs.
+	Cleanup(ctx context.Context, req CleanupRunsRequest) (removed int, err error)
+}
+
 // GetRequest describes parameters to get a single ticket.
 type GetRequest struct {
 	Locator  store.Locator `json:"locator"`
 	TicketID string        `json:"ticket_id"`
 }
+
+// ListRequest describes parameters to list tickets.
+// Empty values of filters are ignored.
+type ListRequest struct {
+	// Tracker filters tickets, that have a variation in the given tracker.
+	Tracker string `json:"tracker"`
+	// Fields filters tickets, that have at least one variation (in Tracker,
+	// if specified) with all the given field values.
+	Fields store.TicketFields `json:"fields"`
+	// UpdatedSince filters tickets, updated at or after the given time.
+	UpdatedSince time.Time `json:"updated_since"`
+	// AfterID filters tickets with IDs, greater than the given one. Tickets are
+	// listed in order of their IDs, so, unlike the offset, the ID of the last
+	// listed ticket keeps pagination stable, if tickets are created or deleted.
+	AfterID string `json:"after_id"`
+
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"` // zero means no limit
+}
+
+// Match returns true if the ticket, updated at the given time,
+// satisfies the filters of the request.
+func (r ListRequest) Match(ticket store.Ticket, updatedAt time.Time) bool {
+	if !r.UpdatedSince.IsZero() && updatedAt.Before(r.UpdatedSince) {
+		return false
+	}
+
+	if r.Tracker != "" && !ticket.Variations.Has(r.Tracker) {
+		return false
+	}
+
+	if len(r.Fields) == 0 {
+		return true
+	}
+
+	matchFields := func(task store.Task) bool {
+		for k, v := range r.Fields {
+			if val, ok := task.Fields[k]; !ok || val != v {
+				return false
+			}
+		}
+		return true
+	}
+
+	if r.Tracker != "" {
+		return matchFields(ticket.Variations.Get(r.Tracker))
+	}
+
+	for _, task := range ticket.Variations {
+		if matchFields(task) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ListRunsRequest describes parameters to list job runs.
+// Empty values of filters are ignored.
+type ListRunsRequest struct {
+	JobName     string        `json:"job_name"`
+	TriggerName string        `json:"trigger_name"`
+	Update      store.Locator `json:"update"` // fields of the locator are matched separately
+	TicketID    string        `json:"ticket_id"`
+	OnlyFailed  bool          `json:"only_failed"`
+	Since       time.Time     `json:"since"` // started at or after
+
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"` // zero means no limit
+}
+
+// Match returns true if the run satisfies the filters of the request.
+func (r ListRunsRequest) Match(run store.JobRun) bool {
+	switch {
+	case r.JobName != "" && run.JobName != r.JobName,
+		r.TriggerName != "" && run.TriggerName != r.TriggerName,
+		r.Update.Tracker != "" && run.Update.Tracker != r.Update.Tracker,
+		r.Update.ID != "" && run.Update.ID != r.Update.ID,
+		r.TicketID != "" && run.TicketID != r.TicketID,
+		r.OnlyFailed && !run.Failed(),
+		!r.Since.IsZero() && run.StartedAt.Before(r.Since):
+		return false
+	}
+	return true
+}
+
+// CleanupRunsRequest describes the retention policy of job runs.
+// Zero values disable the corresponding limit.
+type CleanupRunsRequest struct {
+	// Before removes runs, started before the given time.
+	Before time.Time `json:"before"`
+	// Keep removes all runs, except the given number of the most recent ones.
+	Keep int `json:"keep"`
+}
+
+// Migration describes a single change of the storage schema.
+type Migration struct {
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+}
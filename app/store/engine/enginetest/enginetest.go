@@ -0,0 +1,564 @@
+// Package enginetest provides conformance test suites, which any
+// implementation of engine.Tickets, engine.Subscriptions and engine.Runs must pass.
+package enginetest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTickets runs the conformance suite over the engine.Tickets.
+// newTickets must return a fresh empty storage on each call.
+func TestTickets(t *testing.T, newTickets func(t *testing.T) engine.Tickets) {
+	tbl := []struct {
+		name string
+		fn   func(t *testing.T, svc engine.Tickets)
+	}{
+		{name: "create and get", fn: ticketsCreateGet},
+		{name: "not found", fn: ticketsNotFound},
+		{name: "ref lookup", fn: ticketsRefLookup},
+		{name: "outdated refs removed", fn: ticketsOutdatedRefs},
+		{name: "list", fn: ticketsList},
+		{name: "delete", fn: ticketsDelete},
+		{name: "unlink", fn: ticketsUnlink},
+	}
+
+	for _, tt := range tbl {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) { tt.fn(t, newTickets(t)) })
+	}
+}
+
+// TestTicketsStaleRef checks that unlinking by the stale ref, which points to
+// the ticket without such variation, removes the ref and leaves the ticket
+// intact. putRef must put the ref directly into the storage, as the engine
+// itself must not produce stale refs.
+func TestTicketsStaleRef(t *testing.T, svc engine.Tickets, putRef func(locator store.Locator, ticketID string)) {
+	ctx := context.Background()
+
+	tkt := store.Ticket{ID: "id", Variations: store.Variations{"tracker-1": {ID: "task-id-1"}}}
+	require.NoError(t, svc.Update(ctx, tkt))
+	since := time.Now()
+
+	stale := store.Locator{Tracker: "tracker-1", ID: "stale-task-id"}
+	putRef(stale, tkt.ID)
+
+	require.NoError(t, svc.Unlink(ctx, stale))
+
+	_, err := svc.Get(ctx, engine.GetRequest{Locator: stale})
+	assert.ErrorIs(t, err, errs.ErrNotFound, "stale ref must be removed")
+
+	res, err := svc.Get(ctx, engine.GetRequest{TicketID: tkt.ID})
+	require.NoError(t, err)
+	assert.Equal(t, tkt, res)
+
+	updated, err := svc.List(ctx, engine.ListRequest{UpdatedSince: since})
+	require.NoError(t, err)
+	assert.Empty(t, updated, "ticket must not be updated")
+}
+
+// TestSubscriptions runs the conformance suite over the engine.Subscriptions.
+// newSubs must return a fresh empty storage on each call.
+func TestSubscriptions(t *testing.T, newSubs func(t *testing.T) engine.Subscriptions) {
+	tbl := []struct {
+		name string
+		fn   func(t *testing.T, svc engine.Subscriptions)
+	}{
+		{name: "create and get", fn: subsCreateGet},
+		{name: "not found", fn: subsNotFound},
+		{name: "uniqueness", fn: subsUniqueness},
+		{name: "update", fn: subsUpdate},
+		{name: "list", fn: subsList},
+		{name: "delete", fn: subsDelete},
+	}
+
+	for _, tt := range tbl {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) { tt.fn(t, newSubs(t)) })
+	}
+}
+
+// TestRuns runs the conformance suite over the engine.Runs.
+// newRuns must return a fresh empty storage on each call.
+func TestRuns(t *testing.T, newRuns func(t *testing.T) engine.Runs) {
+	tbl := []struct {
+		name string
+		fn   func(t *testing.T, svc engine.Runs)
+	}{
+		{name: "create and get", fn: runsCreateGet},
+		{name: "not found", fn: runsNotFound},
+		{name: "list", fn: runsList},
+		{name: "cleanup", fn: runsCleanup},
+	}
+
+	for _, tt := range tbl {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) { tt.fn(t, newRuns(t)) })
+	}
+}
+
+func ticketsCreateGet(t *testing.T, svc engine.Tickets) {
+	tkt := store.Ticket{Variations: store.Variations{
+		"tracker-1": {ID: "task-id-1", Content: store.Content{Body: "body-1", Title: "title-1"}},
+		"tracker-2": {ID: "task-id-2", Content: store.Content{
+			Body: "body-2", Title: "title-2", Fields: store.TicketFields{"f": "v"},
+		}},
+	}}
+
+	id, err := svc.Create(context.Background(), tkt)
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+
+	tkt.ID = id
+
+	res, err := svc.Get(context.Background(), engine.GetRequest{TicketID: id})
+	require.NoError(t, err)
+	assert.Equal(t, tkt, res)
+
+	otherID, err := svc.Create(context.Background(), store.Ticket{Variations: store.Variations{
+		"tracker-1": {ID: "task-id-3"},
+	}})
+	require.NoError(t, err)
+	assert.NotEqual(t, id, otherID, "ids of created tickets must be unique")
+}
+
+func ticketsNotFound(t *testing.T, svc engine.Tickets) {
+	ctx := context.Background()
+	locator := store.Locator{Tracker: "tracker", ID: "unknown"}
+
+	_, err := svc.Get(ctx, engine.GetRequest{TicketID: "unknown"})
+	assert.ErrorIs(t, err, errs.ErrNotFound, "get by ticket id")
+
+	_, err = svc.Get(ctx, engine.GetRequest{Locator: locator})
+	assert.ErrorIs(t, err, errs.ErrNotFound, "get by locator")
+
+	assert.ErrorIs(t, svc.Delete(ctx, "unknown"), errs.ErrNotFound, "delete")
+	assert.ErrorIs(t, svc.Unlink(ctx, locator), errs.ErrNotFound, "unlink")
+
+	res, err := svc.List(ctx, engine.ListRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, res, "list")
+}
+
+func ticketsRefLookup(t *testing.T, svc engine.Tickets) {
+	tkt := store.Ticket{ID: "id", Variations: store.Variations{
+		"tracker-1": {ID: "task-id-1"},
+		"tracker-2": {ID: "task-id-2"},
+	}}
+	require.NoError(t, svc.Update(context.Background(), tkt))
+
+	for _, locator := range tkt.Variations.Locators() {
+		res, err := svc.Get(context.Background(), engine.GetRequest{Locator: locator})
+		require.NoError(t, err, "get by locator %s", locator)
+		assert.Equal(t, tkt, res, "get by locator %s", locator)
+	}
+
+	// task ids are unique only within the tracker
+	_, err := svc.Get(context.Background(), engine.GetRequest{
+		Locator: store.Locator{Tracker: "tracker-2", ID: "task-id-1"},
+	})
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+}
+
+func ticketsOutdatedRefs(t *testing.T, svc engine.Tickets) {
+	tkt := store.Ticket{ID: "id", Variations: store.Variations{
+		"tracker-1": {ID: "task-id-1"},
+		"tracker-2": {ID: "task-id-2"},
+	}}
+	require.NoError(t, svc.Update(context.Background(), tkt))
+
+	tkt.Variations = store.Variations{
+		"tracker-1": {ID: "task-id-1-new"},
+		"tracker-3": {ID: "task-id-3"},
+	}
+	require.NoError(t, svc.Update(context.Background(), tkt))
+
+	for _, locator := range []store.Locator{
+		{Tracker: "tracker-1", ID: "task-id-1"},
+		{Tracker: "tracker-2", ID: "task-id-2"},
+	} {
+		_, err := svc.Get(context.Background(), engine.GetRequest{Locator: locator})
+		assert.ErrorIs(t, err, errs.ErrNotFound, "outdated ref %s must be removed", locator)
+	}
+
+	for _, locator := range tkt.Variations.Locators() {
+		res, err := svc.Get(context.Background(), engine.GetRequest{Locator: locator})
+		require.NoError(t, err, "get by locator %s", locator)
+		assert.Equal(t, tkt, res, "get by locator %s", locator)
+	}
+}
+
+func ticketsList(t *testing.T, svc engine.Tickets) {
+	tickets := []store.Ticket{
+		{ID: "1", Variations: store.Variations{
+			"tracker-1": {ID: "task-1", Content: store.Content{Fields: store.TicketFields{"status": "open"}}},
+		}},
+		{ID: "2", Variations: store.Variations{
+			"tracker-1": {ID: "task-2", Content: store.Content{Fields: store.TicketFields{"status": "closed"}}},
+			"tracker-2": {ID: "task-2", Content: store.Content{Fields: store.TicketFields{"status": "open"}}},
+		}},
+		{ID: "3", Variations: store.Variations{
+			"tracker-2": {ID: "task-3", Content: store.Content{Fields: store.TicketFields{"status": "open"}}},
+		}},
+	}
+
+	for _, tkt := range tickets {
+		require.NoError(t, svc.Update(context.Background(), tkt))
+	}
+
+	since := time.Now()
+
+	tickets[2].Variations.Set("tracker-3", store.Task{ID: "task-3"})
+	require.NoError(t, svc.Update(context.Background(), tickets[2]))
+
+	tbl := []struct {
+		name string
+		req  engine.ListRequest
+		want []store.Ticket
+	}{
+		{name: "all", req: engine.ListRequest{}, want: tickets},
+		{name: "by tracker", req: engine.ListRequest{Tracker: "tracker-2"}, want: tickets[1:]},
+		{
+			name: "by fields",
+			req:  engine.ListRequest{Fields: store.TicketFields{"status": "open"}},
+			want: tickets,
+		},
+		{
+			name: "by fields in tracker",
+			req:  engine.ListRequest{Tracker: "tracker-1", Fields: store.TicketFields{"status": "open"}},
+			want: tickets[:1],
+		},
+		{name: "updated since", req: engine.ListRequest{UpdatedSince: since}, want: tickets[2:]},
+		{name: "limit", req: engine.ListRequest{Limit: 2}, want: tickets[:2]},
+		{name: "offset", req: engine.ListRequest{Offset: 1, Limit: 1}, want: tickets[1:2]},
+		{name: "offset out of range", req: engine.ListRequest{Offset: 5}, want: nil},
+		{name: "after id", req: engine.ListRequest{AfterID: "1", Limit: 1}, want: tickets[1:2]},
+		{name: "after missing id", req: engine.ListRequest{AfterID: "10"}, want: tickets[1:]},
+		{name: "after last id", req: engine.ListRequest{AfterID: "3"}, want: nil},
+	}
+
+	for _, tt := range tbl {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := svc.List(context.Background(), tt.req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func ticketsDelete(t *testing.T, svc engine.Tickets) {
+	tkt := store.Ticket{ID: "id", Variations: store.Variations{
+		"tracker-1": {ID: "task-id-1"},
+		"tracker-2": {ID: "task-id-2"},
+	}}
+	require.NoError(t, svc.Update(context.Background(), tkt))
+
+	require.NoError(t, svc.Delete(context.Background(), "id"))
+
+	_, err := svc.Get(context.Background(), engine.GetRequest{TicketID: "id"})
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+
+	for _, locator := range tkt.Variations.Locators() {
+		_, err = svc.Get(context.Background(), engine.GetRequest{Locator: locator})
+		assert.ErrorIs(t, err, errs.ErrNotFound, "ref %s must be removed", locator)
+	}
+}
+
+func ticketsUnlink(t *testing.T, svc engine.Tickets) {
+	tkt := store.Ticket{ID: "id", Variations: store.Variations{
+		"tracker-1": {ID: "task-id-1"},
+		"tracker-2": {ID: "task-id-2"},
+	}}
+	require.NoError(t, svc.Update(context.Background(), tkt))
+
+	require.NoError(t, svc.Unlink(context.Background(), store.Locator{Tracker: "tracker-1", ID: "task-id-1"}))
+
+	res, err := svc.Get(context.Background(), engine.GetRequest{TicketID: "id"})
+	require.NoError(t, err)
+	assert.Equal(t, store.Ticket{ID: "id", Variations: store.Variations{
+		"tracker-2": {ID: "task-id-2"},
+	}}, res)
+
+	_, err = svc.Get(context.Background(), engine.GetRequest{
+		Locator: store.Locator{Tracker: "tracker-1", ID: "task-id-1"},
+	})
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+
+	// the last variation is unlinked, the ticket must be removed
+	require.NoError(t, svc.Unlink(context.Background(), store.Locator{Tracker: "tracker-2", ID: "task-id-2"}))
+
+	_, err = svc.Get(context.Background(), engine.GetRequest{TicketID: "id"})
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+}
+
+func subsCreateGet(t *testing.T, svc engine.Subscriptions) {
+	sub := store.Subscription{
+		TrackerRef:  "tracker-ref",
+		TrackerName: "tracker-name",
+		TriggerName: "trigger-name",
+		BaseURL:     "base-url",
+	}
+
+	id, err := svc.Create(context.Background(), sub)
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+
+	sub.ID = id
+	res, err := svc.Get(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, sub, res)
+}
+
+func subsNotFound(t *testing.T, svc engine.Subscriptions) {
+	_, err := svc.Get(context.Background(), "unknown")
+	assert.ErrorIs(t, err, errs.ErrNotFound, "get")
+
+	assert.ErrorIs(t, svc.Delete(context.Background(), "unknown"), errs.ErrNotFound, "delete")
+
+	res, err := svc.List(context.Background(), "unknown")
+	require.NoError(t, err)
+	assert.Empty(t, res, "list")
+}
+
+func subsUniqueness(t *testing.T, svc engine.Subscriptions) {
+	sub := store.Subscription{TrackerName: "tracker-name", TriggerName: "trigger-name"}
+
+	id, err := svc.Create(context.Background(), sub)
+	require.NoError(t, err)
+
+	_, err = svc.Create(context.Background(), sub)
+	assert.ErrorIs(t, err, errs.ErrExists, "tracker:trigger pair must be unique")
+
+	_, err = svc.Create(context.Background(), store.Subscription{
+		TrackerName: "tracker-name",
+		TriggerName: "other-trigger-name",
+	})
+	require.NoError(t, err, "the same tracker might be used by several triggers")
+
+	require.NoError(t, svc.Delete(context.Background(), id))
+
+	_, err = svc.Create(context.Background(), sub)
+	require.NoError(t, err, "tracker:trigger pair must be released on delete")
+}
+
+func subsUpdate(t *testing.T, svc engine.Subscriptions) {
+	id, err := svc.Create(context.Background(), store.Subscription{
+		TrackerName: "tracker-name",
+		TriggerName: "trigger-name",
+	})
+	require.NoError(t, err)
+
+	sub := store.Subscription{
+		ID:          id,
+		TrackerRef:  "tracker-ref",
+		TrackerName: "tracker-name",
+		TriggerName: "trigger-name",
+		BaseURL:     "base-url",
+	}
+	require.NoError(t, svc.Update(context.Background(), sub))
+
+	res, err := svc.Get(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, sub, res)
+
+	subs, err := svc.List(context.Background(), "tracker-name")
+	require.NoError(t, err)
+	assert.Equal(t, []store.Subscription{sub}, subs)
+}
+
+func subsList(t *testing.T, svc engine.Subscriptions) {
+	var expected []store.Subscription
+	for i := 1; i <= 5; i++ {
+		sub := store.Subscription{
+			TrackerRef:  fmt.Sprintf("%d-tracker-ref", i),
+			TrackerName: fmt.Sprintf("%d-tracker-name", i%2),
+			TriggerName: fmt.Sprintf("%d-trigger-name", i),
+			BaseURL:     fmt.Sprintf("%d-base-url", i),
+		}
+
+		var err error
+		sub.ID, err = svc.Create(context.Background(), sub)
+		require.NoError(t, err)
+
+		expected = append(expected, sub)
+	}
+
+	subs, err := svc.List(context.Background(), "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, expected, subs)
+
+	subs, err = svc.List(context.Background(), "0-tracker-name")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []store.Subscription{expected[1], expected[3]}, subs)
+}
+
+func subsDelete(t *testing.T, svc engine.Subscriptions) {
+	id, err := svc.Create(context.Background(), store.Subscription{
+		TrackerName: "tracker-name",
+		TriggerName: "trigger-name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Delete(context.Background(), id))
+
+	_, err = svc.Get(context.Background(), id)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+
+	subs, err := svc.List(context.Background(), "tracker-name")
+	require.NoError(t, err)
+	assert.Empty(t, subs)
+}
+
+func runsCreateGet(t *testing.T, svc engine.Runs) {
+	run := store.JobRun{
+		TriggerName: "trigger",
+		JobName:     "job",
+		Update:      store.Locator{Tracker: "tracker-1", ID: "task-id-1"},
+		TicketID:    "ticket-id",
+		StartedAt:   time.Date(2021, 11, 3, 12, 0, 0, 0, time.UTC),
+		Duration:    2 * time.Second,
+		Steps: []store.StepRun{
+			{
+				Condition: "{{ eq .Update.Title \"title\" }}",
+				Result:    true,
+				StartedAt: time.Date(2021, 11, 3, 12, 0, 0, 0, time.UTC),
+				Duration:  time.Millisecond,
+			},
+			{
+				Depth:     1,
+				Action:    "tracker-2/Create",
+				Vars:      lib.Vars{"title": "title"},
+				Response:  &store.Task{ID: "task-id-2", Content: store.Content{Title: "title"}},
+				StartedAt: time.Date(2021, 11, 3, 12, 0, 1, 0, time.UTC),
+				Duration:  time.Second,
+			},
+		},
+		Error: "some error",
+	}
+
+	id, err := svc.Create(context.Background(), run)
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+
+	run.ID = id
+
+	res, err := svc.Get(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, run, res)
+
+	// the ID, assigned in advance, is kept
+	run.ID = "run-id"
+	id, err = svc.Create(context.Background(), run)
+	require.NoError(t, err)
+	assert.Equal(t, "run-id", id)
+
+	res, err = svc.Get(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, run, res)
+}
+
+func runsNotFound(t *testing.T, svc engine.Runs) {
+	_, err := svc.Get(context.Background(), "non-existing")
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+}
+
+func runsList(t *testing.T, svc engine.Runs) {
+	base := time.Date(2021, 11, 3, 12, 0, 0, 0, time.UTC)
+
+	var ids []string
+	for i, run := range []store.JobRun{
+		{JobName: "job-1", TriggerName: "trigger-1", Update: store.Locator{Tracker: "tracker-1", ID: "task-1"}},
+		{JobName: "job-2", TriggerName: "trigger-1", Update: store.Locator{Tracker: "tracker-2", ID: "task-2"}},
+		{JobName: "job-1", TriggerName: "trigger-2", Update: store.Locator{Tracker: "tracker-1", ID: "task-2"},
+			Error: "failed"},
+		{JobName: "job-2", TriggerName: "trigger-2", Update: store.Locator{Tracker: "tracker-2", ID: "task-1"},
+			TicketID: "ticket-1"},
+	} {
+		run.StartedAt = base.Add(time.Duration(i) * time.Minute)
+		id, err := svc.Create(context.Background(), run)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	tbl := []struct {
+		req  engine.ListRunsRequest
+		want []string
+	}{
+		{req: engine.ListRunsRequest{}, want: []string{ids[3], ids[2], ids[1], ids[0]}},
+		{req: engine.ListRunsRequest{JobName: "job-1"}, want: []string{ids[2], ids[0]}},
+		{req: engine.ListRunsRequest{TriggerName: "trigger-1"}, want: []string{ids[1], ids[0]}},
+		{req: engine.ListRunsRequest{Update: store.Locator{Tracker: "tracker-2"}}, want: []string{ids[3], ids[1]}},
+		{req: engine.ListRunsRequest{Update: store.Locator{ID: "task-2"}}, want: []string{ids[2], ids[1]}},
+		{req: engine.ListRunsRequest{TicketID: "ticket-1"}, want: []string{ids[3]}},
+		{req: engine.ListRunsRequest{OnlyFailed: true}, want: []string{ids[2]}},
+		{req: engine.ListRunsRequest{Since: base.Add(2 * time.Minute)}, want: []string{ids[3], ids[2]}},
+		{req: engine.ListRunsRequest{Offset: 1, Limit: 2}, want: []string{ids[2], ids[1]}},
+		{req: engine.ListRunsRequest{JobName: "job-3"}, want: nil},
+	}
+
+	for i, tt := range tbl {
+		tt := tt
+		t.Run(fmt.Sprintf("request %d", i), func(t *testing.T) {
+			res, err := svc.List(context.Background(), tt.req)
+			require.NoError(t, err)
+
+			var got []string
+			for _, run := range res {
+				got = append(got, run.ID)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func runsCleanup(t *testing.T, svc engine.Runs) {
+	base := time.Date(2021, 11, 3, 12, 0, 0, 0, time.UTC)
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		id, err := svc.Create(context.Background(), store.JobRun{
+			JobName:   "job",
+			StartedAt: base.Add(time.Duration(i) * time.Minute),
+		})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	listIDs := func() []string {
+		res, err := svc.List(context.Background(), engine.ListRunsRequest{})
+		require.NoError(t, err)
+
+		var got []string
+		for _, run := range res {
+			got = append(got, run.ID)
+		}
+		return got
+	}
+
+	removed, err := svc.Cleanup(context.Background(), engine.CleanupRunsRequest{})
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+
+	removed, err = svc.Cleanup(context.Background(), engine.CleanupRunsRequest{Before: base.Add(time.Minute)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, []string{ids[4], ids[3], ids[2], ids[1]}, listIDs())
+
+	removed, err = svc.Cleanup(context.Background(), engine.CleanupRunsRequest{Keep: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, []string{ids[4], ids[3]}, listIDs())
+
+	_, err = svc.Get(context.Background(), ids[1])
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+}
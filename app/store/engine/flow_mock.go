@@ -16,37 +16,48 @@ var _ Flow = &FlowMock{}
 
 // FlowMock is a mock implementation of Flow.
 //
-// 	func TestSomethingThatUsesFlow(t *testing.T) {
+//	func TestSomethingThatUsesFlow(t *testing.T) {
 //
-// 		// make and configure a mocked Flow
-// 		mockedFlow := &FlowMock{
-// 			ListSubscribedJobsFunc: func(ctx context.Context, triggerName string) ([]store.Job, error) {
-// 				panic("mock out the ListSubscribedJobs method")
-// 			},
-// 			ListTrackersFunc: func(contextMoqParam context.Context) ([]store.Tracker, error) {
-// 				panic("mock out the ListTrackers method")
-// 			},
-// 			ListTriggersFunc: func(contextMoqParam context.Context) ([]store.Trigger, error) {
-// 				panic("mock out the ListTriggers method")
-// 			},
-// 		}
+//		// make and configure a mocked Flow
+//		mockedFlow := &FlowMock{
+//			ListJobsFunc: func(ctx context.Context) ([]store.Job, error) {
+//				panic("mock out the ListJobs method")
+//			},
+//			ListSubscribedJobsFunc: func(ctx context.Context, triggerName string) ([]store.Job, error) {
+//				panic("mock out the ListSubscribedJobs method")
+//			},
+//			ListTrackersFunc: func(ctx context.Context) ([]store.Tracker, error) {
+//				panic("mock out the ListTrackers method")
+//			},
+//			ListTriggersFunc: func(ctx context.Context) ([]store.Trigger, error) {
+//				panic("mock out the ListTriggers method")
+//			},
+//		}
 //
-// 		// use mockedFlow in code that requires Flow
-// 		// and then make assertions.
+//		// use mockedFlow in code that requires Flow
+//		// and then make assertions.
 //
-// 	}
+//	}
 type FlowMock struct {
+	// ListJobsFunc mocks the ListJobs method.
+	ListJobsFunc func(ctx context.Context) ([]store.Job, error)
+
 	// ListSubscribedJobsFunc mocks the ListSubscribedJobs method.
 	ListSubscribedJobsFunc func(ctx context.Context, triggerName string) ([]store.Job, error)
 
 	// ListTrackersFunc mocks the ListTrackers method.
-	ListTrackersFunc func(contextMoqParam context.Context) ([]store.Tracker, error)
+	ListTrackersFunc func(ctx context.Context) ([]store.Tracker, error)
 
 	// ListTriggersFunc mocks the ListTriggers method.
-	ListTriggersFunc func(contextMoqParam context.Context) ([]store.Trigger, error)
+	ListTriggersFunc func(ctx context.Context) ([]store.Trigger, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// ListJobs holds details about calls to the ListJobs method.
+		ListJobs []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
 		// ListSubscribedJobs holds details about calls to the ListSubscribedJobs method.
 		ListSubscribedJobs []struct {
 			// Ctx is the ctx argument value.
@@ -56,20 +67,53 @@ type FlowMock struct {
 		}
 		// ListTrackers holds details about calls to the ListTrackers method.
 		ListTrackers []struct {
-			// ContextMoqParam is the contextMoqParam argument value.
-			ContextMoqParam context.Context
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 		}
 		// ListTriggers holds details about calls to the ListTriggers method.
 		ListTriggers []struct {
-			// ContextMoqParam is the contextMoqParam argument value.
-			ContextMoqParam context.Context
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 		}
 	}
+	lockListJobs           sync.RWMutex
 	lockListSubscribedJobs sync.RWMutex
 	lockListTrackers       sync.RWMutex
 	lockListTriggers       sync.RWMutex
 }
 
+// ListJobs calls ListJobsFunc.
+func (mock *FlowMock) ListJobs(ctx context.Context) ([]store.Job, error) {
+	if mock.ListJobsFunc == nil {
+		panic("FlowMock.ListJobsFunc: method is nil but Flow.ListJobs was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockListJobs.Lock()
+	mock.calls.ListJobs = append(mock.calls.ListJobs, callInfo)
+	mock.lockListJobs.Unlock()
+	return mock.ListJobsFunc(ctx)
+}
+
+// ListJobsCalls gets all the calls that were made to ListJobs.
+// Check the length with:
+//
+//	len(mockedFlow.ListJobsCalls())
+func (mock *FlowMock) ListJobsCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockListJobs.RLock()
+	calls = mock.calls.ListJobs
+	mock.lockListJobs.RUnlock()
+	return calls
+}
+
 // ListSubscribedJobs calls ListSubscribedJobsFunc.
 func (mock *FlowMock) ListSubscribedJobs(ctx context.Context, triggerName string) ([]store.Job, error) {
 	if mock.ListSubscribedJobsFunc == nil {
@@ -90,7 +134,8 @@ func (mock *FlowMock) ListSubscribedJobs(ctx context.Context, triggerName string
 
 // ListSubscribedJobsCalls gets all the calls that were made to ListSubscribedJobs.
 // Check the length with:
-//     len(mockedFlow.ListSubscribedJobsCalls())
+//
+//	len(mockedFlow.ListSubscribedJobsCalls())
 func (mock *FlowMock) ListSubscribedJobsCalls() []struct {
 	Ctx         context.Context
 	TriggerName string
@@ -106,29 +151,30 @@ func (mock *FlowMock) ListSubscribedJobsCalls() []struct {
 }
 
 // ListTrackers calls ListTrackersFunc.
-func (mock *FlowMock) ListTrackers(contextMoqParam context.Context) ([]store.Tracker, error) {
+func (mock *FlowMock) ListTrackers(ctx context.Context) ([]store.Tracker, error) {
 	if mock.ListTrackersFunc == nil {
 		panic("FlowMock.ListTrackersFunc: method is nil but Flow.ListTrackers was just called")
 	}
 	callInfo := struct {
-		ContextMoqParam context.Context
+		Ctx context.Context
 	}{
-		ContextMoqParam: contextMoqParam,
+		Ctx: ctx,
 	}
 	mock.lockListTrackers.Lock()
 	mock.calls.ListTrackers = append(mock.calls.ListTrackers, callInfo)
 	mock.lockListTrackers.Unlock()
-	return mock.ListTrackersFunc(contextMoqParam)
+	return mock.ListTrackersFunc(ctx)
 }
 
 // ListTrackersCalls gets all the calls that were made to ListTrackers.
 // Check the length with:
-//     len(mockedFlow.ListTrackersCalls())
+//
+//	len(mockedFlow.ListTrackersCalls())
 func (mock *FlowMock) ListTrackersCalls() []struct {
-	ContextMoqParam context.Context
+	Ctx context.Context
 } {
 	var calls []struct {
-		ContextMoqParam context.Context
+		Ctx context.Context
 	}
 	mock.lockListTrackers.RLock()
 	calls = mock.calls.ListTrackers
@@ -137,29 +183,30 @@ func (mock *FlowMock) ListTrackersCalls() []struct {
 }
 
 // ListTriggers calls ListTriggersFunc.
-func (mock *FlowMock) ListTriggers(contextMoqParam context.Context) ([]store.Trigger, error) {
+func (mock *FlowMock) ListTriggers(ctx context.Context) ([]store.Trigger, error) {
 	if mock.ListTriggersFunc == nil {
 		panic("FlowMock.ListTriggersFunc: method is nil but Flow.ListTriggers was just called")
 	}
 	callInfo := struct {
-		ContextMoqParam context.Context
+		Ctx context.Context
 	}{
-		ContextMoqParam: contextMoqParam,
+		Ctx: ctx,
 	}
 	mock.lockListTriggers.Lock()
 	mock.calls.ListTriggers = append(mock.calls.ListTriggers, callInfo)
 	mock.lockListTriggers.Unlock()
-	return mock.ListTriggersFunc(contextMoqParam)
+	return mock.ListTriggersFunc(ctx)
 }
 
 // ListTriggersCalls gets all the calls that were made to ListTriggers.
 // Check the length with:
-//     len(mockedFlow.ListTriggersCalls())
+//
+//	len(mockedFlow.ListTriggersCalls())
 func (mock *FlowMock) ListTriggersCalls() []struct {
-	ContextMoqParam context.Context
+	Ctx context.Context
 } {
 	var calls []struct {
-		ContextMoqParam context.Context
+		Ctx context.Context
 	}
 	mock.lockListTriggers.RLock()
 	calls = mock.calls.ListTriggers
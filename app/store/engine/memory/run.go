@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/google/uuid"
+)
+
+// Runs implements engine.Runs over the in-memory slice.
+type Runs struct {
+	mu   sync.RWMutex
+	runs []store.JobRun // ordered by start time, the most recent first
+}
+
+// NewRuns makes new instance of Runs.
+func NewRuns() *Runs { return &Runs{} }
+
+// Create puts the run to the storage.
+func (m *Runs) Create(_ context.Context, run store.JobRun) (string, error) {
+	run, err := copyRun(run)
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if run.ID == "" {
+		run.ID = uuid.NewString()
+	}
+
+	idx := sort.Search(len(m.runs), func(i int) bool { return m.runs[i].StartedAt.Before(run.StartedAt) })
+	m.runs = append(m.runs, store.JobRun{})
+	copy(m.runs[idx+1:], m.runs[idx:])
+	m.runs[idx] = run
+
+	return run.ID, nil
+}
+
+// Get returns the run by its ID.
+func (m *Runs) Get(_ context.Context, runID string) (store.JobRun, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, run := range m.runs {
+		if run.ID == runID {
+			return copyRun(run)
+		}
+	}
+
+	return store.JobRun{}, fmt.Errorf("run %s not found: %w", runID, errs.ErrNotFound)
+}
+
+// List returns runs matching the given criteria, the most recent first.
+func (m *Runs) List(_ context.Context, req engine.ListRunsRequest) ([]store.JobRun, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var res []store.JobRun
+	skipped := 0
+
+	for _, run := range m.runs {
+		if req.Limit > 0 && len(res) >= req.Limit {
+			break
+		}
+
+		if !req.Match(run) {
+			continue
+		}
+
+		if skipped < req.Offset {
+			skipped++
+			continue
+		}
+
+		run, err := copyRun(run)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, run)
+	}
+
+	return res, nil
+}
+
+// Cleanup removes runs according to the retention policy.
+func (m *Runs) Cleanup(_ context.Context, req engine.CleanupRunsRequest) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keep := len(m.runs)
+	if req.Keep > 0 && req.Keep < keep {
+		keep = req.Keep
+	}
+
+	if !req.Before.IsZero() {
+		keep = sort.Search(keep, func(i int) bool { return m.runs[i].StartedAt.Before(req.Before) })
+	}
+
+	removed := len(m.runs) - keep
+	m.runs = m.runs[:keep:keep]
+	return removed, nil
+}
+
+// copyRun makes a deep copy of the run, so that the caller
+// is not able to modify the stored value.
+func copyRun(run store.JobRun) (store.JobRun, error) {
+	bts, err := json.Marshal(run)
+	if err != nil {
+		return store.JobRun{}, fmt.Errorf("marshal run: %w", err)
+	}
+
+	var res store.JobRun
+	if err = json.Unmarshal(bts, &res); err != nil {
+		return store.JobRun{}, fmt.Errorf("unmarshal run: %w", err)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,12 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/app/store/engine/enginetest"
+)
+
+func TestRuns(t *testing.T) {
+	enginetest.TestRuns(t, func(t *testing.T) engine.Runs { return NewRuns() })
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/google/uuid"
+)
+
+// Subscriptions implements engine.Subscriptions over in-memory maps.
+type Subscriptions struct {
+	mu   sync.RWMutex
+	subs map[string]store.Subscription // map[subscriptionID]subscription
+	refs map[string]string             // map["trackerName:triggerName"]subscriptionID
+}
+
+// NewSubscriptions makes new instance of Subscriptions.
+func NewSubscriptions() *Subscriptions {
+	return &Subscriptions{
+		subs: map[string]store.Subscription{},
+		refs: map[string]string{},
+	}
+}
+
+// Create creates a subscription in the storage.
+func (m *Subscriptions) Create(_ context.Context, sub store.Subscription) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sub.ID = uuid.NewString()
+
+	ref := trackerTriggerRef(sub)
+	if _, exists := m.refs[ref]; exists {
+		return "", fmt.Errorf("subscription %s is already assigned to %q tracker:trigger pair: %w",
+			sub.ID, ref, errs.ErrExists)
+	}
+
+	m.put(sub)
+	return sub.ID, nil
+}
+
+// Update totally rewrites the provided subscription entry.
+func (m *Subscriptions) Update(_ context.Context, sub store.Subscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if prev, ok := m.subs[sub.ID]; ok && m.refs[trackerTriggerRef(prev)] == sub.ID {
+		delete(m.refs, trackerTriggerRef(prev))
+	}
+
+	m.put(sub)
+	return nil
+}
+
+// Get subscription by id.
+func (m *Subscriptions) Get(_ context.Context, subID string) (store.Subscription, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sub, ok := m.subs[subID]
+	if !ok {
+		return store.Subscription{}, fmt.Errorf("subscription %s not found: %w", subID, errs.ErrNotFound)
+	}
+	return sub, nil
+}
+
+// Delete subscription by id.
+func (m *Subscriptions) Delete(_ context.Context, subID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sub, ok := m.subs[subID]
+	if !ok {
+		return fmt.Errorf("subscription %s not found: %w", subID, errs.ErrNotFound)
+	}
+
+	if ref := trackerTriggerRef(sub); m.refs[ref] == subID {
+		delete(m.refs, ref)
+	}
+	delete(m.subs, subID)
+	return nil
+}
+
+// List lists the subscriptions registered on the given tracker,
+// or all subscriptions, if the tracker name is empty.
+func (m *Subscriptions) List(_ context.Context, trackerName string) ([]store.Subscription, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var subscriptions []store.Subscription
+	for _, sub := range m.subs {
+		if trackerName == "" || sub.TrackerName == trackerName {
+			subscriptions = append(subscriptions, sub)
+		}
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool { return subscriptions[i].ID < subscriptions[j].ID })
+	return subscriptions, nil
+}
+
+func (m *Subscriptions) put(sub store.Subscription) {
+	m.subs[sub.ID] = sub
+	m.refs[trackerTriggerRef(sub)] = sub.ID
+}
+
+func trackerTriggerRef(sub store.Subscription) string {
+	return fmt.Sprintf("%s:%s", sub.TrackerName, sub.TriggerName)
+}
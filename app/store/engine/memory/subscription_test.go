@@ -0,0 +1,12 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/app/store/engine/enginetest"
+)
+
+func TestSubscriptions(t *testing.T) {
+	enginetest.TestSubscriptions(t, func(t *testing.T) engine.Subscriptions { return NewSubscriptions() })
+}
@@ -0,0 +1,220 @@
+// Package memory implements storage engines, which keep all the data in
+// memory of the process. Data is lost when the process is stopped.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/google/uuid"
+)
+
+// Tickets implements engine.Tickets over in-memory maps.
+type Tickets struct {
+	mu      sync.RWMutex
+	tickets map[string]ticketEntry   // map[ticketID]ticket
+	refs    map[store.Locator]string // map[locator]ticketID
+}
+
+type ticketEntry struct {
+	ticket    store.Ticket
+	updatedAt time.Time
+}
+
+// NewTickets makes new instance of Tickets.
+func NewTickets() *Tickets {
+	return &Tickets{
+		tickets: map[string]ticketEntry{},
+		refs:    map[store.Locator]string{},
+	}
+}
+
+// Create creates ticket in the storage.
+func (m *Tickets) Create(ctx context.Context, ticket store.Ticket) (string, error) {
+	ticket.ID = uuid.NewString()
+
+	if err := m.Update(ctx, ticket); err != nil {
+		return "", fmt.Errorf("put ticket into storage: %w", err)
+	}
+
+	return ticket.ID, nil
+}
+
+// Update updates ticket by its ID.
+func (m *Tickets) Update(_ context.Context, ticket store.Ticket) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	locators := ticket.Variations.Locators()
+
+	// remove outdated references, e.g. if the ID of the variation has changed
+	if prev, ok := m.tickets[ticket.ID]; ok {
+		actual := map[store.Locator]struct{}{}
+		for _, locator := range locators {
+			actual[locator] = struct{}{}
+		}
+
+		for _, locator := range prev.ticket.Variations.Locators() {
+			if _, ok := actual[locator]; !ok {
+				m.deleteRef(ticket.ID, locator)
+			}
+		}
+	}
+
+	for _, locator := range locators {
+		m.refs[locator] = ticket.ID
+	}
+
+	m.tickets[ticket.ID] = ticketEntry{ticket: copyTicket(ticket), updatedAt: time.Now()}
+	return nil
+}
+
+// Get returns ticket by the given engine.GetRequest.
+func (m *Tickets) Get(_ context.Context, req engine.GetRequest) (store.Ticket, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ticketID := req.TicketID
+
+	if ticketID == "" && !req.Locator.Empty() {
+		var ok bool
+		if ticketID, ok = m.refs[req.Locator]; !ok {
+			return store.Ticket{}, fmt.Errorf("get ticket id by ref %q: %w",
+				req.Locator.String(), errs.ErrNotFound)
+		}
+	}
+
+	entry, ok := m.tickets[ticketID]
+	if !ok {
+		return store.Ticket{}, fmt.Errorf("ticket %s not found: %w", ticketID, errs.ErrNotFound)
+	}
+
+	return copyTicket(entry.ticket), nil
+}
+
+// List returns tickets, that match the given engine.ListRequest.
+func (m *Tickets) List(_ context.Context, req engine.ListRequest) ([]store.Ticket, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]string, 0, len(m.tickets))
+	for id := range m.tickets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var tickets []store.Ticket
+	skipped := 0
+	for _, id := range ids {
+		if req.Limit > 0 && len(tickets) >= req.Limit {
+			break
+		}
+
+		if req.AfterID != "" && id <= req.AfterID {
+			continue
+		}
+
+		entry := m.tickets[id]
+		if !req.Match(entry.ticket, entry.updatedAt) {
+			continue
+		}
+
+		if skipped < req.Offset {
+			skipped++
+			continue
+		}
+
+		tickets = append(tickets, copyTicket(entry.ticket))
+	}
+
+	return tickets, nil
+}
+
+// Delete removes the ticket by its ID along with all its refs.
+func (m *Tickets) Delete(_ context.Context, ticketID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.tickets[ticketID]
+	if !ok {
+		return fmt.Errorf("ticket %s not found: %w", ticketID, errs.ErrNotFound)
+	}
+
+	m.delete(entry.ticket)
+	return nil
+}
+
+// Unlink removes the variation, located by the given locator, from its ticket.
+func (m *Tickets) Unlink(_ context.Context, locator store.Locator) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ticketID, ok := m.refs[locator]
+	if !ok {
+		return fmt.Errorf("get ticket id by ref %q: %w", locator.String(), errs.ErrNotFound)
+	}
+
+	entry, ok := m.tickets[ticketID]
+	if !ok {
+		return fmt.Errorf("ticket %s not found: %w", ticketID, errs.ErrNotFound)
+	}
+
+	m.deleteRef(ticketID, locator)
+
+	// the ref is stale, the ticket itself is not changed
+	if task := entry.ticket.Variations.Get(locator.Tracker); task.ID != locator.ID {
+		return nil
+	}
+	delete(entry.ticket.Variations, locator.Tracker)
+
+	if len(entry.ticket.Variations) == 0 {
+		m.delete(entry.ticket)
+		return nil
+	}
+
+	entry.updatedAt = time.Now()
+	m.tickets[ticketID] = entry
+	return nil
+}
+
+func (m *Tickets) delete(ticket store.Ticket) {
+	for _, locator := range ticket.Variations.Locators() {
+		m.deleteRef(ticket.ID, locator)
+	}
+	delete(m.tickets, ticket.ID)
+}
+
+// deleteRef removes the reference only if it points to the given ticket.
+func (m *Tickets) deleteRef(ticketID string, locator store.Locator) {
+	if m.refs[locator] == ticketID {
+		delete(m.refs, locator)
+	}
+}
+
+// copyTicket makes a deep copy of the ticket, so that the caller
+// is not able to modify the stored one.
+func copyTicket(ticket store.Ticket) store.Ticket {
+	if ticket.Variations == nil {
+		return ticket
+	}
+
+	variations := make(store.Variations, len(ticket.Variations))
+	for tracker, task := range ticket.Variations {
+		if task.Fields != nil {
+			fields := make(store.TicketFields, len(task.Fields))
+			for k, v := range task.Fields {
+				fields[k] = v
+			}
+			task.Fields = fields
+		}
+		variations[tracker] = task
+	}
+	ticket.Variations = variations
+	return ticket
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/app/store/engine/enginetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTickets(t *testing.T) {
+	enginetest.TestTickets(t, func(t *testing.T) engine.Tickets { return NewTickets() })
+}
+
+func TestTickets_StaleRef(t *testing.T) {
+	svc := NewTickets()
+	enginetest.TestTicketsStaleRef(t, svc, func(locator store.Locator, ticketID string) {
+		svc.mu.Lock()
+		defer svc.mu.Unlock()
+		svc.refs[locator] = ticketID
+	})
+}
+
+func TestTickets_Isolation(t *testing.T) {
+	svc := NewTickets()
+
+	tkt := store.Ticket{ID: "id", Variations: map[string]store.Task{
+		"tracker-1": {ID: "task-id-1", Content: store.Content{Fields: store.TicketFields{"f": "v"}}},
+	}}
+	require.NoError(t, svc.Update(context.Background(), tkt))
+
+	// modification of the original ticket must not affect the stored one
+	tkt.Variations["tracker-1"].Fields["f"] = "modified"
+
+	res, err := svc.Get(context.Background(), engine.GetRequest{TicketID: "id"})
+	require.NoError(t, err)
+	assert.Equal(t, "v", res.Variations.Get("tracker-1").Fields["f"])
+
+	// as well as modification of the returned one
+	res.Variations["tracker-1"].Fields["f"] = "modified"
+
+	res, err = svc.Get(context.Background(), engine.GetRequest{TicketID: "id"})
+	require.NoError(t, err)
+	assert.Equal(t, "v", res.Variations.Get("tracker-1").Fields["f"])
+}
+
+func TestTickets_Concurrent(t *testing.T) {
+	svc := NewTickets()
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			locator := store.Locator{Tracker: "tracker", ID: fmt.Sprintf("task-%d", i)}
+
+			id, err := svc.Create(context.Background(), store.Ticket{
+				Variations: map[string]store.Task{locator.Tracker: {ID: locator.ID}},
+			})
+			assert.NoError(t, err)
+
+			tkt, err := svc.Get(context.Background(), engine.GetRequest{Locator: locator})
+			assert.NoError(t, err)
+			assert.Equal(t, id, tkt.ID)
+
+			_, err = svc.List(context.Background(), engine.ListRequest{Tracker: "tracker"})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	tickets, err := svc.List(context.Background(), engine.ListRequest{})
+	require.NoError(t, err)
+	assert.Len(t, tickets, 50)
+}
@@ -0,0 +1,233 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package engine
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cappuccinotm/dastracker/app/store"
+)
+
+// Ensure, that RunsMock does implement Runs.
+// If this is not the case, regenerate this file with moq.
+var _ Runs = &RunsMock{}
+
+// RunsMock is a mock implementation of Runs.
+//
+//	func TestSomethingThatUsesRuns(t *testing.T) {
+//
+//		// make and configure a mocked Runs
+//		mockedRuns := &RunsMock{
+//			CleanupFunc: func(ctx context.Context, req CleanupRunsRequest) (int, error) {
+//				panic("mock out the Cleanup method")
+//			},
+//			CreateFunc: func(ctx context.Context, run store.JobRun) (string, error) {
+//				panic("mock out the Create method")
+//			},
+//			GetFunc: func(ctx context.Context, runID string) (store.JobRun, error) {
+//				panic("mock out the Get method")
+//			},
+//			ListFunc: func(ctx context.Context, req ListRunsRequest) ([]store.JobRun, error) {
+//				panic("mock out the List method")
+//			},
+//		}
+//
+//		// use mockedRuns in code that requires Runs
+//		// and then make assertions.
+//
+//	}
+type RunsMock struct {
+	// CleanupFunc mocks the Cleanup method.
+	CleanupFunc func(ctx context.Context, req CleanupRunsRequest) (int, error)
+
+	// CreateFunc mocks the Create method.
+	CreateFunc func(ctx context.Context, run store.JobRun) (string, error)
+
+	// GetFunc mocks the Get method.
+	GetFunc func(ctx context.Context, runID string) (store.JobRun, error)
+
+	// ListFunc mocks the List method.
+	ListFunc func(ctx context.Context, req ListRunsRequest) ([]store.JobRun, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Cleanup holds details about calls to the Cleanup method.
+		Cleanup []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Req is the req argument value.
+			Req CleanupRunsRequest
+		}
+		// Create holds details about calls to the Create method.
+		Create []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Run is the run argument value.
+			Run store.JobRun
+		}
+		// Get holds details about calls to the Get method.
+		Get []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// RunID is the runID argument value.
+			RunID string
+		}
+		// List holds details about calls to the List method.
+		List []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Req is the req argument value.
+			Req ListRunsRequest
+		}
+	}
+	lockCleanup sync.RWMutex
+	lockCreate  sync.RWMutex
+	lockGet     sync.RWMutex
+	lockList    sync.RWMutex
+}
+
+// Cleanup calls CleanupFunc.
+func (mock *RunsMock) Cleanup(ctx context.Context, req CleanupRunsRequest) (int, error) {
+	if mock.CleanupFunc == nil {
+		panic("RunsMock.CleanupFunc: method is nil but Runs.Cleanup was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Req CleanupRunsRequest
+	}{
+		Ctx: ctx,
+		Req: req,
+	}
+	mock.lockCleanup.Lock()
+	mock.calls.Cleanup = append(mock.calls.Cleanup, callInfo)
+	mock.lockCleanup.Unlock()
+	return mock.CleanupFunc(ctx, req)
+}
+
+// CleanupCalls gets all the calls that were made to Cleanup.
+// Check the length with:
+//
+//	len(mockedRuns.CleanupCalls())
+func (mock *RunsMock) CleanupCalls() []struct {
+	Ctx context.Context
+	Req CleanupRunsRequest
+} {
+	var calls []struct {
+		Ctx context.Context
+		Req CleanupRunsRequest
+	}
+	mock.lockCleanup.RLock()
+	calls = mock.calls.Cleanup
+	mock.lockCleanup.RUnlock()
+	return calls
+}
+
+// Create calls CreateFunc.
+func (mock *RunsMock) Create(ctx context.Context, run store.JobRun) (string, error) {
+	if mock.CreateFunc == nil {
+		panic("RunsMock.CreateFunc: method is nil but Runs.Create was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Run store.JobRun
+	}{
+		Ctx: ctx,
+		Run: run,
+	}
+	mock.lockCreate.Lock()
+	mock.calls.Create = append(mock.calls.Create, callInfo)
+	mock.lockCreate.Unlock()
+	return mock.CreateFunc(ctx, run)
+}
+
+// CreateCalls gets all the calls that were made to Create.
+// Check the length with:
+//
+//	len(mockedRuns.CreateCalls())
+func (mock *RunsMock) CreateCalls() []struct {
+	Ctx context.Context
+	Run store.JobRun
+} {
+	var calls []struct {
+		Ctx context.Context
+		Run store.JobRun
+	}
+	mock.lockCreate.RLock()
+	calls = mock.calls.Create
+	mock.lockCreate.RUnlock()
+	return calls
+}
+
+// Get calls GetFunc.
+func (mock *RunsMock) Get(ctx context.Context, runID string) (store.JobRun, error) {
+	if mock.GetFunc == nil {
+		panic("RunsMock.GetFunc: method is nil but Runs.Get was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		RunID string
+	}{
+		Ctx:   ctx,
+		RunID: runID,
+	}
+	mock.lockGet.Lock()
+	mock.calls.Get = append(mock.calls.Get, callInfo)
+	mock.lockGet.Unlock()
+	return mock.GetFunc(ctx, runID)
+}
+
+// GetCalls gets all the calls that were made to Get.
+// Check the length with:
+//
+//	len(mockedRuns.GetCalls())
+func (mock *RunsMock) GetCalls() []struct {
+	Ctx   context.Context
+	RunID string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		RunID string
+	}
+	mock.lockGet.RLock()
+	calls = mock.calls.Get
+	mock.lockGet.RUnlock()
+	return calls
+}
+
+// List calls ListFunc.
+func (mock *RunsMock) List(ctx context.Context, req ListRunsRequest) ([]store.JobRun, error) {
+	if mock.ListFunc == nil {
+		panic("RunsMock.ListFunc: method is nil but Runs.List was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Req ListRunsRequest
+	}{
+		Ctx: ctx,
+		Req: req,
+	}
+	mock.lockList.Lock()
+	mock.calls.List = append(mock.calls.List, callInfo)
+	mock.lockList.Unlock()
+	return mock.ListFunc(ctx, req)
+}
+
+// ListCalls gets all the calls that were made to List.
+// Check the length with:
+//
+//	len(mockedRuns.ListCalls())
+func (mock *RunsMock) ListCalls() []struct {
+	Ctx context.Context
+	Req ListRunsRequest
+} {
+	var calls []struct {
+		Ctx context.Context
+		Req ListRunsRequest
+	}
+	mock.lockList.RLock()
+	calls = mock.calls.List
+	mock.lockList.RUnlock()
+	return calls
+}
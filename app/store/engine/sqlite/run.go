@@ -0,0 +1,188 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/google/uuid"
+)
+
+// Runs implements engine.Runs over SQLite.
+// runs: filtered fields are kept in separate columns, data - JSON-encoded run,
+// started_at - start time of the run in unix nanoseconds
+type Runs struct {
+	db *sql.DB
+}
+
+// NewRuns makes new Runs over the database, opened with Open.
+func NewRuns(db *sql.DB) *Runs {
+	return &Runs{db: db}
+}
+
+// Create puts the run to the storage.
+func (s *Runs) Create(ctx context.Context, run store.JobRun) (string, error) {
+	if run.ID == "" {
+		run.ID = uuid.NewString()
+	}
+
+	bts, err := json.Marshal(run)
+	if err != nil {
+		return "", fmt.Errorf("marshal run: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO runs
+		(id, job_name, trigger_name, tracker, task_id, ticket_id, failed, started_at, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.ID, run.JobName, run.TriggerName, run.Update.Tracker, run.Update.ID, run.TicketID,
+		run.Failed(), run.StartedAt.UnixNano(), string(bts))
+	if err != nil {
+		return "", fmt.Errorf("insert run: %w", err)
+	}
+
+	return run.ID, nil
+}
+
+// Get returns the run by its ID.
+func (s *Runs) Get(ctx context.Context, runID string) (store.JobRun, error) {
+	run, err := scanRun(s.db.QueryRowContext(ctx, "SELECT data FROM runs WHERE id = ?", runID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.JobRun{}, fmt.Errorf("run %s not found: %w", runID, errs.ErrNotFound)
+	}
+	if err != nil {
+		return store.JobRun{}, fmt.Errorf("get run %s: %w", runID, err)
+	}
+
+	return run, nil
+}
+
+// List returns runs, that match the given engine.ListRunsRequest,
+// the most recent first.
+func (s *Runs) List(ctx context.Context, req engine.ListRunsRequest) ([]store.JobRun, error) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+
+	addCond := func(cond string, arg interface{}) {
+		conds = append(conds, cond)
+		args = append(args, arg)
+	}
+
+	if req.JobName != "" {
+		addCond("job_name = ?", req.JobName)
+	}
+	if req.TriggerName != "" {
+		addCond("trigger_name = ?", req.TriggerName)
+	}
+	if req.Update.Tracker != "" {
+		addCond("tracker = ?", req.Update.Tracker)
+	}
+	if req.Update.ID != "" {
+		addCond("task_id = ?", req.Update.ID)
+	}
+	if req.TicketID != "" {
+		addCond("ticket_id = ?", req.TicketID)
+	}
+	if req.OnlyFailed {
+		addCond("failed = ?", true)
+	}
+	if !req.Since.IsZero() {
+		addCond("started_at >= ?", req.Since.UnixNano())
+	}
+
+	query := "SELECT data FROM runs"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	// negative limit means no limit in sqlite
+	limit := -1
+	if req.Limit > 0 {
+		limit = req.Limit
+	}
+	query += " ORDER BY started_at DESC, rowid DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, req.Offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []store.JobRun
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate over runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// Cleanup removes runs according to the retention policy.
+func (s *Runs) Cleanup(ctx context.Context, req engine.CleanupRunsRequest) (int, error) {
+	removed := 0
+	err := inTx(ctx, s.db, func(tx *sql.Tx) error {
+		if !req.Before.IsZero() {
+			res, err := tx.ExecContext(ctx, "DELETE FROM runs WHERE started_at < ?", req.Before.UnixNano())
+			if err != nil {
+				return fmt.Errorf("delete runs started before %s: %w", req.Before, err)
+			}
+
+			n, err := res.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("get number of deleted runs: %w", err)
+			}
+			removed += int(n)
+		}
+
+		if req.Keep > 0 {
+			res, err := tx.ExecContext(ctx, `DELETE FROM runs WHERE id IN (
+				SELECT id FROM runs ORDER BY started_at DESC, rowid DESC LIMIT -1 OFFSET ?
+			)`, req.Keep)
+			if err != nil {
+				return fmt.Errorf("delete all runs except %d most recent: %w", req.Keep, err)
+			}
+
+			n, err := res.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("get number of deleted runs: %w", err)
+			}
+			removed += int(n)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("update storage: %w", err)
+	}
+
+	return removed, nil
+}
+
+func scanRun(row scanner) (store.JobRun, error) {
+	var data string
+	if err := row.Scan(&data); err != nil {
+		return store.JobRun{}, err
+	}
+
+	var run store.JobRun
+	if err := json.Unmarshal([]byte(data), &run); err != nil {
+		return store.JobRun{}, fmt.Errorf("unmarshal run: %w", err)
+	}
+
+	return run, nil
+}
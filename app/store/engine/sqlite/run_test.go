@@ -0,0 +1,16 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/app/store/engine/enginetest"
+)
+
+func TestRuns(t *testing.T) {
+	enginetest.TestRuns(t, func(t *testing.T) engine.Runs { return prepareRuns(t) })
+}
+
+func prepareRuns(t *testing.T) *Runs {
+	return NewRuns(prepareDB(t))
+}
@@ -0,0 +1,192 @@
+// Package sqlite implements storage engines over the SQLite database.
+// Tickets, their references, subscriptions and job runs are kept in separate tables,
+// so the data might be inspected with any SQLite client.
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	_ "modernc.org/sqlite" // register pure go sqlite driver
+)
+
+// migration describes a single change of the database schema.
+// Applied migrations must never be changed, only new ones appended.
+type migration struct {
+	engine.Migration
+	query string
+}
+
+var migrations = []migration{
+	{
+		Migration: engine.Migration{Version: 1, Description: "create tickets, refs and subscriptions tables"},
+		query: `CREATE TABLE tickets (
+			id         TEXT PRIMARY KEY,
+			variations TEXT NOT NULL,
+			updated_at INTEGER NOT NULL
+		);
+		CREATE INDEX tickets_updated_at ON tickets (updated_at);
+
+		CREATE TABLE refs (
+			tracker   TEXT NOT NULL,
+			task_id   TEXT NOT NULL,
+			ticket_id TEXT NOT NULL,
+			PRIMARY KEY (tracker, task_id)
+		);
+		CREATE INDEX refs_ticket_id ON refs (ticket_id);
+
+		CREATE TABLE subscriptions (
+			id           TEXT PRIMARY KEY,
+			tracker_ref  TEXT NOT NULL,
+			tracker_name TEXT NOT NULL,
+			trigger_name TEXT NOT NULL,
+			base_url     TEXT NOT NULL
+		);
+		CREATE INDEX subscriptions_tracker_trigger ON subscriptions (tracker_name, trigger_name);`,
+	},
+	{
+		Migration: engine.Migration{Version: 2, Description: "create runs table"},
+		query: `CREATE TABLE runs (
+			id           TEXT PRIMARY KEY,
+			job_name     TEXT NOT NULL,
+			trigger_name TEXT NOT NULL,
+			tracker      TEXT NOT NULL,
+			task_id      TEXT NOT NULL,
+			ticket_id    TEXT NOT NULL,
+			failed       INTEGER NOT NULL,
+			started_at   INTEGER NOT NULL,
+			data         TEXT NOT NULL
+		);
+		CREATE INDEX runs_started_at ON runs (started_at);`,
+	},
+}
+
+// busyTimeout is the time to wait for the lock of the database, held by
+// another connection, e.g. by the store command, run next to the instance.
+const busyTimeout = 5 * time.Second
+
+// Migrate applies pending migrations to the database and returns them.
+// If dryRun is set, migrations are only listed, and the missing database
+// is reported with errs.ErrNotFound instead of being created.
+func Migrate(fileName string, dryRun bool) ([]engine.Migration, error) {
+	// sqlite creates missing files on connect
+	if _, err := os.Stat(fileName); dryRun && errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("database file %s doesn't exist: %w", fileName, errs.ErrNotFound)
+	}
+
+	// dry run only reads, thus it must not take the write lock
+	// and block the writers of the running instance
+	db, err := sql.Open("sqlite", dsn(fileName, !dryRun))
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite db %s: %w", fileName, err)
+	}
+	defer db.Close()
+
+	return migrate(db, dryRun)
+}
+
+// Open opens the database and applies all pending migrations.
+// The returned handle must be shared by all stores over the same file.
+func Open(fileName string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", dsn(fileName, true))
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite db %s: %w", fileName, err)
+	}
+
+	// sqlite allows only one writer at a time, so there is no need in
+	// multiple connections, which would only fail with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	if _, err = db.Exec("PRAGMA journal_mode = WAL"); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("set journal mode: %w", err)
+	}
+
+	if _, err = migrate(db, false); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("migrate schema of %s: %w", fileName, err)
+	}
+
+	return db, nil
+}
+
+// dsn returns the data source name of the database file. If write is set,
+// transactions are started as immediate, so that they wait for the lock of
+// another connection in the beginning, instead of failing with SQLITE_BUSY
+// on the first write. Thus, transactions must be used only for writes.
+func dsn(fileName string, write bool) string {
+	res := fmt.Sprintf("%s?_pragma=busy_timeout(%d)", fileName, busyTimeout.Milliseconds())
+	if write {
+		res += "&_txlock=immediate"
+	}
+	return res
+}
+
+func migrate(db *sql.DB, dryRun bool) ([]engine.Migration, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after commit
+
+	var exists bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')").
+		Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("check migrations table: %w", err)
+	}
+
+	// in case of dry run the migrations table is not created either
+	if !exists && !dryRun {
+		_, err = tx.Exec(`CREATE TABLE schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			applied_at INTEGER NOT NULL
+		)`)
+		if err != nil {
+			return nil, fmt.Errorf("create migrations table: %w", err)
+		}
+	}
+
+	var version int
+	if exists {
+		if err = tx.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+			return nil, fmt.Errorf("get current schema version: %w", err)
+		}
+	}
+
+	if version > len(migrations) {
+		return nil, errs.ErrSchemaTooNew{Current: version, Supported: len(migrations)}
+	}
+
+	var pending []engine.Migration
+	for _, m := range migrations[version:] {
+		pending = append(pending, m.Migration)
+		if dryRun {
+			continue
+		}
+
+		if _, err = tx.Exec(m.query); err != nil {
+			return nil, fmt.Errorf("apply migration %d: %w", m.Version, err)
+		}
+
+		if _, err = tx.Exec("INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)",
+			m.Version, time.Now().UnixNano()); err != nil {
+			return nil, fmt.Errorf("register migration %d: %w", m.Version, err)
+		}
+	}
+
+	if dryRun {
+		return pending, nil
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	return pending, nil
+}
@@ -0,0 +1,170 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	fileName := tempFile(t)
+
+	// dry run must not create the database
+	_, err := Migrate(fileName, true)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+	_, err = os.Stat(fileName)
+	assert.True(t, os.IsNotExist(err), "file must not be created")
+
+	db, err := Open(fileName)
+	require.NoError(t, err)
+
+	var version int
+	require.NoError(t, db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version))
+	assert.Equal(t, len(migrations), version)
+	require.NoError(t, db.Close())
+
+	// reopening must not apply migrations again
+	db, err = Open(fileName)
+	require.NoError(t, err)
+
+	var applied int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&applied))
+	assert.Equal(t, len(migrations), applied)
+
+	pending, err := Migrate(fileName, true)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// the database, migrated by the newer version of the app, must be refused
+	_, err = db.Exec("INSERT INTO schema_migrations (version, applied_at) VALUES (?, 0)", len(migrations)+1)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	_, err = Open(fileName)
+	assert.ErrorAs(t, err, &errs.ErrSchemaTooNew{})
+}
+
+func TestOpen_Concurrent(t *testing.T) {
+	const n = 200
+
+	t.Run("shared handle", func(t *testing.T) {
+		db := prepareDB(t)
+		testConcurrentWrites(t, NewTickets(db), NewSubscriptions(db, logx.Nop()), NewRuns(db), n)
+	})
+
+	t.Run("separate handles", func(t *testing.T) {
+		// e.g. the store command, run next to the running instance
+		fileName := tempFile(t)
+		testConcurrentWrites(t, NewTickets(openDB(t, fileName)),
+			NewSubscriptions(openDB(t, fileName), logx.Nop()), NewRuns(openDB(t, fileName)), n)
+	})
+}
+
+func TestOpen_ReadsDontLock(t *testing.T) {
+	fileName := tempFile(t)
+	tickets := NewTickets(openDB(t, fileName))
+	tkt := store.Ticket{ID: "id", Variations: store.Variations{"tracker": {ID: "task-id"}}}
+	require.NoError(t, tickets.Update(context.Background(), tkt))
+
+	// the running instance holds the write lock
+	tx, err := openDB(t, fileName).Begin()
+	require.NoError(t, err)
+	defer tx.Rollback() //nolint:errcheck // nothing to commit
+	_, err = tx.Exec("DELETE FROM runs")
+	require.NoError(t, err)
+
+	start := time.Now()
+
+	res, err := tickets.Get(context.Background(), engine.GetRequest{TicketID: "id"})
+	require.NoError(t, err)
+	assert.Equal(t, tkt, res)
+
+	res, err = tickets.Get(context.Background(), engine.GetRequest{Locator: store.Locator{Tracker: "tracker", ID: "task-id"}})
+	require.NoError(t, err)
+	assert.Equal(t, tkt, res)
+
+	pending, err := Migrate(fileName, true)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	assert.Less(t, int64(time.Since(start)), int64(busyTimeout/2), "reads must not wait for the write lock")
+}
+
+func testConcurrentWrites(t *testing.T, tickets *Tickets, subs *Subscriptions, runs *Runs, n int) {
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, 3*n)
+	for i := 0; i < n; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			_, err := tickets.Create(ctx, store.Ticket{Variations: map[string]store.Task{
+				"tracker": {ID: fmt.Sprintf("task-%d", i)},
+			}})
+			errCh <- err
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			_, err := subs.Create(ctx, store.Subscription{
+				TrackerName: "tracker",
+				TriggerName: fmt.Sprintf("trigger-%d", i),
+			})
+			errCh <- err
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			_, err := runs.Create(ctx, store.JobRun{
+				JobName:   "job",
+				Update:    store.Locator{Tracker: "tracker", ID: fmt.Sprintf("task-%d", i)},
+				StartedAt: time.Now(),
+			})
+			errCh <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		require.NoError(t, err)
+	}
+
+	var cnt int
+	require.NoError(t, tickets.db.QueryRow("SELECT COUNT(*) FROM tickets").Scan(&cnt))
+	assert.Equal(t, n, cnt)
+	require.NoError(t, subs.db.QueryRow("SELECT COUNT(*) FROM subscriptions").Scan(&cnt))
+	assert.Equal(t, n, cnt)
+	require.NoError(t, runs.db.QueryRow("SELECT COUNT(*) FROM runs").Scan(&cnt))
+	assert.Equal(t, n, cnt)
+}
+
+func prepareDB(t *testing.T) *sql.DB {
+	return openDB(t, tempFile(t))
+}
+
+func openDB(t *testing.T, fileName string) *sql.DB {
+	db, err := Open(fileName)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, db.Close()) })
+	return db
+}
+
+func tempFile(t *testing.T) string {
+	loc, err := ioutil.TempDir("", "test_dastracker")
+	require.NoError(t, err, "failed to make temp dir")
+	t.Cleanup(func() { assert.NoError(t, os.RemoveAll(loc)) })
+	return path.Join(loc, "dastracker_test.db")
+}
@@ -0,0 +1,156 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+	"github.com/google/uuid"
+)
+
+// Subscriptions implements engine.Subscriptions over SQLite.
+// Each subscription is a row in subscriptions table, uniqueness of the
+// pair of tracker and trigger names is checked on creation.
+type Subscriptions struct {
+	l  logx.Logger
+	db *sql.DB
+}
+
+// NewSubscriptions makes new Subscriptions over the database, opened with Open.
+func NewSubscriptions(db *sql.DB, log logx.Logger) *Subscriptions {
+	return &Subscriptions{db: db, l: log}
+}
+
+// Create creates a subscription in the storage.
+func (s *Subscriptions) Create(ctx context.Context, sub store.Subscription) (string, error) {
+	sub.ID = uuid.NewString()
+
+	err := inTx(ctx, s.db, func(tx *sql.Tx) error {
+		var exists bool
+		err := tx.QueryRowContext(ctx,
+			"SELECT EXISTS (SELECT 1 FROM subscriptions WHERE tracker_name = ? AND trigger_name = ?)",
+			sub.TrackerName, sub.TriggerName).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("check tracker:trigger pair: %w", err)
+		}
+
+		if exists {
+			return fmt.Errorf("subscription %s is already assigned to \"%s:%s\" tracker:trigger pair: %w",
+				sub.ID, sub.TrackerName, sub.TriggerName, errs.ErrExists)
+		}
+
+		if err = s.put(ctx, tx, sub); err != nil {
+			return fmt.Errorf("put subscription %s: %w", sub.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("update storage: %w", err)
+	}
+
+	return sub.ID, nil
+}
+
+// Update totally rewrites the provided subscription entry.
+func (s *Subscriptions) Update(ctx context.Context, sub store.Subscription) error {
+	if err := inTx(ctx, s.db, func(tx *sql.Tx) error { return s.put(ctx, tx, sub) }); err != nil {
+		return fmt.Errorf("update storage: %w", err)
+	}
+	return nil
+}
+
+// Get subscription by id.
+func (s *Subscriptions) Get(ctx context.Context, subID string) (store.Subscription, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT id, tracker_ref, tracker_name, trigger_name, base_url
+		FROM subscriptions WHERE id = ?`, subID)
+
+	sub, err := scanSubscription(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.Subscription{}, fmt.Errorf("subscription %s not found: %w", subID, errs.ErrNotFound)
+	}
+	if err != nil {
+		return store.Subscription{}, fmt.Errorf("query storage: %w", err)
+	}
+
+	return sub, nil
+}
+
+// Delete subscription by id.
+func (s *Subscriptions) Delete(ctx context.Context, subID string) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM subscriptions WHERE id = ?", subID)
+	if err != nil {
+		return fmt.Errorf("delete subscription: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("get amount of deleted rows: %w", err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("subscription %s not found: %w", subID, errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+// List lists the subscriptions registered on the given tracker,
+// or all subscriptions, if the tracker name is empty.
+func (s *Subscriptions) List(ctx context.Context, trackerName string) ([]store.Subscription, error) {
+	query := "SELECT id, tracker_ref, tracker_name, trigger_name, base_url FROM subscriptions"
+	var args []interface{}
+	if trackerName != "" {
+		query += " WHERE tracker_name = ?"
+		args = append(args, trackerName)
+	}
+	query += " ORDER BY id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []store.Subscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate over subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (s *Subscriptions) put(ctx context.Context, tx *sql.Tx, sub store.Subscription) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO subscriptions (id, tracker_ref, tracker_name, trigger_name, base_url)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			tracker_ref = excluded.tracker_ref,
+			tracker_name = excluded.tracker_name,
+			trigger_name = excluded.trigger_name,
+			base_url = excluded.base_url`,
+		sub.ID, sub.TrackerRef, sub.TrackerName, sub.TriggerName, sub.BaseURL)
+	if err != nil {
+		return fmt.Errorf("upsert subscription %s: %w", sub.ID, err)
+	}
+
+	return nil
+}
+
+func scanSubscription(row scanner) (store.Subscription, error) {
+	var sub store.Subscription
+	if err := row.Scan(&sub.ID, &sub.TrackerRef, &sub.TrackerName, &sub.TriggerName, &sub.BaseURL); err != nil {
+		return store.Subscription{}, err
+	}
+	return sub, nil
+}
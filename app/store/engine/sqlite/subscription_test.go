@@ -0,0 +1,17 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/app/store/engine/enginetest"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+)
+
+func TestSubscriptions(t *testing.T) {
+	enginetest.TestSubscriptions(t, func(t *testing.T) engine.Subscriptions { return prepareSubscriptions(t) })
+}
+
+func prepareSubscriptions(t *testing.T) *Subscriptions {
+	return NewSubscriptions(prepareDB(t), logx.Nop())
+}
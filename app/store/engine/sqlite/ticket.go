@@ -0,0 +1,322 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/google/uuid"
+)
+
+// Tickets implements engine.Tickets over SQLite.
+// tickets: id - ticketID, variations - JSON-encoded variations,
+// updated_at - time of the last update in unix nanoseconds
+// refs: tracker and task_id - locator of the variation, ticket_id - ticketID
+type Tickets struct {
+	db *sql.DB
+}
+
+// NewTickets makes new Tickets over the database, opened with Open.
+func NewTickets(db *sql.DB) *Tickets {
+	return &Tickets{db: db}
+}
+
+// Create creates ticket in the storage.
+func (s *Tickets) Create(ctx context.Context, ticket store.Ticket) (string, error) {
+	ticket.ID = uuid.NewString()
+
+	if err := s.Update(ctx, ticket); err != nil {
+		return "", fmt.Errorf("put ticket into storage: %w", err)
+	}
+
+	return ticket.ID, nil
+}
+
+// Update updates ticket by its ID.
+func (s *Tickets) Update(ctx context.Context, ticket store.Ticket) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.put(ctx, tx, ticket); err != nil {
+			return fmt.Errorf("put ticket: %w", err)
+		}
+
+		// drop all refs of the ticket to remove outdated ones,
+		// e.g. if the ID of the variation has changed
+		if _, err := tx.ExecContext(ctx, "DELETE FROM refs WHERE ticket_id = ?", ticket.ID); err != nil {
+			return fmt.Errorf("delete refs: %w", err)
+		}
+
+		for _, locator := range ticket.Variations.Locators() {
+			_, err := tx.ExecContext(ctx, `INSERT INTO refs (tracker, task_id, ticket_id) VALUES (?, ?, ?)
+				ON CONFLICT (tracker, task_id) DO UPDATE SET ticket_id = excluded.ticket_id`,
+				locator.Tracker, locator.ID, ticket.ID)
+			if err != nil {
+				return fmt.Errorf("put ref for %q on %s: %w", locator.String(), ticket.ID, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("update storage: %w", err)
+	}
+	return nil
+}
+
+// Get returns ticket by the given engine.GetRequest.
+// Ticket is looked up with a single query, as transactions take the write lock.
+func (s *Tickets) Get(ctx context.Context, req engine.GetRequest) (store.Ticket, error) {
+	var row *sql.Row
+	if req.TicketID == "" && !req.Locator.Empty() {
+		row = s.db.QueryRowContext(ctx, `SELECT tickets.id, tickets.variations, tickets.updated_at
+			FROM tickets JOIN refs ON refs.ticket_id = tickets.id
+			WHERE refs.tracker = ? AND refs.task_id = ?`, req.Locator.Tracker, req.Locator.ID)
+	} else {
+		row = s.db.QueryRowContext(ctx, "SELECT id, variations, updated_at FROM tickets WHERE id = ?", req.TicketID)
+	}
+
+	ticket, _, err := scanTicket(row)
+	switch {
+	case errors.Is(err, sql.ErrNoRows) && req.TicketID == "":
+		return store.Ticket{}, fmt.Errorf("get ticket by ref %q: %w", req.Locator.String(), errs.ErrNotFound)
+	case errors.Is(err, sql.ErrNoRows):
+		return store.Ticket{}, fmt.Errorf("get ticket %s: %w", req.TicketID, errs.ErrNotFound)
+	case err != nil:
+		return store.Ticket{}, fmt.Errorf("query storage: %w", err)
+	}
+
+	return ticket, nil
+}
+
+// List returns tickets, that match the given engine.ListRequest.
+func (s *Tickets) List(ctx context.Context, req engine.ListRequest) ([]store.Ticket, error) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+
+	if req.Tracker != "" {
+		conds = append(conds, "EXISTS (SELECT 1 FROM refs WHERE refs.ticket_id = tickets.id AND refs.tracker = ?)")
+		args = append(args, req.Tracker)
+	}
+
+	if !req.UpdatedSince.IsZero() {
+		conds = append(conds, "updated_at >= ?")
+		args = append(args, req.UpdatedSince.UnixNano())
+	}
+
+	if req.AfterID != "" {
+		conds = append(conds, "id > ?")
+		args = append(args, req.AfterID)
+	}
+
+	query := "SELECT id, variations, updated_at FROM tickets"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query tickets: %w", err)
+	}
+	defer rows.Close()
+
+	var tickets []store.Ticket
+	skipped := 0
+	for rows.Next() {
+		if req.Limit > 0 && len(tickets) >= req.Limit {
+			break
+		}
+
+		ticket, updatedAt, err := scanTicket(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan ticket: %w", err)
+		}
+
+		// fields are stored as a part of JSON, thus must be filtered out here
+		if !req.Match(ticket, updatedAt) {
+			continue
+		}
+
+		if skipped < req.Offset {
+			skipped++
+			continue
+		}
+
+		tickets = append(tickets, ticket)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate over tickets: %w", err)
+	}
+
+	return tickets, nil
+}
+
+// Delete removes the ticket by its ID along with all its refs.
+func (s *Tickets) Delete(ctx context.Context, ticketID string) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := s.get(ctx, tx, ticketID); err != nil {
+			return fmt.Errorf("get ticket %s: %w", ticketID, err)
+		}
+
+		if err := s.delete(ctx, tx, ticketID); err != nil {
+			return fmt.Errorf("delete ticket %s: %w", ticketID, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("update storage: %w", err)
+	}
+	return nil
+}
+
+// Unlink removes the variation, located by the given locator, from its ticket.
+func (s *Tickets) Unlink(ctx context.Context, locator store.Locator) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		ticketID, err := s.getTicketID(ctx, tx, locator)
+		if err != nil {
+			return fmt.Errorf("get ticket id by ref %q: %w", locator.String(), err)
+		}
+
+		ticket, err := s.get(ctx, tx, ticketID)
+		if err != nil {
+			return fmt.Errorf("get ticket %s: %w", ticketID, err)
+		}
+
+		if _, err = tx.ExecContext(ctx, "DELETE FROM refs WHERE tracker = ? AND task_id = ?",
+			locator.Tracker, locator.ID); err != nil {
+			return fmt.Errorf("delete ref %q: %w", locator.String(), err)
+		}
+
+		// the ref is stale, the ticket itself is not changed
+		if task := ticket.Variations.Get(locator.Tracker); task.ID != locator.ID {
+			return nil
+		}
+		delete(ticket.Variations, locator.Tracker)
+
+		if len(ticket.Variations) == 0 {
+			if err = s.delete(ctx, tx, ticketID); err != nil {
+				return fmt.Errorf("delete ticket %s without variations: %w", ticketID, err)
+			}
+			return nil
+		}
+
+		if err = s.put(ctx, tx, ticket); err != nil {
+			return fmt.Errorf("put ticket %s: %w", ticketID, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("update storage: %w", err)
+	}
+	return nil
+}
+
+func (s *Tickets) get(ctx context.Context, tx *sql.Tx, ticketID string) (store.Ticket, error) {
+	row := tx.QueryRowContext(ctx, "SELECT id, variations, updated_at FROM tickets WHERE id = ?", ticketID)
+
+	ticket, _, err := scanTicket(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.Ticket{}, errs.ErrNotFound
+	}
+	if err != nil {
+		return store.Ticket{}, err
+	}
+
+	return ticket, nil
+}
+
+func (s *Tickets) put(ctx context.Context, tx *sql.Tx, ticket store.Ticket) error {
+	bts, err := json.Marshal(ticket.Variations)
+	if err != nil {
+		return fmt.Errorf("marshal variations: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO tickets (id, variations, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET variations = excluded.variations, updated_at = excluded.updated_at`,
+		ticket.ID, string(bts), time.Now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("upsert ticket: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Tickets) delete(ctx context.Context, tx *sql.Tx, ticketID string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM refs WHERE ticket_id = ?", ticketID); err != nil {
+		return fmt.Errorf("delete refs: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM tickets WHERE id = ?", ticketID); err != nil {
+		return fmt.Errorf("delete ticket itself: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Tickets) getTicketID(ctx context.Context, tx *sql.Tx, locator store.Locator) (string, error) {
+	var ticketID string
+	err := tx.QueryRowContext(ctx, "SELECT ticket_id FROM refs WHERE tracker = ? AND task_id = ?",
+		locator.Tracker, locator.ID).Scan(&ticketID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errs.ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return ticketID, nil
+}
+
+func (s *Tickets) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return inTx(ctx, s.db, fn)
+}
+
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTicket(row scanner) (store.Ticket, time.Time, error) {
+	var (
+		ticket     store.Ticket
+		variations string
+		updatedAt  int64
+	)
+
+	if err := row.Scan(&ticket.ID, &variations, &updatedAt); err != nil {
+		return store.Ticket{}, time.Time{}, err
+	}
+
+	if err := json.Unmarshal([]byte(variations), &ticket.Variations); err != nil {
+		return store.Ticket{}, time.Time{}, fmt.Errorf("unmarshal variations: %w", err)
+	}
+
+	return ticket, time.Unix(0, updatedAt), nil
+}
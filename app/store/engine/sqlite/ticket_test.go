@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/app/store/engine/enginetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTickets(t *testing.T) {
+	enginetest.TestTickets(t, func(t *testing.T) engine.Tickets { return prepareTickets(t) })
+}
+
+func TestTickets_StaleRef(t *testing.T) {
+	svc := prepareTickets(t)
+	enginetest.TestTicketsStaleRef(t, svc, func(locator store.Locator, ticketID string) {
+		_, err := svc.db.Exec("INSERT INTO refs (tracker, task_id, ticket_id) VALUES (?, ?, ?)",
+			locator.Tracker, locator.ID, ticketID)
+		require.NoError(t, err)
+	})
+}
+
+func TestTickets_Create(t *testing.T) {
+	svc := prepareTickets(t)
+
+	tkt := store.Ticket{
+		Variations: map[string]store.Task{
+			"tracker-1": {ID: "task-id-1", Content: store.Content{Body: "body-1", Title: "title-1"}},
+			"tracker-2": {ID: "task-id-2", Content: store.Content{Body: "body-2", Title: "title-2"}},
+		},
+	}
+
+	id, err := svc.Create(context.Background(), tkt)
+	require.NoError(t, err)
+	assert.NotEmpty(t, id)
+
+	tkt.ID = id
+	res, err := svc.Get(context.Background(), engine.GetRequest{TicketID: id})
+	require.NoError(t, err)
+	assert.Equal(t, tkt, res)
+
+	var refs int
+	err = svc.db.QueryRow("SELECT COUNT(*) FROM refs WHERE ticket_id = ?", id).Scan(&refs)
+	require.NoError(t, err)
+	assert.Equal(t, 2, refs)
+}
+
+func prepareTickets(t *testing.T) *Tickets {
+	return NewTickets(prepareDB(t))
+}
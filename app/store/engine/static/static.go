@@ -40,6 +40,14 @@ func NewStatic(path string) (*Static, error) {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	// conditions and templates are compiled once, so that errors in them
+	// are reported at startup and updates are not slowed down by parsing
+	for i := range cfg.Jobs {
+		if cfg.Jobs[i].Actions, err = cfg.Jobs[i].Actions.Compile(); err != nil {
+			return nil, fmt.Errorf("compile job %q: %w", cfg.Jobs[i].Name, err)
+		}
+	}
+
 	svc := &Static{config: cfg, subscriptions: map[string][]store.Job{}}
 
 	for _, job := range cfg.Jobs {
@@ -59,6 +67,11 @@ func (s *Static) ListTrackers(_ context.Context) ([]store.Tracker, error) {
 	return s.Trackers, nil
 }
 
+// ListJobs returns the list of all registered jobs.
+func (s *Static) ListJobs(_ context.Context) ([]store.Job, error) {
+	return s.config.Jobs, nil
+}
+
 // ListSubscribedJobs returns the jobs attached to the trigger
 // by the name of the trigger.
 func (s *Static) ListSubscribedJobs(_ context.Context, triggerName string) ([]store.Job, error) {
@@ -1,6 +1,7 @@
 package static
 
 import (
+	"encoding/json"
 	"io"
 	"os"
 	"path"
@@ -59,58 +60,96 @@ func TestNewStatic(t *testing.T) {
 		svc, err := NewStatic(path.Join(loc, "config.yml"))
 		require.NoError(t, err)
 
-		expectedSvc := &Static{
-			config: config{
-				Trackers: []store.Tracker{
-					{
-						Name:   "gh_dastracker",
-						Driver: "github",
-						With: lib.Vars{
-							"owner":        "cappuccinotm",
-							"name":         "dastracker",
-							"user":         `{{ env "GITHUB_USER" }}`,
-							"access_token": `{{ env "GITHUB_ACCESS_TOKEN" }}`,
-						},
-					},
-					{
-						Name:   "customrpc",
-						Driver: "rpc",
-						With:   lib.Vars{"address": `{{ env "CUSTOM_RPC_ADDRESS" }}`},
-					},
+		expectedJob := store.Job{
+			Name:        "print task update if task is received",
+			TriggerName: "gh_task_updated",
+			Actions: []store.Step{store.Action{
+				Name:     "customrpc/Print",
+				Detached: true,
+				With: lib.Vars{
+					"message": `Task "{{.Update.Title}}" has been updated and printed to the terminal.`,
 				},
-				Triggers: []store.Trigger{{
-					Name:    "gh_task_updated",
-					Tracker: "gh_dastracker",
-					With:    lib.Vars{"events": "issue"},
-				}},
-				Jobs: []store.Job{{
-					Name:        "print task update if task is received",
-					TriggerName: "gh_task_updated",
-					Actions: []store.Step{store.Action{
-						Name:     "customrpc/Print",
-						Detached: true,
-						With: lib.Vars{
-							"message": `Task "{{.Update.Title}}" has been updated and printed to the terminal.`,
-						},
-					}},
-				}},
-			},
-			subscriptions: map[string][]store.Job{
-				"gh_task_updated": {
-					{
-						Name:        "print task update if task is received",
-						TriggerName: "gh_task_updated",
-						Actions: []store.Step{store.Action{
-							Name:     "customrpc/Print",
-							Detached: true,
-							With: lib.Vars{
-								"message": `Task "{{.Update.Title}}" has been updated and printed to the terminal.`,
-							},
-						}},
-					},
+			}},
+		}
+
+		assert.Equal(t, []store.Tracker{
+			{
+				Name:   "gh_dastracker",
+				Driver: "github",
+				With: lib.Vars{
+					"owner":        "cappuccinotm",
+					"name":         "dastracker",
+					"user":         `{{ env "GITHUB_USER" }}`,
+					"access_token": `{{ env "GITHUB_ACCESS_TOKEN" }}`,
 				},
 			},
-		}
-		assert.Equal(t, expectedSvc, svc)
+			{
+				Name:   "customrpc",
+				Driver: "rpc",
+				With:   lib.Vars{"address": `{{ env "CUSTOM_RPC_ADDRESS" }}`},
+			},
+		}, svc.Trackers)
+		assert.Equal(t, []store.Trigger{{
+			Name:    "gh_task_updated",
+			Tracker: "gh_dastracker",
+			With:    lib.Vars{"events": "issue"},
+		}}, svc.Triggers)
+
+		// compiled templates contain functions, which can't be compared,
+		// thus jobs are compared by their source
+		expectedJSON, err := json.Marshal([]store.Job{expectedJob})
+		require.NoError(t, err)
+		actualJSON, err := json.Marshal(svc.Jobs)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expectedJSON), string(actualJSON))
+		assert.Equal(t, map[string][]store.Job{"gh_task_updated": svc.Jobs}, svc.subscriptions)
+
+		vars, err := svc.Jobs[0].Actions[0].(store.Action).Evaluate(store.EvalData{
+			Update: store.Update{Content: store.Content{Title: "title"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, lib.Vars{"message": `Task "title" has been updated and printed to the terminal.`}, vars)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		loc, err := os.MkdirTemp("", "test_dastracker")
+		require.NoError(t, err, "failed to make temp dir")
+		defer os.RemoveAll(loc)
+
+		cfg := testCfg + `
+  - name: dry run
+    on: gh_task_updated
+    dry_run: true
+    do:
+      - action: customrpc/Print
+`
+		require.NoError(t, os.WriteFile(path.Join(loc, "config.yml"), []byte(cfg), 0o600))
+
+		svc, err := NewStatic(path.Join(loc, "config.yml"))
+		require.NoError(t, err)
+		require.Len(t, svc.Jobs, 2)
+		assert.False(t, svc.Jobs[0].DryRun)
+		assert.Equal(t, "dry run", svc.Jobs[1].Name)
+		assert.True(t, svc.Jobs[1].DryRun)
+	})
+
+	t.Run("invalid condition", func(t *testing.T) {
+		loc, err := os.MkdirTemp("", "test_dastracker")
+		require.NoError(t, err, "failed to make temp dir")
+		defer os.RemoveAll(loc)
+
+		cfg := testCfg + `
+  - name: invalid condition
+    on: gh_task_updated
+    do:
+      - if: Update.Titel == ""
+        do:
+          - action: customrpc/Print
+`
+		require.NoError(t, os.WriteFile(path.Join(loc, "config.yml"), []byte(cfg), 0o600))
+
+		_, err = NewStatic(path.Join(loc, "config.yml"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `compile job "invalid condition"`)
 	})
 }
@@ -16,32 +16,50 @@ var _ Tickets = &TicketsMock{}
 
 // TicketsMock is a mock implementation of Tickets.
 //
-// 	func TestSomethingThatUsesTickets(t *testing.T) {
+//	func TestSomethingThatUsesTickets(t *testing.T) {
 //
-// 		// make and configure a mocked Tickets
-// 		mockedTickets := &TicketsMock{
-// 			CreateFunc: func(ctx context.Context, ticket store.Ticket) (string, error) {
-// 				panic("mock out the Create method")
-// 			},
-// 			GetFunc: func(ctx context.Context, req GetRequest) (store.Ticket, error) {
-// 				panic("mock out the Get method")
-// 			},
-// 			UpdateFunc: func(ctx context.Context, ticket store.Ticket) error {
-// 				panic("mock out the Update method")
-// 			},
-// 		}
+//		// make and configure a mocked Tickets
+//		mockedTickets := &TicketsMock{
+//			CreateFunc: func(ctx context.Context, ticket store.Ticket) (string, error) {
+//				panic("mock out the Create method")
+//			},
+//			DeleteFunc: func(ctx context.Context, ticketID string) error {
+//				panic("mock out the Delete method")
+//			},
+//			GetFunc: func(ctx context.Context, req GetRequest) (store.Ticket, error) {
+//				panic("mock out the Get method")
+//			},
+//			ListFunc: func(ctx context.Context, req ListRequest) ([]store.Ticket, error) {
+//				panic("mock out the List method")
+//			},
+//			UnlinkFunc: func(ctx context.Context, locator store.Locator) error {
+//				panic("mock out the Unlink method")
+//			},
+//			UpdateFunc: func(ctx context.Context, ticket store.Ticket) error {
+//				panic("mock out the Update method")
+//			},
+//		}
 //
-// 		// use mockedTickets in code that requires Tickets
-// 		// and then make assertions.
+//		// use mockedTickets in code that requires Tickets
+//		// and then make assertions.
 //
-// 	}
+//	}
 type TicketsMock struct {
 	// CreateFunc mocks the Create method.
 	CreateFunc func(ctx context.Context, ticket store.Ticket) (string, error)
 
+	// DeleteFunc mocks the Delete method.
+	DeleteFunc func(ctx context.Context, ticketID string) error
+
 	// GetFunc mocks the Get method.
 	GetFunc func(ctx context.Context, req GetRequest) (store.Ticket, error)
 
+	// ListFunc mocks the List method.
+	ListFunc func(ctx context.Context, req ListRequest) ([]store.Ticket, error)
+
+	// UnlinkFunc mocks the Unlink method.
+	UnlinkFunc func(ctx context.Context, locator store.Locator) error
+
 	// UpdateFunc mocks the Update method.
 	UpdateFunc func(ctx context.Context, ticket store.Ticket) error
 
@@ -54,6 +72,13 @@ type TicketsMock struct {
 			// Ticket is the ticket argument value.
 			Ticket store.Ticket
 		}
+		// Delete holds details about calls to the Delete method.
+		Delete []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// TicketID is the ticketID argument value.
+			TicketID string
+		}
 		// Get holds details about calls to the Get method.
 		Get []struct {
 			// Ctx is the ctx argument value.
@@ -61,6 +86,20 @@ type TicketsMock struct {
 			// Req is the req argument value.
 			Req GetRequest
 		}
+		// List holds details about calls to the List method.
+		List []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Req is the req argument value.
+			Req ListRequest
+		}
+		// Unlink holds details about calls to the Unlink method.
+		Unlink []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Locator is the locator argument value.
+			Locator store.Locator
+		}
 		// Update holds details about calls to the Update method.
 		Update []struct {
 			// Ctx is the ctx argument value.
@@ -70,7 +109,10 @@ type TicketsMock struct {
 		}
 	}
 	lockCreate sync.RWMutex
+	lockDelete sync.RWMutex
 	lockGet    sync.RWMutex
+	lockList   sync.RWMutex
+	lockUnlink sync.RWMutex
 	lockUpdate sync.RWMutex
 }
 
@@ -94,7 +136,8 @@ func (mock *TicketsMock) Create(ctx context.Context, ticket store.Ticket) (strin
 
 // CreateCalls gets all the calls that were made to Create.
 // Check the length with:
-//     len(mockedTickets.CreateCalls())
+//
+//	len(mockedTickets.CreateCalls())
 func (mock *TicketsMock) CreateCalls() []struct {
 	Ctx    context.Context
 	Ticket store.Ticket
@@ -109,6 +152,42 @@ func (mock *TicketsMock) CreateCalls() []struct {
 	return calls
 }
 
+// Delete calls DeleteFunc.
+func (mock *TicketsMock) Delete(ctx context.Context, ticketID string) error {
+	if mock.DeleteFunc == nil {
+		panic("TicketsMock.DeleteFunc: method is nil but Tickets.Delete was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		TicketID string
+	}{
+		Ctx:      ctx,
+		TicketID: ticketID,
+	}
+	mock.lockDelete.Lock()
+	mock.calls.Delete = append(mock.calls.Delete, callInfo)
+	mock.lockDelete.Unlock()
+	return mock.DeleteFunc(ctx, ticketID)
+}
+
+// DeleteCalls gets all the calls that were made to Delete.
+// Check the length with:
+//
+//	len(mockedTickets.DeleteCalls())
+func (mock *TicketsMock) DeleteCalls() []struct {
+	Ctx      context.Context
+	TicketID string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		TicketID string
+	}
+	mock.lockDelete.RLock()
+	calls = mock.calls.Delete
+	mock.lockDelete.RUnlock()
+	return calls
+}
+
 // Get calls GetFunc.
 func (mock *TicketsMock) Get(ctx context.Context, req GetRequest) (store.Ticket, error) {
 	if mock.GetFunc == nil {
@@ -129,7 +208,8 @@ func (mock *TicketsMock) Get(ctx context.Context, req GetRequest) (store.Ticket,
 
 // GetCalls gets all the calls that were made to Get.
 // Check the length with:
-//     len(mockedTickets.GetCalls())
+//
+//	len(mockedTickets.GetCalls())
 func (mock *TicketsMock) GetCalls() []struct {
 	Ctx context.Context
 	Req GetRequest
@@ -144,6 +224,78 @@ func (mock *TicketsMock) GetCalls() []struct {
 	return calls
 }
 
+// List calls ListFunc.
+func (mock *TicketsMock) List(ctx context.Context, req ListRequest) ([]store.Ticket, error) {
+	if mock.ListFunc == nil {
+		panic("TicketsMock.ListFunc: method is nil but Tickets.List was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Req ListRequest
+	}{
+		Ctx: ctx,
+		Req: req,
+	}
+	mock.lockList.Lock()
+	mock.calls.List = append(mock.calls.List, callInfo)
+	mock.lockList.Unlock()
+	return mock.ListFunc(ctx, req)
+}
+
+// ListCalls gets all the calls that were made to List.
+// Check the length with:
+//
+//	len(mockedTickets.ListCalls())
+func (mock *TicketsMock) ListCalls() []struct {
+	Ctx context.Context
+	Req ListRequest
+} {
+	var calls []struct {
+		Ctx context.Context
+		Req ListRequest
+	}
+	mock.lockList.RLock()
+	calls = mock.calls.List
+	mock.lockList.RUnlock()
+	return calls
+}
+
+// Unlink calls UnlinkFunc.
+func (mock *TicketsMock) Unlink(ctx context.Context, locator store.Locator) error {
+	if mock.UnlinkFunc == nil {
+		panic("TicketsMock.UnlinkFunc: method is nil but Tickets.Unlink was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Locator store.Locator
+	}{
+		Ctx:     ctx,
+		Locator: locator,
+	}
+	mock.lockUnlink.Lock()
+	mock.calls.Unlink = append(mock.calls.Unlink, callInfo)
+	mock.lockUnlink.Unlock()
+	return mock.UnlinkFunc(ctx, locator)
+}
+
+// UnlinkCalls gets all the calls that were made to Unlink.
+// Check the length with:
+//
+//	len(mockedTickets.UnlinkCalls())
+func (mock *TicketsMock) UnlinkCalls() []struct {
+	Ctx     context.Context
+	Locator store.Locator
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Locator store.Locator
+	}
+	mock.lockUnlink.RLock()
+	calls = mock.calls.Unlink
+	mock.lockUnlink.RUnlock()
+	return calls
+}
+
 // Update calls UpdateFunc.
 func (mock *TicketsMock) Update(ctx context.Context, ticket store.Ticket) error {
 	if mock.UpdateFunc == nil {
@@ -164,7 +316,8 @@ func (mock *TicketsMock) Update(ctx context.Context, ticket store.Ticket) error
 
 // UpdateCalls gets all the calls that were made to Update.
 // Check the length with:
-//     len(mockedTickets.UpdateCalls())
+//
+//	len(mockedTickets.UpdateCalls())
 func (mock *TicketsMock) UpdateCalls() []struct {
 	Ctx    context.Context
 	Ticket store.Ticket
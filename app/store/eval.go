@@ -3,21 +3,31 @@ package store
 import (
 	"bytes"
 	"fmt"
-	"os"
-	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
 	"github.com/cappuccinotm/dastracker/app/errs"
 	"github.com/cappuccinotm/dastracker/lib"
 )
 
-// Eval evaluates a predicate.
+// Eval evaluates a predicate. Conditions, which have not been compiled
+// in advance, are compiled on each call.
 func (i If) Eval(upd Update) (bool, error) {
-	env := map[string]interface{}{"Update": upd}
-	copyFuncs(env)
+	prg := i.prg
+	if prg == nil {
+		var err error
+		if prg, err = i.Compile(); err != nil {
+			return false, err
+		}
+	}
 
-	v, err := expr.Eval(i.Condition, env)
+	env := envs.Get().(map[string]interface{})
+	env["Update"] = upd
+	v, err := expr.Run(prg, env)
+	env["Update"] = Update{}
+	envs.Put(env)
 	if err != nil {
 		return false, fmt.Errorf("evaluate expression: %w", err)
 	}
@@ -28,61 +38,198 @@ func (i If) Eval(upd Update) (bool, error) {
 	return vv, nil
 }
 
-// Evaluate evaluates the final values of each variable.
-func Evaluate(v lib.Vars, data EvalData) (lib.Vars, error) {
+// Compile compiles the condition and checks that it refers to the existing
+// fields of the update and returns a boolean value.
+func (i If) Compile() (*vm.Program, error) {
+	prg, err := expr.Compile(i.Condition, expr.Env(compileEnv))
+	if err != nil {
+		return nil, fmt.Errorf("compile expression %q: %w", i.Condition, err)
+	}
+	if _, err = expr.Compile(i.Condition, expr.Env(compileEnv), expr.AsBool()); err != nil {
+		return nil, fmt.Errorf("expression %q: %w", i.Condition, errs.ErrIfNotBool)
+	}
+	return prg, nil
+}
+
+// compileEnv describes the types of the environment of conditions, it's never changed.
+var compileEnv = newEnv()
+
+// envs are the environments of conditions with all helper functions,
+// only the update is set on each evaluation, instead of copying the functions.
+var envs = sync.Pool{New: func() interface{} { return newEnv() }}
+
+func newEnv() map[string]interface{} {
+	env := make(map[string]interface{}, len(funcs)+1)
+	for k, v := range funcs {
+		env[k] = v
+	}
+	env["Update"] = Update{}
+	return env
+}
+
+// Evaluate evaluates the variables of the action. Templates, which
+// have not been compiled in advance, are parsed on each call.
+func (a Action) Evaluate(data EvalData) (lib.Vars, error) {
+	if a.tmpl == nil {
+		return Evaluate(a.With, data)
+	}
+	return a.tmpl.Execute(data)
+}
+
+// Compile compiles conditions and templates of variables of all steps
+// in the sequence, so that they are not parsed on each update.
+func (s Sequence) Compile() (Sequence, error) {
+	if len(s) == 0 {
+		return s, nil
+	}
+
+	res := make(Sequence, 0, len(s))
+	for _, step := range s {
+		switch step := step.(type) {
+		case Action:
+			tmpl, err := CompileVars(step.With)
+			if err != nil {
+				return nil, fmt.Errorf("action %q: %w", step.Name, err)
+			}
+			step.tmpl = tmpl
+			res = append(res, step)
+		case If:
+			prg, err := step.Compile()
+			if err != nil {
+				return nil, err
+			}
+			step.prg = prg
+			if step.Actions, err = step.Actions.Compile(); err != nil {
+				return nil, fmt.Errorf("in if condition %q: %w", step.Condition, err)
+			}
+			res = append(res, step)
+		default:
+			return nil, fmt.Errorf("invalid step type %T", step)
+		}
+	}
+
+	return res, nil
+}
+
+// Templates are the parsed templates of variables. Strings in the values of
+// variables, including the ones nested in lists and maps, are templates,
+// other values are kept as is.
+type Templates map[string]interface{}
+
+// CompileVars parses the templates of variables.
+func CompileVars(v lib.Vars) (Templates, error) {
 	if len(v) == 0 {
 		return nil, nil
 	}
 
-	res := lib.Vars(map[string]string{})
+	res := Templates{}
 	for key, vv := range v {
-		tmpl, err := template.New("").Funcs(funcs).Parse(vv)
+		tmpl, err := compileValue(vv)
 		if err != nil {
-			return lib.Vars{}, fmt.Errorf("parse %q variable: %w", key, err)
+			return nil, fmt.Errorf("parse %q variable: %w", key, err)
 		}
+		res[key] = tmpl
+	}
 
-		buf := &bytes.Buffer{}
-		if err = tmpl.Execute(buf, data); err != nil {
-			return lib.Vars{}, fmt.Errorf("evaluate the value of the %q variable: %w", key, err)
+	return res, nil
+}
+
+func compileValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return ParseTemplate(v)
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, vv := range v {
+			tmpl, err := compileValue(vv)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			res[i] = tmpl
+		}
+		return res, nil
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for key, vv := range v {
+			tmpl, err := compileValue(vv)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", key, err)
+			}
+			res[key] = tmpl
 		}
+		return res, nil
+	default:
+		return v, nil
+	}
+}
 
-		res[key] = buf.String()
+// Execute evaluates the final values of variables.
+func (t Templates) Execute(data EvalData) (lib.Vars, error) {
+	if len(t) == 0 {
+		return nil, nil
 	}
 
-	return res, nil
-}
+	res := make(lib.Vars, len(t))
+	for key, tmpl := range t {
+		v, err := executeValue(tmpl, data)
+		if err != nil {
+			return lib.Vars{}, fmt.Errorf("evaluate the value of the %q variable: %w", key, err)
+		}
+		res[key] = v
+	}
 
-// EvalData combines all the possible data to evaluate the template/expression..
-type EvalData struct {
-	Ticket Ticket
-	Update Update
+	return res, nil
 }
 
-// map of functions to parse from the config file
-var funcs = map[string]interface{}{
-	"env": os.Getenv,
-	"keys": func(s map[string]string) []string {
-		res := make([]string, 0, len(s))
-		for k := range s {
-			res = append(res, k)
+func executeValue(tmpl interface{}, data EvalData) (interface{}, error) {
+	switch tmpl := tmpl.(type) {
+	case *template.Template:
+		buf := &bytes.Buffer{}
+		if err := tmpl.Execute(buf, data); err != nil {
+			return nil, err
+		}
+		return buf.String(), nil
+	case []interface{}:
+		res := make([]interface{}, len(tmpl))
+		for i, t := range tmpl {
+			v, err := executeValue(t, data)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			res[i] = v
 		}
-		return res
-	},
-	"values": func(s map[string]string) []string {
-		res := make([]string, 0, len(s))
-		for _, v := range s {
-			res = append(res, v)
+		return res, nil
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(tmpl))
+		for key, t := range tmpl {
+			v, err := executeValue(t, data)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", key, err)
+			}
+			res[key] = v
 		}
-		return res
-	},
-	"seq": func(s []string) string {
-		return strings.Join(s, ",")
-	},
-	"string_contains": strings.Contains,
+		return res, nil
+	default:
+		return tmpl, nil
+	}
 }
 
-func copyFuncs(f map[string]interface{}) {
-	for k, v := range funcs {
-		f[k] = v
+// ParseTemplate parses the template of the variable with all helper functions.
+func ParseTemplate(s string) (*template.Template, error) {
+	return template.New("").Funcs(funcs).Parse(s)
+}
+
+// Evaluate evaluates the final values of each variable.
+func Evaluate(v lib.Vars, data EvalData) (lib.Vars, error) {
+	tmpl, err := CompileVars(v)
+	if err != nil {
+		return lib.Vars{}, err
 	}
+	return tmpl.Execute(data)
+}
+
+// EvalData combines all the possible data to evaluate the template/expression..
+type EvalData struct {
+	Ticket Ticket
+	Update Update
 }
@@ -1,17 +1,20 @@
 package store
 
 import (
+	"fmt"
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/secrets"
 	"github.com/cappuccinotm/dastracker/lib"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestEvaluate(t *testing.T) {
-	v := lib.Vars(map[string]string{
+	v := lib.Vars{
 		"var1": `{{ env "TESTVAR" }}`,
 		"var2": `{{ seq (keys .Update.Fields) }}`,
 		"var3": `{{ seq (values .Update.Fields) }}`,
@@ -19,7 +22,7 @@ func TestEvaluate(t *testing.T) {
 		"var5": `{{ .Ticket.ID }}`,
 		"var6": `{{ (.Ticket.Variations.Get "jira").Title }}`,
 		"var7": `{{ (.Ticket.Variations.Get "gitlab").Title }}`,
-	})
+	}
 	err := os.Setenv("TESTVAR", "blah")
 	vs, err := Evaluate(v, EvalData{
 		Ticket: Ticket{
@@ -72,3 +75,317 @@ func TestIf_Eval(t *testing.T) {
 	assert.ErrorIs(t, err, errs.ErrIfNotBool)
 	assert.False(t, b)
 }
+
+func TestIf_Compile(t *testing.T) {
+	_, err := If{Condition: `string_contains(Update.Title, "[PTT]") && Update.Fields["status"] == "open"`}.Compile()
+	assert.NoError(t, err)
+
+	_, err = If{Condition: `Update.Titel == ""`}.Compile()
+	assert.Error(t, err, "unknown field")
+
+	_, err = If{Condition: `Update.Title`}.Compile()
+	assert.Error(t, err, "not a boolean")
+
+	_, err = If{Condition: `Update.Title ==`}.Compile()
+	assert.Error(t, err, "syntax error")
+}
+
+func TestParseTemplate(t *testing.T) {
+	_, err := ParseTemplate(`{{ seq (keys .Update.Fields) }}`)
+	assert.NoError(t, err)
+
+	_, err = ParseTemplate(`{{ unknown .Update.Fields }}`)
+	assert.Error(t, err)
+
+	_, err = ParseTemplate(`{{ .Update.Title `)
+	assert.Error(t, err)
+}
+
+func TestSequence_Compile(t *testing.T) {
+	seq, err := Sequence{
+		If{Condition: `Update.Title != ""`, Actions: Sequence{
+			Action{Name: "tracker/method", With: lib.Vars{"title": "{{ .Update.Title }}"}},
+		}},
+		Action{Name: "tracker/other"},
+	}.Compile()
+	require.NoError(t, err)
+	require.Len(t, seq, 2)
+
+	cond := seq[0].(If)
+	assert.NotNil(t, cond.prg)
+	b, err := cond.Eval(Update{Content: Content{Title: "title"}})
+	require.NoError(t, err)
+	assert.True(t, b)
+
+	act := cond.Actions[0].(Action)
+	assert.NotNil(t, act.tmpl)
+	vars, err := act.Evaluate(EvalData{Update: Update{Content: Content{Title: "title"}}})
+	require.NoError(t, err)
+	assert.Equal(t, lib.Vars{"title": "title"}, vars)
+
+	vars, err = seq[1].(Action).Evaluate(EvalData{})
+	require.NoError(t, err)
+	assert.Nil(t, vars)
+
+	_, err = Sequence{If{Condition: `Update.Title`}}.Compile()
+	assert.ErrorIs(t, err, errs.ErrIfNotBool)
+
+	_, err = Sequence{If{Condition: "true", Actions: Sequence{
+		Action{Name: "tracker/method", With: lib.Vars{"title": "{{ .Update.Title "}},
+	}}}.Compile()
+	assert.Error(t, err)
+}
+
+func TestIf_Eval_Concurrent(t *testing.T) {
+	seq, err := Sequence{If{Condition: `Update.Title == Update.Body`}}.Compile()
+	require.NoError(t, err)
+	cond := seq[0].(If)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				// environments are reused, updates of other calls must not leak
+				title := fmt.Sprintf("%d-%d", i, j)
+				b, err := cond.Eval(Update{Content: Content{Title: title, Body: title}})
+				require.NoError(t, err)
+				assert.True(t, b)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+var benchData = EvalData{
+	Ticket: Ticket{ID: "ticket-id", Variations: Variations{"jira": Task{ID: "task-id", Content: Content{Title: "title"}}}},
+	Update: Update{Content: Content{
+		Title:  "[PTT] something",
+		Body:   "body",
+		Fields: TicketFields{"status": "open", "priority": "high"},
+	}},
+}
+
+var benchVars = lib.Vars{
+	"title":    `[{{ .Ticket.ID }}] {{ .Update.Title }}`,
+	"body":     `{{ .Update.Body }}`,
+	"fields":   `{{ seq (keys .Update.Fields) }}`,
+	"jira":     `{{ (.Ticket.Variations.Get "jira").ID }}`,
+	"priority": `{{ index .Update.Fields "priority" }}`,
+}
+
+func BenchmarkEvaluate(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Evaluate(benchVars, benchData); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTemplates_Execute(b *testing.B) {
+	tmpl, err := CompileVars(benchVars)
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err = tmpl.Execute(benchData); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIf_Eval(b *testing.B) {
+	cond := If{Condition: `string_contains(Update.Title, "[PTT]") && Update.Fields["status"] == "open"`}
+
+	b.Run("not compiled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := cond.Eval(benchData.Update); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("compiled", func(b *testing.B) {
+		seq, err := Sequence{cond}.Compile()
+		require.NoError(b, err)
+		compiled := seq[0].(If)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := compiled.Eval(benchData.Update); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestFuncs(t *testing.T) {
+	data := EvalData{Update: Update{
+		URL: "https://example.com",
+		Content: Content{
+			Title:  "  [PTT] fix the *bug*  ",
+			Fields: TicketFields{"labels": "bug,ui", "created": "2021-11-03T12:00:00Z", "empty": ""},
+		},
+	}}
+
+	tbl := []struct {
+		tmpl string
+		want string
+	}{
+		{`{{ regex_match "^\\[PTT\\]" (trim .Update.Title) }}`, "true"},
+		{`{{ regex_replace "\\s+" " " "a   b  c" }}`, "a b c"},
+		{`{{ .Update.Title | trim | lower }}`, "[ptt] fix the *bug*"},
+		{`{{ upper "abc" }} {{ title "hello big world" }}`, "ABC Hello Big World"},
+		{`{{ trim_prefix "[PTT] " (trim .Update.Title) | trim_suffix "*" }}`, "fix the *bug"},
+		{`{{ to_json .Update.Fields.labels }}`, `"bug,ui"`},
+		{`{{ (from_json "{\"a\": [1, 2]}").a }}`, "[1 2]"},
+		{`{{ parse_time "2006-01-02T15:04:05Z07:00" .Update.Fields.created | time_add "36h" | format_time "2006-01-02 15:04" }}`,
+			"2021-11-05 00:00"},
+		{`{{ time_before (parse_time "2006-01-02" "2021-11-03") now }}`, "true"},
+		{`{{ default "none" .Update.Fields.empty }} {{ default "none" .Update.Fields.labels }}`, "none bug,ui"},
+		{`{{ default "none" .Update.Fields.unknown }}`, "none"},
+		{`{{ coalesce .Update.Fields.empty .Update.Fields.unknown "last" }}`, "last"},
+		{`{{ list_contains (split "," .Update.Fields.labels) "ui" }}`, "true"},
+		{`{{ split "," .Update.Fields.labels | join "; " }}`, "bug; ui"},
+		{`{{ len (split "," .Update.Fields.empty) }}`, "0"},
+		{`{{ markdown_escape "*bold* [link](url) #1" }}`, `\*bold\* \[link\]\(url\) \#1`},
+		{`{{ build_url .Update.URL "issues" "a/b c" "?" "q" "x&y" "page" "2" }}`,
+			"https://example.com/issues/a%2Fb%20c?page=2&q=x%26y"},
+		{`{{ path_escape "a/b" }} {{ query_escape "a b" }}`, "a%2Fb a+b"},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			vars, err := Evaluate(lib.Vars{"v": tt.tmpl}, data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, vars.Get("v"))
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tmpl := range []string{
+			`{{ regex_match "(" "s" }}`,
+			`{{ regex_replace "(" "" "s" }}`,
+			`{{ from_json "{" }}`,
+			`{{ parse_time "2006-01-02" "yesterday" }}`,
+			`{{ time_add "tomorrow" now }}`,
+			`{{ build_url "https://example.com" "?" "q" }}`,
+			`{{ build_url ":" }}`,
+		} {
+			_, err := Evaluate(lib.Vars{"v": tmpl}, EvalData{})
+			assert.Error(t, err, tmpl)
+		}
+	})
+
+	t.Run("compiled patterns are cached", func(t *testing.T) {
+		re, err := compileRegex(`^cached-\d+$`)
+		require.NoError(t, err)
+		cached, err := compileRegex(`^cached-\d+$`)
+		require.NoError(t, err)
+		assert.Same(t, re, cached)
+
+		_, err = compileRegex("(")
+		require.Error(t, err)
+		_, ok := regexes.Load("(")
+		assert.False(t, ok, "invalid patterns must not be cached")
+	})
+
+	t.Run("in conditions", func(t *testing.T) {
+		for _, cond := range []string{
+			`regex_match("^\\[PTT\\]", trim(Update.Title))`,
+			`list_contains(split(",", Update.Fields["labels"]), "bug")`,
+			`lower(trim(Update.Title)) == "[ptt] fix the *bug*"`,
+			`time_before(parse_time("2006-01-02T15:04:05Z07:00", Update.Fields["created"]), now())`,
+			`default("none", Update.Fields["empty"]) == "none"`,
+			`from_json("[1]")[0] == 1`,
+		} {
+			seq, err := Sequence{If{Condition: cond}}.Compile()
+			require.NoError(t, err, cond)
+			b, err := seq[0].(If).Eval(data.Update)
+			require.NoError(t, err, cond)
+			assert.True(t, b, cond)
+		}
+	})
+}
+
+func TestBuildURL(t *testing.T) {
+	u, err := buildURL("https://example.com/api/", "v1", "tasks")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/api/v1/tasks", u)
+
+	u, err = buildURL("https://example.com?a=b", "?", "c", "d")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com?a=b&c=d", u)
+}
+
+func TestEvaluate_TypedValues(t *testing.T) {
+	vs, err := Evaluate(lib.Vars{
+		"num":    float64(42),
+		"bool":   true,
+		"labels": []interface{}{"{{ .Update.Title }}", "static, with comma", float64(1)},
+		"meta": map[string]interface{}{
+			"ticket": "{{ .Ticket.ID }}",
+			"nested": []interface{}{"{{ .Update.Fields.f1 }}"},
+		},
+	}, EvalData{
+		Ticket: Ticket{ID: "ticket-id"},
+		Update: Update{Content: Content{Title: "title", Fields: TicketFields{"f1": "f1v"}}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, lib.Vars{
+		"num":    float64(42),
+		"bool":   true,
+		"labels": []interface{}{"title", "static, with comma", float64(1)},
+		"meta":   map[string]interface{}{"ticket": "ticket-id", "nested": []interface{}{"f1v"}},
+	}, vs)
+
+	_, err = Evaluate(lib.Vars{"meta": map[string]interface{}{"k": []interface{}{"{{ .Update.Title "}}}, EvalData{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `parse "meta" variable: key "k": item 0`)
+
+	_, err = Evaluate(lib.Vars{"l": []interface{}{"{{ .Update.Unknown }}"}}, EvalData{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `evaluate the value of the "l" variable: item 0`)
+}
+
+func TestFuncs_Access(t *testing.T) {
+	t.Setenv("TEST_ALLOWED", "allowed")
+	t.Setenv("TEST_PREFIX_VAR", "prefixed")
+	t.Setenv("TEST_DENIED", "denied")
+
+	AllowEnv([]string{"TEST_ALLOWED", "TEST_PREFIX_*"})
+	SetSecrets(secrets.Static{"token": "secret-value"})
+	defer func() {
+		AllowEnv(nil)
+		SetSecrets(nil)
+	}()
+
+	vs, err := Evaluate(lib.Vars{
+		"allowed":  `{{ env "TEST_ALLOWED" }}`,
+		"prefixed": `{{ env "TEST_PREFIX_VAR" }}`,
+		"secret":   `{{ secret "token" }}`,
+	}, EvalData{})
+	require.NoError(t, err)
+	assert.Equal(t, lib.Vars{"allowed": "allowed", "prefixed": "prefixed", "secret": "secret-value"}, vs)
+
+	_, err = Evaluate(lib.Vars{"v": `{{ env "TEST_DENIED" }}`}, EvalData{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `environment variable "TEST_DENIED" is not allowed`)
+
+	_, err = Evaluate(lib.Vars{"v": `{{ secret "unknown" }}`}, EvalData{})
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+
+	seq, err := Sequence{If{Condition: `env("TEST_DENIED") == ""`}}.Compile()
+	require.NoError(t, err)
+	_, err = seq[0].(If).Eval(Update{})
+	assert.Error(t, err, "allowlist must be applied to conditions")
+
+	SetSecrets(nil)
+	_, err = Evaluate(lib.Vars{"v": `{{ secret "token" }}`}, EvalData{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,275 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/cappuccinotm/dastracker/app/secrets"
+)
+
+// map of functions, available in templates and conditions from the config file,
+// arguments, which are usually passed via the pipeline, go last
+var funcs = map[string]interface{}{
+	"env":    getEnv,
+	"secret": getSecret,
+	"keys": func(s map[string]string) []string {
+		res := make([]string, 0, len(s))
+		for k := range s {
+			res = append(res, k)
+		}
+		return res
+	},
+	"values": func(s map[string]string) []string {
+		res := make([]string, 0, len(s))
+		for _, v := range s {
+			res = append(res, v)
+		}
+		return res
+	},
+	"seq": func(s []string) string {
+		return strings.Join(s, ",")
+	},
+	"string_contains": strings.Contains,
+
+	// regular expressions
+	"regex_match": func(pattern, s string) (bool, error) {
+		re, err := compileRegex(pattern)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(s), nil
+	},
+	"regex_replace": func(pattern, repl, s string) (string, error) {
+		re, err := compileRegex(pattern)
+		if err != nil {
+			return "", err
+		}
+		return re.ReplaceAllString(s, repl), nil
+	},
+
+	// case and trim
+	"lower":       strings.ToLower,
+	"upper":       strings.ToUpper,
+	"title":       title,
+	"trim":        strings.TrimSpace,
+	"trim_prefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trim_suffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+
+	// json
+	"to_json": func(v interface{}) (string, error) {
+		bts, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(bts), nil
+	},
+	"from_json": func(s string) (interface{}, error) {
+		var res interface{}
+		if err := json.Unmarshal([]byte(s), &res); err != nil {
+			return nil, err
+		}
+		return res, nil
+	},
+
+	// dates
+	"now":         func() time.Time { return time.Now().UTC() },
+	"parse_time":  time.Parse,
+	"format_time": func(layout string, t time.Time) string { return t.Format(layout) },
+	"time_add": func(d string, t time.Time) (time.Time, error) {
+		dur, err := time.ParseDuration(d)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return t.Add(dur), nil
+	},
+	"time_before": func(t, u time.Time) bool { return t.Before(u) },
+
+	// defaults
+	"default": func(def, v interface{}) interface{} {
+		if empty(v) {
+			return def
+		}
+		return v
+	},
+	"coalesce": func(v ...interface{}) interface{} {
+		for _, vv := range v {
+			if !empty(vv) {
+				return vv
+			}
+		}
+		return nil
+	},
+
+	// lists
+	"list_contains": func(l []string, s string) bool {
+		for _, v := range l {
+			if v == s {
+				return true
+			}
+		}
+		return false
+	},
+	"join": func(sep string, l []string) string { return strings.Join(l, sep) },
+	"split": func(sep, s string) []string {
+		if s == "" {
+			return []string{}
+		}
+		return strings.Split(s, sep)
+	},
+
+	// markdown and urls
+	"markdown_escape": markdownEscape,
+	"build_url":       buildURL,
+	"path_escape":     url.PathEscape,
+	"query_escape":    url.QueryEscape,
+}
+
+// access restricts the env function and provides the values for the secret
+// function, it is set up once at startup
+var access struct {
+	sync.RWMutex
+	envAllowed []string // nil allows all variables
+	secrets    secrets.Provider
+}
+
+// AllowEnv restricts the env function to the listed environment variables,
+// a name, ending with "*", allows all variables with the prefix.
+// nil allows all variables.
+func AllowEnv(names []string) {
+	access.Lock()
+	defer access.Unlock()
+	access.envAllowed = names
+}
+
+// SetSecrets sets the provider of values for the secret function.
+func SetSecrets(p secrets.Provider) {
+	access.Lock()
+	defer access.Unlock()
+	access.secrets = p
+}
+
+func getEnv(name string) (string, error) {
+	access.RLock()
+	defer access.RUnlock()
+
+	if access.envAllowed == nil {
+		return os.Getenv(name), nil
+	}
+
+	for _, allowed := range access.envAllowed {
+		if name == allowed || (strings.HasSuffix(allowed, "*") && strings.HasPrefix(name, allowed[:len(allowed)-1])) {
+			return os.Getenv(name), nil
+		}
+	}
+
+	return "", fmt.Errorf("environment variable %q is not allowed", name)
+}
+
+func getSecret(name string) (string, error) {
+	access.RLock()
+	defer access.RUnlock()
+
+	if access.secrets == nil {
+		return "", fmt.Errorf("secret %q: no secrets provider is set", name)
+	}
+
+	return access.secrets.Get(name)
+}
+
+// regexes caches compiled patterns of regex functions, as patterns
+// usually come from the configuration and are evaluated on each update.
+var regexes sync.Map // map[string]*regexp.Regexp
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexes.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexes.Store(pattern, re)
+	return re, nil
+}
+
+// title makes the first letter of each word upper case.
+func title(s string) string {
+	rs := []rune(s)
+	for i, r := range rs {
+		if i == 0 || unicode.IsSpace(rs[i-1]) {
+			rs[i] = unicode.ToTitle(r)
+		}
+	}
+	return string(rs)
+}
+
+// empty returns true if the value is nil or the zero value of its type
+// or an empty collection.
+func empty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	default:
+		return rv.IsZero()
+	}
+}
+
+var mdSpecial = strings.NewReplacer(func() []string {
+	var res []string
+	for _, c := range "\\`*_{}[]()#+-.!|<>~" {
+		res = append(res, string(c), "\\"+string(c))
+	}
+	return res
+}()...)
+
+// markdownEscape escapes the characters, which have special meaning in markdown.
+func markdownEscape(s string) string { return mdSpecial.Replace(s) }
+
+// buildURL joins the base URL with the escaped path segments and query
+// parameters, given as key-value pairs after "?", e.g.
+// build_url "https://example.com" "issues" "my issue" "?" "page" "2".
+func buildURL(base string, parts ...string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	segments := parts
+	var query []string
+	for idx, p := range parts {
+		if p == "?" {
+			segments, query = parts[:idx], parts[idx+1:]
+			break
+		}
+	}
+
+	if len(query)%2 != 0 {
+		return "", fmt.Errorf("query parameter %q has no value", query[len(query)-1])
+	}
+
+	for _, seg := range segments {
+		u.RawPath = strings.TrimSuffix(u.EscapedPath(), "/") + "/" + url.PathEscape(seg)
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + seg
+	}
+
+	q := u.Query()
+	for i := 0; i < len(query); i += 2 {
+		q.Add(query[i], query[i+1])
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/lib"
+)
+
+// JobRun describes a single execution of the job over the update.
+type JobRun struct {
+	ID          string        `json:"id"`
+	TriggerName string        `json:"trigger_name"`
+	JobName     string        `json:"job_name"`
+	Update      Locator       `json:"update"`
+	TicketID    string        `json:"ticket_id,omitempty"`
+	StartedAt   time.Time     `json:"started_at"`
+	Duration    time.Duration `json:"duration"`
+	Steps       []StepRun     `json:"steps"`
+	Error       string        `json:"error,omitempty"`
+	DryRun      bool          `json:"dry_run,omitempty"` // trackers were not called and the ticket was not changed
+}
+
+// Failed returns true if the run has finished with an error.
+func (r JobRun) Failed() bool { return r.Error != "" }
+
+// StepRun describes an execution of a single step of the job.
+// Either Action or Condition is set, depending on the kind of the step.
+type StepRun struct {
+	// Depth is the nesting level of the step, steps under the
+	// condition have the depth of the condition plus one.
+	Depth int `json:"depth"`
+
+	Action   string   `json:"action,omitempty"`
+	TaskID   string   `json:"task_id,omitempty"` // ID of the task, requested to be changed
+	Vars     lib.Vars `json:"vars,omitempty"`
+	Response *Task    `json:"response,omitempty"`
+
+	Condition string `json:"condition,omitempty"`
+	Result    bool   `json:"result,omitempty"`
+
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Plan returns the human-readable list of the steps of the run.
+func (r JobRun) Plan() string {
+	sb := &strings.Builder{}
+
+	for _, step := range r.Steps {
+		sb.WriteString(strings.Repeat("  ", step.Depth))
+
+		switch {
+		case step.Action != "":
+			fmt.Fprintf(sb, "call %s", step.Action)
+			if step.TaskID != "" {
+				fmt.Fprintf(sb, " on task %q", step.TaskID)
+			}
+			if len(step.Vars) > 0 {
+				sb.WriteString(" with ")
+				writeVars(sb, step.Vars)
+			}
+		default:
+			fmt.Fprintf(sb, "if %s: %t", step.Condition, step.Result)
+		}
+
+		if step.Error != "" {
+			fmt.Fprintf(sb, ", failed: %s", step.Error)
+		}
+
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+func writeVars(sb *strings.Builder, vars lib.Vars) {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		val, err := json.Marshal(vars[k])
+		if err != nil {
+			val = []byte(fmt.Sprintf("%q", vars.Get(k)))
+		}
+		fmt.Fprintf(sb, "%s=%s", k, val)
+	}
+}
@@ -0,0 +1,23 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/cappuccinotm/dastracker/lib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobRun_Plan(t *testing.T) {
+	run := JobRun{Steps: []StepRun{
+		{Condition: `Update.Title == "title"`, Result: true},
+		{Depth: 1, Action: "tracker/create", Vars: lib.Vars{"title": "title", "body": "line\nbreak"}},
+		{Depth: 1, Action: "tracker/update", TaskID: "task-id", Error: "tracker error"},
+		{Condition: "false"},
+	}}
+
+	assert.Equal(t, `if Update.Title == "title": true
+  call tracker/create with body="line\nbreak", title="title"
+  call tracker/update on task "task-id", failed: tracker error
+if false: false
+`, run.Plan())
+}
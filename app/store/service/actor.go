@@ -7,11 +7,13 @@ import (
 	"time"
 
 	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/secrets"
 	"github.com/cappuccinotm/dastracker/app/store"
 	"github.com/cappuccinotm/dastracker/app/store/engine"
 	"github.com/cappuccinotm/dastracker/app/tracker"
 	"github.com/cappuccinotm/dastracker/pkg/logx"
 	"github.com/go-pkgz/syncs"
+	"github.com/google/uuid"
 	"golang.org/x/sync/errgroup"
 	"net/http"
 	"sync"
@@ -21,15 +23,26 @@ import (
 // process updates concurrently at the same time
 const maxConcurrentUpdates = 15
 
+// maxCallAttempts is the maximal number of attempts to call the tracker,
+// which is temporarily unavailable or limits the calls.
+const maxCallAttempts = 3
+
+// dryRunTaskID is the ID of the task, returned in synthetic responses
+// to the requests, which would create a task in the tracker.
+const dryRunTaskID = "dry-run"
+
 // Actor receives updates from the Tracker and follows the calls the actions in the
 // wrapped tracker according to the job provided by the Flow.
 type Actor struct {
 	Trackers             map[string]tracker.Interface
 	TicketsStore         engine.Tickets
+	Runs                 engine.Runs // optional, history of job runs is not kept if nil
 	Flow                 engine.Flow
 	Log                  logx.Logger
 	UpdateTimeout        time.Duration
 	SubscriptionsManager *SubscriptionsManager
+	DryRun               bool              // don't call trackers in all jobs
+	Redactor             *secrets.Redactor // optional, redacts secrets from the history of job runs
 }
 
 // Listen runs the updates' listener. Always returns non-nil error.
@@ -67,11 +80,8 @@ func (s *Actor) Listen(ctx context.Context) error {
 func (s *Actor) handleUpdate(ctx context.Context, upd store.Update) {
 	s.Log.Printf("[DEBUG] received update %+v", upd)
 
-	if s.UpdateTimeout != 0 {
-		var cancel func()
-		ctx, cancel = context.WithTimeout(ctx, s.UpdateTimeout)
-		defer cancel()
-	}
+	ctx, cancel := s.withUpdateTimeout(ctx)
+	defer cancel()
 
 	sub, err := store.GetSubscription(ctx)
 	if err != nil {
@@ -91,15 +101,97 @@ func (s *Actor) handleUpdate(ctx context.Context, upd store.Update) {
 		job := job
 		swg.Go(func(ctx context.Context) {
 			s.Log.Printf("[DEBUG] running job %q, triggered by %q", job.Name, job.TriggerName)
-			if err := s.runSequence(ctx, job.Actions, upd); err != nil {
-				s.Log.Printf("[WARN] failed to handle update %v for job %q: %v", upd, job.Name, err)
-				return
-			}
+			s.runJob(ctx, job, upd, false)
 		})
 	}
 	swg.Wait()
 }
 
+// RunJob runs the job with the given name over the update, regardless
+// of the trigger of the job, and returns the run. If dryRun is set,
+// trackers are not called, even if the job is not configured so.
+// Failure of the job itself is reported in the returned run.
+func (s *Actor) RunJob(ctx context.Context, jobName string, upd store.Update, dryRun bool) (store.JobRun, error) {
+	jobs, err := s.Flow.ListJobs(ctx)
+	if err != nil {
+		return store.JobRun{}, fmt.Errorf("list jobs: %w", err)
+	}
+
+	for _, job := range jobs {
+		if job.Name != jobName {
+			continue
+		}
+
+		s.Log.Printf("[INFO] running job %q manually over update from %s", job.Name, upd.ReceivedFrom)
+
+		ctx, cancel := s.withUpdateTimeout(ctx)
+		defer cancel()
+
+		return s.runJob(ctx, job, upd, dryRun), nil
+	}
+
+	return store.JobRun{}, fmt.Errorf("job %q: %w", jobName, errs.ErrNotFound)
+}
+
+// runJob runs the job over the update and puts the run to the history.
+func (s *Actor) runJob(ctx context.Context, job store.Job, upd store.Update, dryRun bool) store.JobRun {
+	run := store.JobRun{
+		ID:          uuid.NewString(),
+		TriggerName: job.TriggerName,
+		JobName:     job.Name,
+		Update:      upd.ReceivedFrom,
+		StartedAt:   time.Now().UTC(),
+		DryRun:      s.DryRun || job.DryRun || dryRun,
+	}
+
+	err := s.runSequence(ctx, job.Actions, upd, &run, 0)
+	run.Duration = time.Since(run.StartedAt)
+	if err != nil {
+		run.Error = err.Error()
+		s.Log.Printf("[WARN] failed to handle update %v for job %q: %v", upd, job.Name, err)
+	}
+
+	if run.DryRun {
+		s.Log.Printf("[INFO] dry run of job %q over update from %s, plan:\n%s", job.Name, upd.ReceivedFrom, run.Plan())
+	}
+
+	s.saveRun(run)
+	return run
+}
+
+// call calls the tracker and repeats the call, if the tracker is temporarily
+// unavailable or limits the calls, unless the delay exceeds the deadline.
+func (s *Actor) call(ctx context.Context, trkName string, req tracker.Request) (tracker.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := s.Trackers[trkName].Call(ctx, req)
+		delay, retry := errs.RetryAfter(err)
+		if err == nil || !retry || attempt >= maxCallAttempts {
+			return resp, err
+		}
+
+		if delay == 0 {
+			delay = time.Duration(attempt) * time.Second
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err
+		}
+
+		s.Log.Printf("[WARN] call %s/%s failed, retrying in %s: %v", trkName, req.Method, delay, err)
+		select {
+		case <-ctx.Done():
+			return resp, err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (s *Actor) withUpdateTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.UpdateTimeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.UpdateTimeout)
+}
+
 // HandleWebhook handles all webhooks to trackers
 func (s *Actor) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	sub, err := store.GetSubscription(r.Context())
@@ -137,8 +229,9 @@ func (s *Actor) registerTriggers(ctx context.Context) error {
 			}
 
 			resp, err := s.Trackers[trigger.Tracker].Subscribe(ctx, tracker.SubscribeReq{
-				WebhookURL: sub.URL(),
-				Vars:       vars,
+				WebhookURL:   sub.URL(),
+				Vars:         vars,
+				Subscription: sub,
 			})
 			if err != nil {
 				return fmt.Errorf("subscribe %q to %q: %w", trigger.Name, trigger.Tracker, err)
@@ -196,7 +289,27 @@ func (s *Actor) unregisterTriggers(ctx context.Context) {
 	wg.Wait()
 }
 
-func (s *Actor) runSequence(ctx context.Context, seq store.Sequence, upd store.Update) error {
+// saveRun puts the run to the history, if it is enabled. The run keeps
+// its ID on failure, as it has been already sent to the plugins.
+func (s *Actor) saveRun(run store.JobRun) {
+	if s.Runs == nil {
+		return
+	}
+
+	// the run must be saved even if the update has timed out
+	runID, err := s.Runs.Create(context.Background(), run)
+	if err != nil {
+		s.Log.Printf("[WARN] failed to save run %s of job %q over update from %s: %v",
+			run.ID, run.JobName, run.Update, err)
+		return
+	}
+
+	s.Log.Printf("[DEBUG] saved run %s of job %q", runID, run.JobName)
+}
+
+// runSequence runs the steps of the sequence and records them to the run.
+// Depth is the nesting level of the sequence.
+func (s *Actor) runSequence(ctx context.Context, seq store.Sequence, upd store.Update, run *store.JobRun, depth int) error {
 	ticket, err := s.TicketsStore.Get(ctx, engine.GetRequest{Locator: upd.ReceivedFrom})
 	if err != nil && !errors.Is(err, errs.ErrNotFound) {
 		return fmt.Errorf("get ticket by locator %s: %w", upd.ReceivedFrom, err)
@@ -216,18 +329,34 @@ func (s *Actor) runSequence(ctx context.Context, seq store.Sequence, upd store.U
 				modifiesUpdatedTask = true
 			}
 
-			if ticket, err = s.act(ctx, step, ticket, upd); err != nil {
+			rec := store.StepRun{Depth: depth, Action: step.Name, StartedAt: time.Now().UTC()}
+			ticket, err = s.act(ctx, step, ticket, upd, run, &rec)
+			rec.Duration = time.Since(rec.StartedAt)
+			if err != nil {
+				rec.Error = err.Error()
+			}
+			run.Steps = append(run.Steps, rec)
+
+			if err != nil {
 				return fmt.Errorf("act: %w", err)
 			}
 		case store.If:
+			rec := store.StepRun{Depth: depth, Condition: step.Condition, StartedAt: time.Now().UTC()}
 			b, err := step.Eval(upd)
+			rec.Duration = time.Since(rec.StartedAt)
+			rec.Result = b
+			if err != nil {
+				rec.Error = err.Error()
+			}
+			run.Steps = append(run.Steps, rec)
+
 			if err != nil {
 				return fmt.Errorf("evaluate if: %w", err)
 			}
 			if !b {
 				continue
 			}
-			if err := s.runSequence(ctx, step.Actions, upd); err != nil {
+			if err := s.runSequence(ctx, step.Actions, upd, run, depth+1); err != nil {
 				return fmt.Errorf("run sequence: %w", err)
 			}
 		default:
@@ -239,8 +368,14 @@ func (s *Actor) runSequence(ctx context.Context, seq store.Sequence, upd store.U
 		ticket.Patch(upd)
 	}
 
+	// the ticket contains synthetic responses of trackers, so it mustn't be saved
+	if run.DryRun {
+		run.TicketID = ticket.ID
+		return nil
+	}
+
 	if ticket.ID == "" {
-		if _, err = s.TicketsStore.Create(ctx, ticket); err != nil {
+		if run.TicketID, err = s.TicketsStore.Create(ctx, ticket); err != nil {
 			return fmt.Errorf("create ticket: %w", err)
 		}
 
@@ -251,29 +386,57 @@ func (s *Actor) runSequence(ctx context.Context, seq store.Sequence, upd store.U
 		return fmt.Errorf("update ticket from %s: %w", upd.ReceivedFrom, err)
 	}
 
+	run.TicketID = ticket.ID
 	return nil
 }
 
-func (s *Actor) act(ctx context.Context, act store.Action, ticket store.Ticket, upd store.Update) (store.Ticket, error) {
+// act calls the action and records the evaluated variables
+// and the response of the tracker to rec. In case of dry run,
+// the tracker is not called and the response is synthetic.
+func (s *Actor) act(ctx context.Context, act store.Action, ticket store.Ticket, upd store.Update,
+	run *store.JobRun, rec *store.StepRun) (store.Ticket, error) {
 	// TODO(semior): add support of detached calls
-	vars, err := store.Evaluate(act.With, store.EvalData{Update: upd, Ticket: ticket})
+	vars, err := act.Evaluate(store.EvalData{Update: upd, Ticket: ticket})
 	if err != nil {
 		return store.Ticket{}, fmt.Errorf("evaluate variables for %q action: %w", act.Name, err)
 	}
+	rec.Vars = s.Redactor.RedactVars(vars)
 
 	trkName, method := act.Path()
 
+	req := tracker.Request{
+		TaskID:      ticket.Variations.Get(trkName).ID,
+		Method:      method,
+		Vars:        vars,
+		Update:      upd,
+		Ticket:      ticket,
+		JobName:     run.JobName,
+		TriggerName: run.TriggerName,
+		RunID:       run.ID,
+	}
+	rec.TaskID = req.TaskID
+
+	if run.DryRun {
+		// the task is left as is, so that the following steps are
+		// evaluated over the known state of the ticket
+		task := ticket.Variations.Get(trkName)
+		if task.ID == "" {
+			task.ID = dryRunTaskID
+		}
+		s.Log.Printf("[DEBUG] dry run of action %s with vars %+v", act.Name, vars)
+		rec.Response = &task
+		ticket.Variations.Set(trkName, task)
+		return ticket, nil
+	}
+
 	s.Log.Printf("[DEBUG] running action %s with vars %+v", act.Name, vars)
 
-	resp, err := s.Trackers[trkName].Call(ctx, tracker.Request{
-		TaskID: ticket.Variations.Get(trkName).ID,
-		Method: method,
-		Vars:   vars,
-	})
+	resp, err := s.call(ctx, trkName, req)
 	if err != nil {
 		return store.Ticket{}, fmt.Errorf("call to %s: %w", act.Name, err)
 	}
 	s.Log.Printf("[DEBUG] received response from tracker %s on action %s: %v", trkName, act.Name, resp)
+	rec.Response = &resp.Task
 
 	ticket.Variations.Set(trkName, resp.Task)
 	return ticket, nil
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"github.com/cappuccinotm/dastracker/app/errs"
+	"github.com/cappuccinotm/dastracker/app/secrets"
 	"github.com/cappuccinotm/dastracker/app/store"
 	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/app/store/engine/memory"
 	"github.com/cappuccinotm/dastracker/app/tracker"
 	"github.com/cappuccinotm/dastracker/lib"
 	"github.com/cappuccinotm/dastracker/pkg/logx"
@@ -53,7 +55,32 @@ func TestActor_runSequence(t *testing.T) {
 			assert.Equal(t, tracker.Request{
 				Method: "create-or-update",
 				TaskID: "task-id",
-				Vars:   map[string]string{"url": "https://blah.com"},
+				Vars:   lib.Vars{"url": "https://blah.com"},
+				Update: store.Update{
+					URL:          "https://blah.com",
+					ReceivedFrom: store.Locator{Tracker: "other-tracker", ID: "task-id"},
+					Content: store.Content{
+						Body:   "received-body",
+						Title:  "received-title",
+						Fields: map[string]string{"field": "received-value"},
+					},
+				},
+				Ticket: store.Ticket{
+					ID: "ticket-id",
+					Variations: store.Variations{
+						"other-tracker": {ID: "task-id"},
+						"tracker": {
+							ID: "task-id",
+							Content: store.Content{
+								Body:   "ticket-body",
+								Title:  "ticket-title",
+								Fields: map[string]string{"field": "value"},
+							},
+						}},
+				},
+				JobName:     "job",
+				TriggerName: "trigger",
+				RunID:       "run-id",
 			}, req)
 			return tracker.Response{Task: store.Task{
 				ID: "task-id",
@@ -90,9 +117,10 @@ func TestActor_runSequence(t *testing.T) {
 			return nil
 		}
 
+		run := &store.JobRun{ID: "run-id", JobName: "job", TriggerName: "trigger"}
 		err := svc.runSequence(context.Background(), store.Sequence{store.Action{
 			Name: "tracker/create-or-update",
-			With: map[string]string{"url": "{{.Update.URL}}"}}},
+			With: lib.Vars{"url": "{{.Update.URL}}"}}},
 			store.Update{
 				URL:          "https://blah.com",
 				ReceivedFrom: store.Locator{Tracker: "other-tracker", ID: "task-id"},
@@ -102,8 +130,23 @@ func TestActor_runSequence(t *testing.T) {
 					Fields: map[string]string{"field": "received-value"},
 				},
 			},
+			run, 0,
 		)
 		require.NoError(t, err)
+
+		assert.Equal(t, "ticket-id", run.TicketID)
+		require.Len(t, run.Steps, 1)
+		assert.Equal(t, "tracker/create-or-update", run.Steps[0].Action)
+		assert.Equal(t, lib.Vars{"url": "https://blah.com"}, run.Steps[0].Vars)
+		assert.Equal(t, &store.Task{
+			ID: "task-id",
+			Content: store.Content{
+				Body:   "updated-body",
+				Title:  "updated-title",
+				Fields: map[string]string{"field": "updated-value"},
+			},
+		}, run.Steps[0].Response)
+		assert.Empty(t, run.Steps[0].Error)
 	})
 
 	t.Run("creating new ticket", func(t *testing.T) {
@@ -126,7 +169,17 @@ func TestActor_runSequence(t *testing.T) {
 			assert.Equal(t, tracker.Request{
 				TaskID: "",
 				Method: "create-or-update",
-				Vars:   map[string]string{"url": "https://blah.com"},
+				Vars:   lib.Vars{"url": "https://blah.com"},
+				Update: store.Update{
+					ReceivedFrom: store.Locator{Tracker: "other-tracker", ID: "received-task-id"},
+					URL:          "https://blah.com",
+					Content: store.Content{
+						Body:   "received-body",
+						Title:  "received-title",
+						Fields: map[string]string{"field": "received-value"},
+					},
+				},
+				Ticket: store.Ticket{Variations: store.Variations{"other-tracker": {ID: "received-task-id"}}},
 			}, req)
 			return tracker.Response{Task: store.Task{
 				ID: "created-task-id",
@@ -151,12 +204,13 @@ func TestActor_runSequence(t *testing.T) {
 					Fields: map[string]string{"field": "received-value"},
 				}},
 			}}, ticket)
-			return "", nil // should not be used
+			return "created-ticket-id", nil
 		}
 
+		run := &store.JobRun{}
 		err := svc.runSequence(context.Background(), store.Sequence{store.Action{
 			Name: "tracker/create-or-update",
-			With: map[string]string{"url": "{{.Update.URL}}"},
+			With: lib.Vars{"url": "{{.Update.URL}}"},
 		}}, store.Update{
 			ReceivedFrom: store.Locator{Tracker: "other-tracker", ID: "received-task-id"},
 			URL:          "https://blah.com",
@@ -165,8 +219,292 @@ func TestActor_runSequence(t *testing.T) {
 				Title:  "received-title",
 				Fields: map[string]string{"field": "received-value"},
 			},
-		})
+		}, run, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "created-ticket-id", run.TicketID)
+	})
+
+	t.Run("records conditions and failed steps", func(t *testing.T) {
+		tickets := &engine.TicketsMock{}
+		trk := &tracker.InterfaceMock{}
+		svc := &Actor{
+			Trackers:     map[string]tracker.Interface{"tracker": trk},
+			TicketsStore: tickets,
+			Log:          logx.Nop(),
+		}
+
+		tickets.GetFunc = func(context.Context, engine.GetRequest) (store.Ticket, error) {
+			return store.Ticket{}, errs.ErrNotFound
+		}
+		trk.CallFunc = func(context.Context, tracker.Request) (tracker.Response, error) {
+			return tracker.Response{}, errors.New("tracker error")
+		}
+
+		run := &store.JobRun{}
+		err := svc.runSequence(context.Background(), store.Sequence{
+			store.If{Condition: `Update.Title == "other"`, Actions: store.Sequence{
+				store.Action{Name: "tracker/skipped"},
+			}},
+			store.If{Condition: `Update.Title == "title"`, Actions: store.Sequence{
+				store.Action{Name: "tracker/failed"},
+			}},
+		}, store.Update{
+			ReceivedFrom: store.Locator{Tracker: "other-tracker", ID: "task-id"},
+			Content:      store.Content{Title: "title"},
+		}, run, 0)
+		require.Error(t, err)
+
+		require.Len(t, run.Steps, 3)
+		assert.Equal(t, `Update.Title == "other"`, run.Steps[0].Condition)
+		assert.False(t, run.Steps[0].Result)
+		assert.Equal(t, `Update.Title == "title"`, run.Steps[1].Condition)
+		assert.True(t, run.Steps[1].Result)
+		assert.Equal(t, "tracker/failed", run.Steps[2].Action)
+		assert.Equal(t, 1, run.Steps[2].Depth)
+		assert.Contains(t, run.Steps[2].Error, "tracker error")
+		assert.Nil(t, run.Steps[2].Response)
+	})
+
+	t.Run("redacts secrets from the recorded variables", func(t *testing.T) {
+		tickets := &engine.TicketsMock{}
+		trk := &tracker.InterfaceMock{}
+		redactor := &secrets.Redactor{}
+		redactor.Add("secret-value")
+		svc := &Actor{
+			Trackers:     map[string]tracker.Interface{"tracker": trk},
+			TicketsStore: tickets,
+			Log:          logx.Nop(),
+			Redactor:     redactor,
+		}
+
+		tickets.GetFunc = func(context.Context, engine.GetRequest) (store.Ticket, error) {
+			return store.Ticket{}, errs.ErrNotFound
+		}
+		tickets.CreateFunc = func(context.Context, store.Ticket) (string, error) { return "ticket-id", nil }
+		trk.CallFunc = func(_ context.Context, req tracker.Request) (tracker.Response, error) {
+			assert.Equal(t, lib.Vars{"token": "secret-value"}, req.Vars, "tracker must receive the secret")
+			return tracker.Response{Task: store.Task{ID: "task-id"}}, nil
+		}
+
+		run := &store.JobRun{}
+		err := svc.runSequence(context.Background(), store.Sequence{
+			store.Action{Name: "tracker/method", With: lib.Vars{"token": "secret-value"}},
+		}, store.Update{ReceivedFrom: store.Locator{Tracker: "other-tracker", ID: "task-id"}}, run, 0)
+		require.NoError(t, err)
+
+		require.Len(t, run.Steps, 1)
+		assert.Equal(t, lib.Vars{"token": "*****"}, run.Steps[0].Vars)
+	})
+}
+
+func TestActor_handleUpdate(t *testing.T) {
+	flow := &engine.FlowMock{}
+	tickets := &engine.TicketsMock{}
+	runs := &engine.RunsMock{}
+	trk := &tracker.InterfaceMock{}
+	svc := &Actor{
+		Trackers:     map[string]tracker.Interface{"tracker": trk},
+		TicketsStore: tickets,
+		Runs:         runs,
+		Flow:         flow,
+		Log:          logx.Nop(),
+	}
+
+	flow.ListSubscribedJobsFunc = func(_ context.Context, triggerName string) ([]store.Job, error) {
+		assert.Equal(t, "trigger", triggerName)
+		return []store.Job{{Name: "job", TriggerName: "trigger", Actions: store.Sequence{
+			store.Action{Name: "tracker/update"},
+		}}}, nil
+	}
+	tickets.GetFunc = func(context.Context, engine.GetRequest) (store.Ticket, error) {
+		return store.Ticket{ID: "ticket-id"}, nil
+	}
+	tickets.UpdateFunc = func(context.Context, store.Ticket) error { return nil }
+	trk.CallFunc = func(context.Context, tracker.Request) (tracker.Response, error) {
+		return tracker.Response{Task: store.Task{ID: "task-id"}}, nil
+	}
+	runs.CreateFunc = func(_ context.Context, run store.JobRun) (string, error) {
+		assert.Equal(t, "job", run.JobName)
+		assert.Equal(t, "trigger", run.TriggerName)
+		assert.Equal(t, store.Locator{Tracker: "tracker", ID: "task-id"}, run.Update)
+		assert.Equal(t, "ticket-id", run.TicketID)
+		assert.False(t, run.StartedAt.IsZero())
+		assert.Empty(t, run.Error)
+		assert.Len(t, run.Steps, 1)
+		return "run-id", nil
+	}
+
+	ctx := store.PutSubscription(context.Background(), store.Subscription{TriggerName: "trigger"})
+	svc.handleUpdate(ctx, store.Update{ReceivedFrom: store.Locator{Tracker: "tracker", ID: "task-id"}})
+	assert.Len(t, runs.CreateCalls(), 1)
+}
+
+func TestActor_RunJob(t *testing.T) {
+	flow := &engine.FlowMock{}
+	tickets := &engine.TicketsMock{}
+	trk := &tracker.InterfaceMock{}
+	svc := &Actor{
+		Trackers:     map[string]tracker.Interface{"tracker": trk},
+		TicketsStore: tickets,
+		Runs:         memory.NewRuns(),
+		Flow:         flow,
+		Log:          logx.Nop(),
+	}
+
+	flow.ListJobsFunc = func(context.Context) ([]store.Job, error) {
+		return []store.Job{{Name: "job", TriggerName: "trigger", Actions: store.Sequence{
+			store.Action{Name: "tracker/update", With: lib.Vars{"title": "{{.Update.Title}}"}},
+		}}}, nil
+	}
+	tickets.GetFunc = func(context.Context, engine.GetRequest) (store.Ticket, error) {
+		return store.Ticket{}, errs.ErrNotFound
+	}
+	tickets.CreateFunc = func(context.Context, store.Ticket) (string, error) { return "ticket-id", nil }
+	var runID string
+	trk.CallFunc = func(_ context.Context, req tracker.Request) (tracker.Response, error) {
+		assert.Equal(t, lib.Vars{"title": "title"}, req.Vars)
+		assert.Equal(t, "job", req.JobName)
+		assert.Equal(t, "trigger", req.TriggerName)
+		runID = req.RunID
+		return tracker.Response{Task: store.Task{ID: "task-id"}}, nil
+	}
+
+	_, err := svc.RunJob(context.Background(), "unknown", store.Update{}, false)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+
+	upd := store.Update{
+		ReceivedFrom: store.Locator{Tracker: "other-tracker", ID: "other-task-id"},
+		Content:      store.Content{Title: "title"},
+	}
+	run, err := svc.RunJob(context.Background(), "job", upd, false)
+	require.NoError(t, err)
+	assert.NotEmpty(t, run.ID)
+	assert.Equal(t, runID, run.ID, "plugins must receive the ID of the run in the history")
+	assert.Equal(t, "job", run.JobName)
+	assert.Equal(t, "ticket-id", run.TicketID)
+	assert.False(t, run.Failed())
+
+	saved, err := svc.Runs.Get(context.Background(), run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, run.Steps, saved.Steps)
+
+	// the ID, sent to the plugins, must be kept, even if the run isn't saved
+	svc.Runs = &engine.RunsMock{CreateFunc: func(context.Context, store.JobRun) (string, error) {
+		return "", errors.New("database is locked")
+	}}
+	run, err = svc.RunJob(context.Background(), "job", upd, false)
+	require.NoError(t, err)
+	assert.NotEmpty(t, run.ID)
+	assert.Equal(t, runID, run.ID)
+
+	svc.Runs = nil
+	run, err = svc.RunJob(context.Background(), "job", upd, false)
+	require.NoError(t, err)
+	assert.NotEmpty(t, run.ID)
+	assert.Equal(t, runID, run.ID)
+}
+
+func TestActor_call(t *testing.T) {
+	call := func(t *testing.T, ctx context.Context, failures ...error) (int, error) {
+		attempts := 0
+		trk := &tracker.InterfaceMock{CallFunc: func(context.Context, tracker.Request) (tracker.Response, error) {
+			attempts++
+			if attempts <= len(failures) {
+				return tracker.Response{}, failures[attempts-1]
+			}
+			return tracker.Response{Task: store.Task{ID: "task-id"}}, nil
+		}}
+		svc := &Actor{Trackers: map[string]tracker.Interface{"tracker": trk}, Log: logx.Nop()}
+		resp, err := svc.call(ctx, "tracker", tracker.Request{Method: "update"})
+		if err == nil {
+			assert.Equal(t, "task-id", resp.Task.ID)
+		}
+		return attempts, err
+	}
+
+	t.Run("temporary errors are retried", func(t *testing.T) {
+		attempts, err := call(t, context.Background(),
+			errs.ErrRateLimited{RetryAfter: time.Millisecond},
+			errs.ErrUnavailable{RetryAfter: time.Millisecond})
 		require.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("attempts are limited", func(t *testing.T) {
+		attempts, err := call(t, context.Background(),
+			errs.ErrUnavailable{RetryAfter: time.Millisecond},
+			errs.ErrUnavailable{RetryAfter: time.Millisecond},
+			errs.ErrUnavailable{RetryAfter: time.Millisecond})
+		assert.ErrorAs(t, err, &errs.ErrUnavailable{})
+		assert.Equal(t, maxCallAttempts, attempts)
+	})
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		attempts, err := call(t, context.Background(), errs.ErrInvalidArgument("title is empty"))
+		assert.Equal(t, errs.ErrInvalidArgument("title is empty"), err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("delay exceeds the deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		attempts, err := call(t, ctx, errs.ErrRateLimited{RetryAfter: time.Minute})
+		assert.ErrorAs(t, err, &errs.ErrRateLimited{})
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func TestActor_runJob_DryRun(t *testing.T) {
+	tickets := &engine.TicketsMock{}
+	svc := &Actor{
+		// calls to the tracker or changes of the ticket would panic
+		Trackers:     map[string]tracker.Interface{"tracker": &tracker.InterfaceMock{}},
+		TicketsStore: tickets,
+		Runs:         memory.NewRuns(),
+		Log:          logx.Nop(),
+	}
+
+	tickets.GetFunc = func(context.Context, engine.GetRequest) (store.Ticket, error) {
+		return store.Ticket{ID: "ticket-id", Variations: store.Variations{
+			"other-tracker": {ID: "other-task-id"},
+		}}, nil
+	}
+
+	job := store.Job{Name: "job", Actions: store.Sequence{
+		store.Action{Name: "tracker/create", With: lib.Vars{"title": "{{.Update.Title}}"}},
+		store.Action{Name: "tracker/update", With: lib.Vars{"id": `{{ (index .Ticket.Variations "tracker").ID }}`}},
+	}}
+	upd := store.Update{
+		ReceivedFrom: store.Locator{Tracker: "other-tracker", ID: "other-task-id"},
+		Content:      store.Content{Title: "title"},
+	}
+
+	check := func(t *testing.T, run store.JobRun) {
+		assert.True(t, run.DryRun)
+		assert.False(t, run.Failed())
+		assert.Equal(t, "ticket-id", run.TicketID)
+		require.Len(t, run.Steps, 2)
+		assert.Equal(t, lib.Vars{"title": "title"}, run.Steps[0].Vars)
+		assert.Empty(t, run.Steps[0].TaskID)
+		assert.Equal(t, &store.Task{ID: dryRunTaskID}, run.Steps[0].Response)
+		assert.Equal(t, lib.Vars{"id": dryRunTaskID}, run.Steps[1].Vars, "synthetic response must be used")
+		assert.Equal(t, dryRunTaskID, run.Steps[1].TaskID)
+	}
+
+	t.Run("configured in job", func(t *testing.T) {
+		job := job
+		job.DryRun = true
+		check(t, svc.runJob(context.Background(), job, upd, false))
+	})
+
+	t.Run("requested", func(t *testing.T) {
+		check(t, svc.runJob(context.Background(), job, upd, true))
+	})
+
+	t.Run("configured for actor", func(t *testing.T) {
+		svc.DryRun = true
+		defer func() { svc.DryRun = false }()
+		check(t, svc.runJob(context.Background(), job, upd, false))
 	})
 }
 
@@ -222,6 +560,12 @@ func TestActor_Listen(t *testing.T) {
 			assert.Equal(t, tracker.SubscribeReq{
 				Vars:       lib.Vars{"test": varsEvaluated},
 				WebhookURL: "https://localhost/subscription-1",
+				Subscription: store.Subscription{
+					ID:          "subscription-1",
+					TrackerName: "tracker-1",
+					TriggerName: "trigger-1",
+					BaseURL:     "https://localhost",
+				},
 			}, req)
 			return tracker.SubscribeResp{TrackerRef: "tracker-ref-1"}, nil
 		}
@@ -229,6 +573,12 @@ func TestActor_Listen(t *testing.T) {
 			assert.Equal(t, tracker.SubscribeReq{
 				Vars:       lib.Vars{"test1": varsEvaluated},
 				WebhookURL: "https://localhost/subscription-2",
+				Subscription: store.Subscription{
+					ID:          "subscription-2",
+					TrackerName: "tracker-2",
+					TriggerName: "trigger-2",
+					BaseURL:     "https://localhost",
+				},
 			}, req)
 			return tracker.SubscribeResp{TrackerRef: "tracker-ref-2"}, nil
 		}
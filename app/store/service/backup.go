@@ -0,0 +1,204 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+)
+
+// backupVersion is the version of the backup format, it must be
+// increased on each incompatible change of the records.
+const backupVersion = 1
+
+// exportPageSize is the amount of tickets, read from the storage at once.
+const exportPageSize = 100
+
+// Backup exports and imports the state of dastracker in form of JSON Lines,
+// independently of the storage engine. The first line of the dump is a header
+// with the version of the format, each next line is either a ticket or a
+// subscription. References of tickets are restored from their variations.
+type Backup struct {
+	Tickets       engine.Tickets
+	Subscriptions engine.Subscriptions
+	Log           logx.Logger
+}
+
+// BackupStats describes the amount of exported or imported entries.
+type BackupStats struct {
+	Tickets       int
+	Subscriptions int
+}
+
+type backupRecord struct {
+	Kind         string              `json:"kind"`
+	Version      int                 `json:"version,omitempty"`
+	CreatedAt    *time.Time          `json:"created_at,omitempty"`
+	Ticket       *store.Ticket       `json:"ticket,omitempty"`
+	Subscription *store.Subscription `json:"subscription,omitempty"`
+}
+
+const (
+	recordKindHeader       = "header"
+	recordKindTicket       = "ticket"
+	recordKindSubscription = "subscription"
+)
+
+// Export writes all tickets and subscriptions to w.
+// The dump is not taken atomically, so entries, changed during the export,
+// might be present either in the previous or in the new state. Tickets are
+// paginated by their IDs, thus each of them is written at most once.
+func (b *Backup) Export(ctx context.Context, w io.Writer) (BackupStats, error) {
+	var stats BackupStats
+	enc := json.NewEncoder(w)
+
+	now := time.Now().UTC()
+	if err := enc.Encode(backupRecord{Kind: recordKindHeader, Version: backupVersion, CreatedAt: &now}); err != nil {
+		return stats, fmt.Errorf("write header: %w", err)
+	}
+
+	for afterID := ""; ; {
+		tickets, err := b.Tickets.List(ctx, engine.ListRequest{AfterID: afterID, Limit: exportPageSize})
+		if err != nil {
+			return stats, fmt.Errorf("list tickets after %q: %w", afterID, err)
+		}
+
+		for idx := range tickets {
+			if err = enc.Encode(backupRecord{Kind: recordKindTicket, Ticket: &tickets[idx]}); err != nil {
+				return stats, fmt.Errorf("write ticket %s: %w", tickets[idx].ID, err)
+			}
+			stats.Tickets++
+		}
+
+		if len(tickets) < exportPageSize {
+			break
+		}
+		afterID = tickets[len(tickets)-1].ID
+	}
+
+	subs, err := b.Subscriptions.List(ctx, "")
+	if err != nil {
+		return stats, fmt.Errorf("list subscriptions: %w", err)
+	}
+
+	for idx := range subs {
+		if err = enc.Encode(backupRecord{Kind: recordKindSubscription, Subscription: &subs[idx]}); err != nil {
+			return stats, fmt.Errorf("write subscription %s: %w", subs[idx].ID, err)
+		}
+		stats.Subscriptions++
+	}
+
+	return stats, nil
+}
+
+// Import reads the dump, made by Export, from r and puts its entries to
+// the storages. Existing entries with the same IDs are overwritten.
+func (b *Backup) Import(ctx context.Context, r io.Reader) (BackupStats, error) {
+	var stats BackupStats
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	var header backupRecord
+	if err := dec.Decode(&header); err != nil {
+		return stats, fmt.Errorf("read header: %w", err)
+	}
+
+	if header.Kind != recordKindHeader {
+		return stats, fmt.Errorf("dump must start with a header, got %q record", header.Kind)
+	}
+
+	if header.Version > backupVersion {
+		return stats, fmt.Errorf("dump version %d is newer than the latest supported %d", header.Version, backupVersion)
+	}
+
+	for line := 2; ; line++ {
+		var rec backupRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			return stats, nil
+		} else if err != nil {
+			return stats, fmt.Errorf("read record at line %d: %w", line, err)
+		}
+
+		switch {
+		case rec.Kind == recordKindTicket && rec.Ticket != nil:
+			if err := b.Tickets.Update(ctx, *rec.Ticket); err != nil {
+				return stats, fmt.Errorf("put ticket %s: %w", rec.Ticket.ID, err)
+			}
+			stats.Tickets++
+		case rec.Kind == recordKindSubscription && rec.Subscription != nil:
+			if err := b.Subscriptions.Update(ctx, *rec.Subscription); err != nil {
+				return stats, fmt.Errorf("put subscription %s: %w", rec.Subscription.ID, err)
+			}
+			stats.Subscriptions++
+		default:
+			return stats, fmt.Errorf("invalid %q record at line %d", rec.Kind, line)
+		}
+	}
+}
+
+// Schedule makes a backup to the given directory each interval and each time
+// a signal is received from trigger, until the context is canceled. Periodic
+// backups are disabled, if the interval is not positive.
+// Always returns non-nil error. Blocking call.
+func (b *Backup) Schedule(ctx context.Context, dir string, interval time.Duration, trigger <-chan os.Signal) error {
+	var tick <-chan time.Time // nil channel never fires
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick:
+		case <-trigger:
+		}
+
+		fileName, err := b.backupToDir(ctx, dir)
+		if err != nil {
+			b.Log.Printf("[WARN] failed to make hot backup: %v", err)
+			continue
+		}
+
+		b.Log.Printf("[INFO] hot backup is written to %s", fileName)
+	}
+}
+
+func (b *Backup) backupToDir(ctx context.Context, dir string) (string, error) {
+	fileName := filepath.Join(dir, fmt.Sprintf("dastracker-%s.jsonl", time.Now().UTC().Format("20060102T150405Z")))
+
+	// write to the temporary file first, so that the broken
+	// backup will never be mistaken for a complete one
+	tmpName := fileName + ".tmp"
+
+	f, err := os.OpenFile(tmpName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600) //nolint:gosec // path is set by operator
+	if err != nil {
+		return "", fmt.Errorf("create backup file: %w", err)
+	}
+
+	if _, err = b.Export(ctx, f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmpName)
+		return "", fmt.Errorf("export: %w", err)
+	}
+
+	if err = f.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return "", fmt.Errorf("close backup file: %w", err)
+	}
+
+	if err = os.Rename(tmpName, fileName); err != nil {
+		return "", fmt.Errorf("rename backup file: %w", err)
+	}
+
+	return fileName, nil
+}
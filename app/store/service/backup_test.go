@@ -0,0 +1,178 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/app/store/engine/memory"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackup_ExportImport(t *testing.T) {
+	src := &Backup{Tickets: memory.NewTickets(), Subscriptions: memory.NewSubscriptions(), Log: logx.Nop()}
+
+	var tickets []store.Ticket
+	for i := 0; i < exportPageSize+5; i++ {
+		tkt := store.Ticket{ID: fmt.Sprintf("%03d", i), Variations: store.Variations{
+			"tracker": {ID: fmt.Sprintf("task-%d", i), Content: store.Content{Title: "title, with comma"}},
+		}}
+		require.NoError(t, src.Tickets.Update(context.Background(), tkt))
+		tickets = append(tickets, tkt)
+	}
+
+	sub := store.Subscription{ID: "sub-id", TrackerRef: "ref", TrackerName: "tracker", TriggerName: "trigger"}
+	require.NoError(t, src.Subscriptions.Update(context.Background(), sub))
+
+	buf := &bytes.Buffer{}
+	stats, err := src.Export(context.Background(), buf)
+	require.NoError(t, err)
+	assert.Equal(t, BackupStats{Tickets: len(tickets), Subscriptions: 1}, stats)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, len(tickets)+2)
+
+	var header backupRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &header))
+	assert.Equal(t, recordKindHeader, header.Kind)
+	assert.Equal(t, backupVersion, header.Version)
+
+	dst := &Backup{Tickets: memory.NewTickets(), Subscriptions: memory.NewSubscriptions(), Log: logx.Nop()}
+	stats, err = dst.Import(context.Background(), buf)
+	require.NoError(t, err)
+	assert.Equal(t, BackupStats{Tickets: len(tickets), Subscriptions: 1}, stats)
+
+	res, err := dst.Tickets.List(context.Background(), engine.ListRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, tickets, res)
+
+	// refs must be restored as well
+	tkt, err := dst.Tickets.Get(context.Background(), engine.GetRequest{
+		Locator: store.Locator{Tracker: "tracker", ID: "task-7"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, tickets[7], tkt)
+
+	subs, err := dst.Subscriptions.List(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, []store.Subscription{sub}, subs)
+}
+
+func TestBackup_Export_ConcurrentDelete(t *testing.T) {
+	tickets := memory.NewTickets()
+	for i := 0; i < exportPageSize+5; i++ {
+		require.NoError(t, tickets.Update(context.Background(), store.Ticket{ID: fmt.Sprintf("%03d", i)}))
+	}
+
+	// the first ticket is removed right after the first page is listed
+	svc := &Backup{
+		Tickets: &listHook{Tickets: tickets, after: func() {
+			_ = tickets.Delete(context.Background(), "000")
+		}},
+		Subscriptions: memory.NewSubscriptions(),
+		Log:           logx.Nop(),
+	}
+
+	buf := &bytes.Buffer{}
+	stats, err := svc.Export(context.Background(), buf)
+	require.NoError(t, err)
+	assert.Equal(t, BackupStats{Tickets: exportPageSize + 5}, stats)
+
+	ids := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n")[1:] {
+		var rec backupRecord
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		assert.False(t, ids[rec.Ticket.ID], "ticket %s is duplicated", rec.Ticket.ID)
+		ids[rec.Ticket.ID] = true
+	}
+	assert.Len(t, ids, exportPageSize+5)
+}
+
+// listHook calls after func after each List call.
+type listHook struct {
+	engine.Tickets
+	after func()
+}
+
+func (l *listHook) List(ctx context.Context, req engine.ListRequest) ([]store.Ticket, error) {
+	defer l.after()
+	return l.Tickets.List(ctx, req)
+}
+
+func TestBackup_Import_Invalid(t *testing.T) {
+	tbl := []struct {
+		name string
+		dump string
+	}{
+		{name: "empty", dump: ""},
+		{name: "no header", dump: `{"kind":"ticket","ticket":{"id":"id"}}`},
+		{name: "newer version", dump: `{"kind":"header","version":100}`},
+		{name: "unknown record", dump: `{"kind":"header","version":1}` + "\n" + `{"kind":"blah"}`},
+		{name: "broken record", dump: `{"kind":"header","version":1}` + "\n" + `{"kind":`},
+	}
+
+	for _, tt := range tbl {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &Backup{Tickets: memory.NewTickets(), Subscriptions: memory.NewSubscriptions(), Log: logx.Nop()}
+			_, err := svc.Import(context.Background(), strings.NewReader(tt.dump))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestBackup_Schedule(t *testing.T) {
+	// periodic backups are disabled with zero interval, only the trigger works
+	for _, interval := range []time.Duration{time.Hour, 0} {
+		t.Run(interval.String(), func(t *testing.T) {
+			testBackupSchedule(t, interval)
+		})
+	}
+}
+
+func testBackupSchedule(t *testing.T, interval time.Duration) {
+	dir, err := ioutil.TempDir("", "test_dastracker")
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, os.RemoveAll(dir)) })
+
+	svc := &Backup{Tickets: memory.NewTickets(), Subscriptions: memory.NewSubscriptions(), Log: logx.Nop()}
+	require.NoError(t, svc.Tickets.Update(context.Background(), store.Ticket{ID: "id"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	trigger := make(chan os.Signal, 1)
+	errCh := make(chan error, 1)
+	go func() { errCh <- svc.Schedule(ctx, dir, interval, trigger) }()
+
+	trigger <- syscall.SIGUSR1
+
+	var files []string
+	require.Eventually(t, func() bool {
+		files, err = filepath.Glob(filepath.Join(dir, "dastracker-*.jsonl"))
+		require.NoError(t, err)
+		return len(files) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-errCh, context.Canceled)
+
+	f, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+
+	dst := &Backup{Tickets: memory.NewTickets(), Subscriptions: memory.NewSubscriptions(), Log: logx.Nop()}
+	stats, err := dst.Import(context.Background(), f)
+	require.NoError(t, err)
+	assert.Equal(t, BackupStats{Tickets: 1}, stats)
+}
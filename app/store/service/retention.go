@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+)
+
+// RunsRetention periodically removes outdated job runs from the history.
+type RunsRetention struct {
+	Runs     engine.Runs
+	Log      logx.Logger
+	MaxAge   time.Duration // zero means runs are not removed by age
+	MaxRuns  int           // zero means runs are not removed by count
+	Interval time.Duration
+}
+
+// Listen runs the cleanup each interval. Always returns non-nil error.
+// Blocking call.
+func (r *RunsRetention) Listen(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Cleanup(ctx); err != nil {
+			r.Log.Printf("[WARN] failed to clean up runs history: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cleanup removes the runs, that exceed the retention limits,
+// and returns the number of removed runs.
+func (r *RunsRetention) Cleanup(ctx context.Context) (int, error) {
+	if r.MaxAge == 0 && r.MaxRuns == 0 {
+		return 0, nil
+	}
+
+	req := engine.CleanupRunsRequest{Keep: r.MaxRuns}
+	if r.MaxAge > 0 {
+		req.Before = time.Now().Add(-r.MaxAge)
+	}
+
+	removed, err := r.Runs.Cleanup(ctx, req)
+	if err != nil {
+		return 0, fmt.Errorf("clean up runs: %w", err)
+	}
+
+	if removed > 0 {
+		r.Log.Printf("[INFO] removed %d outdated runs", removed)
+	}
+
+	return removed, nil
+}
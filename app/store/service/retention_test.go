@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/store/engine"
+	"github.com/cappuccinotm/dastracker/app/store/engine/memory"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunsRetention_Cleanup(t *testing.T) {
+	runs := memory.NewRuns()
+	for _, startedAt := range []time.Time{
+		time.Now().Add(-3 * time.Hour),
+		time.Now().Add(-2 * time.Hour),
+		time.Now().Add(-time.Minute),
+		time.Now(),
+	} {
+		_, err := runs.Create(context.Background(), store.JobRun{StartedAt: startedAt})
+		require.NoError(t, err)
+	}
+
+	svc := &RunsRetention{Runs: runs, Log: logx.Nop()}
+
+	removed, err := svc.Cleanup(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, removed, "nothing must be removed without limits")
+
+	svc.MaxAge = 150 * time.Minute
+	removed, err = svc.Cleanup(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	svc.MaxRuns = 2
+	removed, err = svc.Cleanup(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	left, err := runs.List(context.Background(), engine.ListRunsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, left, 2)
+}
@@ -4,7 +4,7 @@ import "github.com/cappuccinotm/dastracker/lib"
 
 // Tracker describes a connection parameters to the certain tracker.
 type Tracker struct {
-	Name   string   `yaml:"name"`
-	Driver string   `yaml:"driver"`
-	With   lib.Vars `yaml:"with"`
+	Name   string   `yaml:"name" json:"name"`
+	Driver string   `yaml:"driver" json:"driver"`
+	With   lib.Vars `yaml:"with" json:"-"` // might contain credentials
 }
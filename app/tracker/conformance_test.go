@@ -0,0 +1,112 @@
+package tracker_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/tracker"
+	"github.com/cappuccinotm/dastracker/app/tracker/trackertest"
+	"github.com/cappuccinotm/dastracker/lib"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGithub_Conformance(t *testing.T) {
+	trackertest.Run(t, trackertest.Suite{
+		New: func(t *testing.T) tracker.Interface {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && r.URL.Path == "/repos/owner/name/hooks":
+					w.WriteHeader(http.StatusCreated)
+					_, _ = w.Write([]byte(`{"id": 123}`))
+				case r.Method == http.MethodDelete && r.URL.Path == "/repos/owner/name/hooks/123":
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			t.Cleanup(ts.Close)
+
+			svc, err := tracker.NewGithub(tracker.GithubParams{
+				Name:    "gh",
+				Vars:    lib.Vars{"owner": "owner", "name": "name"},
+				Client:  ts.Client(),
+				Logger:  logx.Nop(),
+				BaseURL: ts.URL,
+			})
+			require.NoError(t, err)
+			return svc
+		},
+		Subscribe: tracker.SubscribeReq{
+			WebhookURL: "http://localhost/webhook-id",
+			Vars:       lib.Vars{"events": "issues"},
+		},
+		Webhook: func(t *testing.T) *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/webhook-id", bytes.NewBufferString(`{
+				"action": "edited",
+				"issue": {"number": 12345, "title": "title", "body": "body", "url": "update-url"}
+			}`))
+		},
+		Update: store.Update{
+			URL:          "update-url",
+			ReceivedFrom: store.Locator{Tracker: "gh", ID: "12345"},
+			Content:      store.Content{Body: "body", Title: "title"},
+		},
+	})
+}
+
+func TestJSONRPC_Conformance(t *testing.T) {
+	trackertest.Run(t, trackertest.Suite{
+		New: func(t *testing.T) tracker.Interface {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			go func() { _ = (&lib.Plugin{Name: "test"}).Serve(ctx, l, &fakePlugin{}) }()
+
+			svc, err := tracker.NewJSONRPC("rpc", logx.Nop(), lib.Vars{"address": l.Addr().String()})
+			require.NoError(t, err)
+			return svc
+		},
+		Subscribe: tracker.SubscribeReq{
+			WebhookURL: "http://localhost/webhook-id",
+			Vars:       lib.Vars{"project": "project"},
+		},
+		Webhook: func(t *testing.T) *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/webhook-id", bytes.NewBufferString(`{
+				"id": "task-id", "url": "update-url", "title": "title", "body": "body",
+				"fields": {"field": "value"}
+			}`))
+		},
+		Update: store.Update{
+			URL:          "update-url",
+			ReceivedFrom: store.Locator{Tracker: "rpc", ID: "task-id"},
+			Content: store.Content{
+				Body:   "body",
+				Title:  "title",
+				Fields: store.TicketFields{"field": "value"},
+			},
+		},
+	})
+}
+
+type fakePlugin struct{}
+
+func (fakePlugin) Subscribe(req lib.SubscribeReq, resp *lib.SubscribeResp) error {
+	resp.TrackerRef = "ref-" + req.Vars.Get("project")
+	return nil
+}
+
+func (fakePlugin) Unsubscribe(req lib.UnsubscribeReq, _ *struct{}) error {
+	if req.TrackerRef != "ref-project" {
+		return fmt.Errorf("unknown tracker ref %q", req.TrackerRef)
+	}
+	return nil
+}
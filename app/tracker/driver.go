@@ -0,0 +1,25 @@
+package tracker
+
+import "sort"
+
+// Driver describes the requirements of the tracker driver to its configuration.
+type Driver struct {
+	Vars    []string // variables, that must be set in the configuration of the tracker
+	AnyOf   []string // variables, at least one of which must be set
+	Methods []string // supported methods, empty if they are defined by the plugin
+}
+
+// Drivers lists the supported drivers by their names.
+var Drivers = map[string]Driver{
+	"github": {Vars: []string{"owner", "name", "user", "access_token"}, Methods: ghMethods()},
+	"rpc":    {AnyOf: []string{"address", "command"}},
+}
+
+func ghMethods() []string {
+	res := make([]string, 0, len(ghSupportedActions))
+	for method := range ghSupportedActions {
+		res = append(res, method)
+	}
+	sort.Strings(res)
+	return res
+}
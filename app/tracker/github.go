@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/cappuccinotm/dastracker/app/errs"
@@ -29,15 +30,18 @@ type Github struct {
 	l       logx.Logger
 	repo    struct{ Owner, Name string }
 	cl      *http.Client
+
+	mu      sync.RWMutex
 	handler Handler
 }
 
 // GithubParams describes parameters to initialize Github.
 type GithubParams struct {
-	Name   string
-	Vars   lib.Vars
-	Client *http.Client
-	Logger logx.Logger
+	Name    string
+	Vars    lib.Vars
+	Client  *http.Client
+	Logger  logx.Logger
+	BaseURL string // api.github.com by default
 }
 
 // NewGithub makes new instance of Github tracker.
@@ -49,6 +53,10 @@ func NewGithub(params GithubParams) (*Github, error) {
 		cl:      params.Client,
 	}
 
+	if params.BaseURL != "" {
+		svc.baseURL = params.BaseURL
+	}
+
 	svc.repo.Owner = params.Vars.Get("owner")
 	svc.repo.Name = params.Vars.Get("name")
 
@@ -254,16 +262,22 @@ func (g *Github) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 
 	g.l.Printf("[DEBUG] received webhook update: %+v", upd)
 
-	if g.handler == nil {
+	g.mu.RLock()
+	h := g.handler
+	g.mu.RUnlock()
+
+	if h == nil {
 		g.l.Printf("[WARN] no handler is set, but update received")
 		return
 	}
-	g.handler.Handle(ctx, upd)
+	h.Handle(ctx, upd)
 }
 
 // Listen does nothing and waits until the context is dead.
 func (g *Github) Listen(ctx context.Context, h Handler) error {
+	g.mu.Lock()
 	g.handler = h
+	g.mu.Unlock()
 	<-ctx.Done()
 	return ctx.Err()
 }
@@ -0,0 +1,175 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/cappuccinotm/dastracker/lib"
+)
+
+// httpClient calls the plugin with JSON-RPC 2.0 requests over HTTP.
+// It implements rpcx.Client.
+type httpClient struct {
+	url   string
+	token string
+	cl    *http.Client
+	id    uint64
+}
+
+// httpClientParams describes parameters of the HTTP client to the plugin.
+type httpClientParams struct {
+	// Address of the plugin, either an URL, "host:port"
+	// or "unix:///path/to/socket"
+	Address string
+	// Resolve returns the address to dial, if set, the host of the address
+	// is ignored, used for the plugins, launched by the tracker
+	Resolve func(ctx context.Context) (network, addr string, err error)
+	TLS     *tls.Config // connections are not encrypted if nil, unless the address is https URL
+	Token   string      // sent in "Authorization: Bearer" header, if set
+	Size    int         // maximal number of idle connections, 4 by default
+}
+
+// newHTTPClient makes new instance of httpClient.
+func newHTTPClient(params httpClientParams) (*httpClient, error) {
+	u, network, addr, err := pluginURL(params.Address, params.TLS != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsCfg := params.TLS
+	if tlsCfg == nil && u.Scheme == "https" {
+		tlsCfg = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	resolve := params.Resolve
+	if resolve == nil {
+		resolve = func(context.Context) (string, string, error) { return network, addr, nil }
+	}
+
+	if params.Size <= 0 {
+		params.Size = 4
+	}
+
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		network, addr, err := resolve(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return dialPlugin(ctx, network, addr, tlsCfg)
+	}
+
+	transport := &http.Transport{MaxIdleConnsPerHost: params.Size}
+	if tlsCfg != nil {
+		transport.DialTLSContext = dial
+	} else {
+		transport.DialContext = dial
+	}
+
+	return &httpClient{url: u.String(), token: params.Token, cl: &http.Client{Transport: transport}}, nil
+}
+
+// pluginURL makes the URL for the requests to the plugin and
+// returns the network and the address to dial.
+func pluginURL(address string, secure bool) (u *url.URL, network, addr string, err error) {
+	if strings.HasPrefix(address, "http://") || strings.HasPrefix(address, "https://") {
+		if u, err = url.Parse(address); err != nil {
+			return nil, "", "", fmt.Errorf("parse address: %w", err)
+		}
+		addr = u.Host
+		if u.Port() == "" {
+			port := "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+			addr = net.JoinHostPort(u.Hostname(), port)
+		}
+		return u, "tcp", addr, nil
+	}
+
+	u = &url.URL{Scheme: "http", Host: "plugin", Path: "/"}
+	if secure {
+		u.Scheme = "https"
+	}
+
+	network, addr = lib.SplitAddress(address)
+	if network == "tcp" && addr != "" {
+		u.Host = addr
+	}
+	return u, network, addr, nil
+}
+
+// Call sends the JSON-RPC 2.0 request to the plugin and decodes the result
+// into reply. Errors, returned by the plugin, are of type *lib.RPCError.
+func (c *httpClient) Call(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	params, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("encode params: %w", err)
+	}
+
+	body, err := json.Marshal(lib.RPCRequest{
+		Version: "2.0",
+		Method:  serviceMethod,
+		Params:  params,
+		ID:      json.RawMessage(strconv.FormatUint(atomic.AddUint64(&c.id, 1), 10)),
+	})
+	if err != nil {
+		return fmt.Errorf("encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("make request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.cl.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var rpcResp lib.RPCResponse
+	if err = json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected response status %d", resp.StatusCode)
+		}
+		return fmt.Errorf("decode response: %w", err)
+	}
+	// drain the body to reuse the connection
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	if len(rpcResp.Result) == 0 || reply == nil {
+		return nil
+	}
+	if err = json.Unmarshal(rpcResp.Result, reply); err != nil {
+		return fmt.Errorf("decode result: %w", err)
+	}
+	return nil
+}
+
+// Close closes the idle connections to the plugin.
+func (c *httpClient) Close() error {
+	c.cl.CloseIdleConnections()
+	return nil
+}
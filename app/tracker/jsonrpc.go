@@ -2,46 +2,301 @@ package tracker
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
+	netrpc "net/rpc"
+	"net/rpc/jsonrpc"
+	"strconv"
+	"strings"
+	"sync"
 
 	"encoding/json"
 	"time"
 
+	"github.com/cappuccinotm/dastracker/app/errs"
 	"github.com/cappuccinotm/dastracker/app/store"
 	"github.com/cappuccinotm/dastracker/lib"
 	"github.com/cappuccinotm/dastracker/pkg/logx"
 	"github.com/cappuccinotm/dastracker/pkg/rpcx"
-	"github.com/go-pkgz/repeater/strategy"
+	"github.com/google/uuid"
 )
 
 // JSONRPC implements Interface in order to allow external services,
 // described in the configuration file, extend the functionality of
 // the dastracker.
 type JSONRPC struct {
-	cl      rpcx.Client
-	name    string
-	l       logx.Logger
+	cl   rpcx.Client
+	name string
+	l    logx.Logger
+	proc *Process // nil if the plugin is not launched by dastracker
+
+	mu      sync.RWMutex
 	handler Handler
+	subs    map[string]SubscribeReq // requests by IDs of subscriptions, to deliver pushed updates and to restore them
+
+	desc *lib.Description // nil if the plugin doesn't describe itself
 }
 
-// NewJSONRPC makes new instance of JSONRPC.
+// NewJSONRPC makes new instance of JSONRPC. If the "command" variable is
+// set, the plugin is launched by the tracker, otherwise the plugin is
+// expected to listen on the "address".
 func NewJSONRPC(name string, l logx.Logger, vars lib.Vars) (*JSONRPC, error) {
-	dialer, err := rpcx.NewRedialer(
-		rpcx.JSONRPC(),
-		&strategy.FixedDelay{Repeats: 3, Delay: time.Second},
-		"tcp",
-		vars.Get("address"),
-	)
+	svc := &JSONRPC{name: name, l: l}
+
+	dialer, err := pluginDialer(vars)
 	if err != nil {
-		return nil, fmt.Errorf("initialize new dialer for %s tracker: %w", name, err)
+		return nil, fmt.Errorf("prepare dialer for %s tracker: %w", name, err)
+	}
+
+	if cmd := commandArgs(vars); len(cmd) > 0 {
+		proc, err := StartProcess(ProcessParams{
+			Command: cmd,
+			Network: vars.Get("network"),
+			Dialer:  dialer,
+			Logger:  l,
+			// the restarted plugin doesn't know the subscriptions of the crashed one
+			OnRestart: svc.resubscribe,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("launch plugin for %s tracker: %w", name, err)
+		}
+		svc.proc, dialer = proc, proc
 	}
 
-	svc := &JSONRPC{cl: dialer, name: name, l: l}
+	size := 0
+	if vars.Get("connections") != "" {
+		if size, err = strconv.Atoi(vars.Get("connections")); err != nil {
+			svc.stopProcess()
+			return nil, fmt.Errorf("parse number of connections for %s tracker: %w", name, err)
+		}
+	}
+
+	if svc.cl, err = pluginClient(vars, dialer, svc.proc, size, l); err != nil {
+		svc.stopProcess()
+		return nil, fmt.Errorf("initialize client for %s tracker: %w", name, err)
+	}
+
+	desc, err := svc.describe(context.Background())
+	switch {
+	case errors.As(err, new(errs.ErrActionNotSupported)):
+		l.Printf("[WARN] plugin doesn't describe itself, its methods and variables won't be checked")
+	case err != nil:
+		_ = svc.Close()
+		return nil, fmt.Errorf("describe plugin %s: %w", name, err)
+	default:
+		l.Printf("[INFO] connected to plugin %s %s, methods: %s",
+			desc.Name, desc.Version, strings.Join(desc.MethodNames(), ", "))
+		svc.desc = &desc
+	}
 
 	return svc, nil
 }
 
+// Close closes the connection to the plugin and
+// stops it, if it is launched by the tracker.
+func (rpc *JSONRPC) Close() error {
+	if pool, ok := rpc.cl.(*rpcx.Pool); ok {
+		rpc.l.Printf("[DEBUG] connection pool stats: %+v", pool.Stats())
+	}
+	err := rpc.cl.Close()
+	rpc.stopProcess()
+	if err != nil {
+		return fmt.Errorf("close connection: %w", err)
+	}
+	return nil
+}
+
+func (rpc *JSONRPC) stopProcess() {
+	if rpc.proc == nil {
+		return
+	}
+	if err := rpc.proc.Close(); err != nil {
+		rpc.l.Printf("[WARN] failed to stop plugin: %v", err)
+	}
+}
+
+// pluginClient makes the client to call the plugin over the transport,
+// set in the "transport" variable: either JSON-RPC 1.0 over the pool of
+// raw connections, or JSON-RPC 2.0 over HTTP.
+func pluginClient(vars lib.Vars, dialer rpcx.Dialer, proc *Process, size int, l logx.Logger) (rpcx.Client, error) {
+	switch lib.Transport(vars.Get("transport")) {
+	case "", lib.TransportRPC:
+		network, addr := lib.SplitAddress(vars.Get("address"))
+		return rpcx.NewPool(rpcx.PoolParams{
+			Dialer:         dialer,
+			Network:        network,
+			Addr:           addr,
+			Size:           size,
+			Logger:         l,
+			HealthCheck:    "plugin.Describe",
+			HealthInterval: healthInterval,
+		})
+	case lib.TransportHTTP:
+		tlsCfg, err := pluginTLS(vars)
+		if err != nil {
+			return nil, err
+		}
+		params := httpClientParams{Address: vars.Get("address"), TLS: tlsCfg, Token: vars.Get("token"), Size: size}
+		if proc != nil {
+			params.Resolve = proc.Addr
+		}
+		return newHTTPClient(params)
+	default:
+		return nil, fmt.Errorf("unsupported transport %q", vars.Get("transport"))
+	}
+}
+
+// pluginDialer makes the dialer to the plugin, which encrypts the connection
+// with TLS and authenticates it with the token, if they are set in variables.
+func pluginDialer(vars lib.Vars) (rpcx.Dialer, error) {
+	tlsCfg, err := pluginTLS(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	token := vars.Get("token")
+
+	return rpcx.DialerFunc(func(ctx context.Context, network, addr string) (rpcx.RPCClient, error) {
+		conn, err := dialPlugin(ctx, network, addr, tlsCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		cl := jsonrpc.NewClient(conn)
+		if token == "" {
+			return cl, nil
+		}
+
+		call := cl.Go("plugin.Authenticate", lib.AuthReq{Token: token}, &struct{}{}, make(chan *netrpc.Call, 1))
+		select {
+		case <-call.Done:
+			err = call.Error
+		case <-time.After(dialTimeout):
+			err = errors.New("timeout")
+		case <-ctx.Done():
+			_ = cl.Close()
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			_ = cl.Close()
+			return nil, rpcx.Permanent(fmt.Errorf("authenticate: %w", err))
+		}
+
+		return cl, nil
+	}), nil
+}
+
+// pluginTLS makes the TLS configuration to connect to the plugin,
+// nil if the connection must not be encrypted.
+func pluginTLS(vars lib.Vars) (*tls.Config, error) {
+	if vars.Get("tls") != "true" && vars.Get("tls_ca") == "" && vars.Get("tls_cert") == "" {
+		return nil, nil
+	}
+	res, err := lib.ClientTLS(vars.Get("tls_cert"), vars.Get("tls_key"), vars.Get("tls_ca"), vars.Get("tls_server_name"))
+	if err != nil {
+		return nil, fmt.Errorf("prepare tls config: %w", err)
+	}
+	return res, nil
+}
+
+// dialPlugin connects to the plugin and makes the TLS handshake, if the
+// TLS configuration is set. Failures of the handshake mean misconfiguration,
+// thus they are marked as permanent.
+func dialPlugin(ctx context.Context, network, addr string, tlsCfg *tls.Config) (net.Conn, error) {
+	conn, err := (&net.Dialer{Timeout: dialTimeout}).DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg == nil {
+		return conn, nil
+	}
+
+	cfg := tlsCfg.Clone()
+	if cfg.ServerName == "" && network == "tcp" {
+		cfg.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+	tlsConn := tls.Client(conn, cfg)
+	_ = tlsConn.SetDeadline(time.Now().Add(dialTimeout))
+	if err = tlsConn.Handshake(); err != nil {
+		_ = conn.Close()
+		return nil, rpcx.Permanent(fmt.Errorf("tls handshake: %w", err))
+	}
+	_ = tlsConn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
+// dialTimeout limits the time to connect to the plugin and authenticate.
+const dialTimeout = 10 * time.Second
+
+// commandArgs returns the command to launch the plugin, the command
+// might be set either as a list or as a string, split by spaces.
+func commandArgs(vars lib.Vars) []string {
+	if _, ok := vars["command"].([]interface{}); ok {
+		return vars.List("command")
+	}
+	return strings.Fields(vars.Get("command"))
+}
+
+// Description returns the description of the plugin, received at startup,
+// false if the plugin doesn't describe itself.
+func (rpc *JSONRPC) Description() (lib.Description, bool) {
+	if rpc.desc == nil {
+		return lib.Description{}, false
+	}
+	return *rpc.desc, true
+}
+
+// describe requests the description of the plugin's capabilities.
+func (rpc *JSONRPC) describe(ctx context.Context) (lib.Description, error) {
+	// the plugin might accept the connection, but never answer
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+
+	var resp lib.Description
+	err := rpc.cl.Call(ctx, "plugin.Describe", struct{}{}, &resp)
+	var srvErr netrpc.ServerError
+	var rpcErr *lib.RPCError
+	if errors.As(err, &srvErr) && strings.HasPrefix(string(srvErr), "rpc: can't find") ||
+		errors.As(err, &rpcErr) && rpcErr.Code == lib.CodeMethodNotFound {
+		return lib.Description{}, errs.ErrActionNotSupported("Describe")
+	}
+	if err != nil {
+		return lib.Description{}, fmt.Errorf("call remote Describe: %w", err)
+	}
+
+	if resp.ProtocolVersion > lib.ProtocolVersion {
+		return lib.Description{}, fmt.Errorf("plugin requires protocol version %d, supported up to %d",
+			resp.ProtocolVersion, lib.ProtocolVersion)
+	}
+
+	return resp, nil
+}
+
+// typedVarsProtocol is the first version of the protocol, in which
+// plugins receive typed values of variables.
+const typedVarsProtocol = 1
+
+// wireVars prepares the variables to be sent to the plugin. Plugins, built
+// before typed values of variables, decode them to map[string]string, so
+// unless the plugin reports the protocol version with typed values, all
+// values are sent as strings, the same as Vars.Get returns them.
+func (rpc *JSONRPC) wireVars(vars lib.Vars) lib.Vars {
+	if vars == nil || rpc.desc != nil && rpc.desc.ProtocolVersion >= typedVarsProtocol {
+		return vars
+	}
+
+	res := make(lib.Vars, len(vars))
+	for key := range vars {
+		res[key] = vars.Get(key)
+	}
+	return res
+}
+
 // Name returns the name of the JSONRPC plugin tracker.
 func (rpc *JSONRPC) Name() string { return rpc.name }
 
@@ -49,8 +304,24 @@ func (rpc *JSONRPC) Name() string { return rpc.name }
 func (rpc *JSONRPC) Call(ctx context.Context, req Request) (Response, error) {
 	var resp lib.Response
 
-	rpcReq := lib.Request{TaskID: req.TaskID, Vars: req.Vars}
-	if err := rpc.cl.Call(ctx, "plugin."+req.Method, rpcReq, &resp); err != nil {
+	rpcReq := lib.Request{
+		Call:   lib.NewCall(ctx, uuid.NewString()),
+		TaskID: req.TaskID,
+		Vars:   rpc.wireVars(req.Vars),
+		Update: lib.Update{
+			Tracker: req.Update.ReceivedFrom.Tracker,
+			Task:    libTask(req.Update.ReceivedFrom.ID, req.Update.URL, req.Update.Content),
+		},
+		Ticket:  lib.Ticket{ID: req.Ticket.ID, Variations: make(map[string]lib.Task, len(req.Ticket.Variations))},
+		Job:     req.JobName,
+		Trigger: req.TriggerName,
+		RunID:   req.RunID,
+	}
+	for trk, task := range req.Ticket.Variations {
+		rpcReq.Ticket.Variations[trk] = libTask(task.ID, "", task.Content)
+	}
+
+	if err := rpc.call(ctx, "plugin."+req.Method, rpcReq.Call, rpcReq, &resp); err != nil {
 		return Response{}, fmt.Errorf("call remote method %s: %w", req.Method, err)
 	}
 
@@ -60,29 +331,170 @@ func (rpc *JSONRPC) Call(ctx context.Context, req Request) (Response, error) {
 // Subscribe sends subscribe call to the remote JSONRPC server.
 func (rpc *JSONRPC) Subscribe(ctx context.Context, req SubscribeReq) (SubscribeResp, error) {
 	var resp lib.SubscribeResp
-	if err := rpc.cl.Call(ctx, "plugin.Subscribe", lib.SubscribeReq{
-		WebhookURL: req.WebhookURL,
-		Vars:       req.Vars,
-	}, &resp); err != nil {
+	rpcReq := lib.SubscribeReq{
+		Call:           lib.NewCall(ctx, uuid.NewString()),
+		SubscriptionID: req.Subscription.ID,
+		WebhookURL:     req.WebhookURL,
+		Vars:           rpc.wireVars(req.Vars),
+	}
+
+	// the plugin might push updates before it responds to the call,
+	// thus the subscription must be known in advance
+	id := req.Subscription.ID
+	if id != "" {
+		rpc.mu.Lock()
+		if rpc.subs == nil {
+			rpc.subs = map[string]SubscribeReq{}
+		}
+		rpc.subs[id] = req
+		rpc.mu.Unlock()
+	}
+
+	if err := rpc.call(ctx, "plugin.Subscribe", rpcReq.Call, rpcReq, &resp); err != nil {
+		if id != "" {
+			rpc.mu.Lock()
+			delete(rpc.subs, id)
+			rpc.mu.Unlock()
+		}
 		return SubscribeResp{}, fmt.Errorf("call remote Subscribe: %w", err)
 	}
 
+	if id != "" {
+		req.Subscription.TrackerRef = resp.TrackerRef
+		rpc.mu.Lock()
+		rpc.subs[id] = req
+		rpc.mu.Unlock()
+	}
+
 	return SubscribeResp{TrackerRef: resp.TrackerRef}, nil
 }
 
 // Unsubscribe sends unsubscribe call to the remote JSONRPC server.
 func (rpc *JSONRPC) Unsubscribe(ctx context.Context, req UnsubscribeReq) error {
-	if err := rpc.cl.Call(ctx, "plugin.Unsubscribe", lib.UnsubscribeReq{
-		TrackerRef: req.TrackerRef,
-	}, &struct{}{}); err != nil {
+	rpcReq := lib.UnsubscribeReq{Call: lib.NewCall(ctx, uuid.NewString()), TrackerRef: req.TrackerRef}
+	if err := rpc.call(ctx, "plugin.Unsubscribe", rpcReq.Call, rpcReq, &struct{}{}); err != nil {
 		return fmt.Errorf("call remote Unsubscribe: %w", err)
 	}
+
+	rpc.mu.Lock()
+	for id, sub := range rpc.subs {
+		if sub.Subscription.TrackerRef == req.TrackerRef {
+			delete(rpc.subs, id)
+		}
+	}
+	rpc.mu.Unlock()
 	return nil
 }
 
+// resubscribe repeats the subscriptions, made before the restart of the plugin.
+func (rpc *JSONRPC) resubscribe() {
+	rpc.mu.RLock()
+	reqs := make([]SubscribeReq, 0, len(rpc.subs))
+	for _, req := range rpc.subs {
+		reqs = append(reqs, req)
+	}
+	rpc.mu.RUnlock()
+
+	for _, req := range reqs {
+		rpc.mu.RLock()
+		_, ok := rpc.subs[req.Subscription.ID]
+		rpc.mu.RUnlock()
+		if !ok { // unsubscribed in the meantime
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), resubscribeTimeout)
+		ref := req.Subscription.TrackerRef
+		resp, err := rpc.Subscribe(ctx, req)
+		cancel()
+		switch {
+		case err != nil:
+			rpc.l.Printf("[WARN] failed to restore subscription %q after the restart of the plugin: %v",
+				req.Subscription.ID, err)
+		case resp.TrackerRef != ref:
+			rpc.l.Printf("[WARN] restored subscription %q has new tracker reference %q instead of %q",
+				req.Subscription.ID, resp.TrackerRef, ref)
+		default:
+			rpc.l.Printf("[INFO] restored subscription %q after the restart of the plugin", req.Subscription.ID)
+		}
+	}
+}
+
+// resubscribeTimeout limits the time to restore a single subscription.
+const resubscribeTimeout = 30 * time.Second
+
+// call makes a call to the plugin, if the context is done before the
+// response is received, the plugin is notified to cancel the call.
+func (rpc *JSONRPC) call(ctx context.Context, method string, call lib.Call, args, reply interface{}) error {
+	err := rpc.cl.Call(ctx, method, args, reply)
+	if ctx.Err() == nil || !errors.Is(err, ctx.Err()) {
+		return pluginErr(err)
+	}
+
+	// the context is already done, but the plugin must be notified anyway
+	cancelCtx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+	defer cancel()
+	if cerr := rpc.cl.Call(cancelCtx, "plugin.Cancel", lib.CancelReq{ID: call.ID}, &struct{}{}); cerr != nil {
+		rpc.l.Printf("[DEBUG] failed to notify plugin about cancellation of %s call %s: %v", method, call.ID, cerr)
+	}
+
+	return err
+}
+
+// healthInterval is the interval between health checks of the connections to plugins.
+const healthInterval = 30 * time.Second
+
+// pluginErr converts the error with the code, returned by the plugin,
+// to the domain error, other errors are returned as is.
+func pluginErr(err error) error {
+	var e *lib.Error
+	ok := false
+
+	var srvErr netrpc.ServerError
+	var rpcErr *lib.RPCError
+	switch {
+	case errors.As(err, &srvErr):
+		e, ok = lib.ParseError(string(srvErr))
+	case errors.As(err, &rpcErr):
+		e, ok = rpcErr.PluginError()
+	}
+	if !ok {
+		return err
+	}
+
+	switch e.Code {
+	case lib.ErrNotFound:
+		return fmt.Errorf("%s: %w", e.Message, errs.ErrNotFound)
+	case lib.ErrInvalidArgument:
+		return errs.ErrInvalidArgument(e.Message)
+	case lib.ErrConflict:
+		return errs.ErrConflict(e.Message)
+	case lib.ErrUnavailable:
+		return errs.ErrUnavailable{Message: e.Message, RetryAfter: e.RetryAfter}
+	case lib.ErrRateLimited:
+		return errs.ErrRateLimited{Message: e.Message, RetryAfter: e.RetryAfter}
+	default:
+		return err
+	}
+}
+
+// pollTimeout is the time, the plugin waits for the updates to push.
+const pollTimeout = 30 * time.Second
+
+// maxPollBackoff is the maximal delay between attempts to poll the failing plugin.
+const maxPollBackoff = 30 * time.Second
+
+// cancelTimeout limits the time to notify the plugin about the canceled call.
+const cancelTimeout = 5 * time.Second
+
 // HandleWebhook handles webhook call from the remote JSONRPC server.
+// If the plugin parses webhooks itself, the raw webhook is forwarded
+// to it, otherwise the body is expected to contain a single lib.Task.
 func (rpc *JSONRPC) HandleWebhook(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	if rpc.desc != nil && rpc.desc.Webhooks {
+		rpc.parseWebhook(w, r)
+		return
+	}
 
 	var ticket lib.Task
 	if err := json.NewDecoder(r.Body).Decode(&ticket); err != nil {
@@ -90,28 +502,163 @@ func (rpc *JSONRPC) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rpc.handler.Handle(ctx, store.Update{
-		URL: ticket.URL,
+	if !rpc.handle(r.Context(), ticket) {
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// parseWebhook forwards the raw webhook to the plugin, handles the updates,
+// parsed by the plugin and replies with the response, made by the plugin.
+func (rpc *JSONRPC) parseWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxWebhookSize))
+	if err != nil {
+		rpc.l.Printf("[WARN] failed to read webhook: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	req := lib.WebhookReq{
+		Call:    lib.NewCall(ctx, uuid.NewString()),
+		Method:  r.Method,
+		URL:     r.URL.RequestURI(),
+		Headers: r.Header,
+		Body:    body,
+	}
+	if sub, err := store.GetSubscription(ctx); err == nil {
+		req.SubscriptionID, req.TrackerRef = sub.ID, sub.TrackerRef
+	}
+
+	var resp lib.WebhookResp
+	if err = rpc.call(ctx, "plugin.ParseWebhook", req.Call, req, &resp); err != nil {
+		rpc.l.Printf("[WARN] plugin failed to parse webhook: %v", err)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	for _, task := range resp.Updates {
+		rpc.handle(ctx, task)
+	}
+
+	for k, v := range resp.Headers {
+		w.Header().Set(k, v)
+	}
+	if resp.Status == 0 {
+		resp.Status = http.StatusOK
+	}
+	w.WriteHeader(resp.Status)
+	if _, err = w.Write(resp.Body); err != nil {
+		rpc.l.Printf("[WARN] failed to write webhook response: %v", err)
+	}
+}
+
+// maxWebhookSize limits the size of the webhook, forwarded to the plugin.
+const maxWebhookSize = 10 << 20
+
+// handle passes the update of the task to the handler,
+// returns false if there is no handler set.
+func (rpc *JSONRPC) handle(ctx context.Context, task lib.Task) bool {
+	rpc.mu.RLock()
+	h := rpc.handler
+	rpc.mu.RUnlock()
+
+	if h == nil {
+		rpc.l.Printf("[WARN] no handler is set, but update received")
+		return false
+	}
+
+	h.Handle(ctx, store.Update{
+		URL: task.URL,
 		ReceivedFrom: store.Locator{
 			Tracker: rpc.name,
-			ID:      ticket.ID,
+			ID:      task.ID,
 		},
 		Content: store.Content{
-			Body:   ticket.Body,
-			Title:  ticket.Title,
-			Fields: ticket.Fields,
+			Body:   task.Body,
+			Title:  task.Title,
+			Fields: task.Fields,
 		},
 	})
-	w.WriteHeader(http.StatusOK)
+	return true
 }
 
-// Listen starts updates listener.
+// Listen starts updates listener. If the plugin serves plugin.Updates,
+// the updates, pushed by the plugin, are polled and delivered to the
+// handler as if they were received via webhooks.
 func (rpc *JSONRPC) Listen(ctx context.Context, h Handler) error {
+	rpc.mu.Lock()
 	rpc.handler = h
+	rpc.mu.Unlock()
+
+	if rpc.desc != nil && rpc.desc.Push {
+		rpc.pollUpdates(ctx)
+	}
+
 	<-ctx.Done()
 	return ctx.Err()
 }
 
+// pollUpdates polls the plugin for the pushed updates until the context is done.
+func (rpc *JSONRPC) pollUpdates(ctx context.Context) {
+	backoff := time.Duration(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		// the call is not limited by the plugin's timeout, in case if it doesn't respond
+		callCtx, cancel := context.WithTimeout(ctx, pollTimeout+cancelTimeout)
+		req := lib.UpdatesReq{Call: lib.NewCall(callCtx, uuid.NewString()), Timeout: int(pollTimeout / time.Second)}
+		var resp lib.UpdatesResp
+		err := rpc.call(callCtx, "plugin.Updates", req.Call, req, &resp)
+		cancel()
+
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			if backoff *= 2; backoff < time.Second {
+				backoff = time.Second
+			}
+			if backoff > maxPollBackoff {
+				backoff = maxPollBackoff
+			}
+			rpc.l.Printf("[WARN] failed to poll updates from plugin, retrying in %s: %v", backoff, err)
+			continue
+		}
+
+		backoff = 0
+		for _, upd := range resp.Updates {
+			rpc.deliver(ctx, upd)
+		}
+	}
+}
+
+// deliver passes the pushed update to the handler
+// with the subscription in the context.
+func (rpc *JSONRPC) deliver(ctx context.Context, upd lib.PushedUpdate) {
+	rpc.mu.RLock()
+	req, ok := rpc.subs[upd.SubscriptionID]
+	rpc.mu.RUnlock()
+
+	if !ok {
+		rpc.l.Printf("[WARN] plugin pushed update of task %q for unknown subscription %q",
+			upd.Task.ID, upd.SubscriptionID)
+		return
+	}
+
+	rpc.handle(store.PutSubscription(ctx, req.Subscription), upd.Task)
+}
+
+// libTask converts the task to its representation for plugins.
+func libTask(id, url string, content store.Content) lib.Task {
+	return lib.Task{ID: id, URL: url, Title: content.Title, Body: content.Body, Fields: content.Fields}
+}
+
 func (rpc *JSONRPC) transformResponse(resp lib.Response) Response {
 	return Response{Task: store.Task{
 		ID: resp.Task.ID,
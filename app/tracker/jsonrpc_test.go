@@ -1,14 +1,29 @@
 package tracker
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
-	"net/url"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"path/filepath"
 	"testing"
+	"time"
 
-	"bytes"
-	"encoding/json"
+	"github.com/cappuccinotm/dastracker/app/errs"
 	"github.com/cappuccinotm/dastracker/app/store"
 	"github.com/cappuccinotm/dastracker/lib"
 	"github.com/cappuccinotm/dastracker/pkg/logx"
@@ -16,20 +31,62 @@ import (
 	"github.com/cappuccinotm/dastracker/pkg/sign"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"time"
 )
 
 func TestNewJSONRPC(t *testing.T) {
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
-	defer ts.Close()
-
-	u, err := url.Parse(ts.URL)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
 	require.NoError(t, err)
 
-	_, err = NewJSONRPC("jsonrpc", logx.Nop(), lib.Vars{"address": u.Host})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = (&lib.Plugin{}).Serve(ctx, l, nopPlugin{}) }()
+
+	_, err = NewJSONRPC("jsonrpc", logx.Nop(), lib.Vars{"address": l.Addr().String()})
 	require.NoError(t, err)
 }
 
+type nopPlugin struct{}
+
+func (nopPlugin) Nop(lib.Request, *lib.Response) error { return nil }
+
+func TestJSONRPC_describe(t *testing.T) {
+	t.Run("newer protocol", func(t *testing.T) {
+		svc := &JSONRPC{cl: &rpcx.ClientMock{
+			CallFunc: func(_ context.Context, serviceMethod string, _, reply interface{}) error {
+				assert.Equal(t, "plugin.Describe", serviceMethod)
+				reply.(*lib.Description).ProtocolVersion = lib.ProtocolVersion + 1
+				return nil
+			},
+		}}
+		_, err := svc.describe(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "plugin requires protocol version 2, supported up to 1")
+	})
+
+	t.Run("not supported", func(t *testing.T) {
+		svc := &JSONRPC{cl: &rpcx.ClientMock{
+			CallFunc: func(context.Context, string, interface{}, interface{}) error {
+				return rpc.ServerError("rpc: can't find method plugin.Describe")
+			},
+		}}
+		_, err := svc.describe(context.Background())
+		assert.ErrorIs(t, err, errs.ErrActionNotSupported("Describe"))
+	})
+
+	t.Run("plugin doesn't answer", func(t *testing.T) {
+		svc := &JSONRPC{cl: &rpcx.ClientMock{
+			CallFunc: func(ctx context.Context, _ string, _, _ interface{}) error {
+				deadline, ok := ctx.Deadline()
+				require.True(t, ok, "describe must be limited in time")
+				assert.WithinDuration(t, time.Now().Add(dialTimeout), deadline, time.Second)
+				return context.DeadlineExceeded
+			},
+		}}
+		_, err := svc.describe(context.Background())
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
 func TestJSONRPC_Name(t *testing.T) {
 	assert.Equal(t, "jsonrpc", (&JSONRPC{name: "jsonrpc"}).Name())
 }
@@ -43,6 +100,8 @@ func TestJSONRPC_Call(t *testing.T) {
 			assert.True(t, ok)
 
 			assert.Equal(t, "plugin.some-method", serviceMethod)
+			assert.NotEmpty(t, req.ID, "call must be identified to be canceled")
+			req.Call = lib.Call{}
 			*resp = lib.Response{Task: lib.Task{
 				ID:     "task-id",
 				URL:    "task-url",
@@ -53,6 +112,20 @@ func TestJSONRPC_Call(t *testing.T) {
 			assert.Equal(t, lib.Request{
 				TaskID: "task-id",
 				Vars:   lib.Vars{},
+				Update: lib.Update{Tracker: "github", Task: lib.Task{
+					ID:     "gh-task-id",
+					URL:    "gh-task-url",
+					Title:  "gh-title",
+					Body:   "gh-body",
+					Fields: map[string]string{"gh-field": "gh-value"},
+				}},
+				Ticket: lib.Ticket{ID: "ticket-id", Variations: map[string]lib.Task{
+					"github": {ID: "gh-task-id", Title: "gh-title"},
+					"jrpc":   {ID: "task-id"},
+				}},
+				Job:     "job",
+				Trigger: "trigger",
+				RunID:   "run-id",
 			}, req)
 			return nil
 		},
@@ -62,6 +135,22 @@ func TestJSONRPC_Call(t *testing.T) {
 		Method: "some-method",
 		TaskID: "task-id",
 		Vars:   lib.Vars{},
+		Update: store.Update{
+			ReceivedFrom: store.Locator{Tracker: "github", ID: "gh-task-id"},
+			URL:          "gh-task-url",
+			Content: store.Content{
+				Body:   "gh-body",
+				Title:  "gh-title",
+				Fields: map[string]string{"gh-field": "gh-value"},
+			},
+		},
+		Ticket: store.Ticket{ID: "ticket-id", Variations: store.Variations{
+			"github": {ID: "gh-task-id", Content: store.Content{Title: "gh-title"}},
+			"jrpc":   {ID: "task-id"},
+		}},
+		JobName:     "job",
+		TriggerName: "trigger",
+		RunID:       "run-id",
 	})
 	require.NoError(t, err)
 	assert.Equal(t, Response{Task: store.Task{
@@ -74,6 +163,51 @@ func TestJSONRPC_Call(t *testing.T) {
 	}}, resp)
 }
 
+func TestJSONRPC_Call_Vars(t *testing.T) {
+	vars := lib.Vars{
+		"title":  "title",
+		"count":  json.Number("12345678901234567890"),
+		"draft":  true,
+		"labels": []interface{}{"bug", "help wanted"},
+		"meta":   map[string]interface{}{"priority": json.Number("1")},
+		"empty":  nil,
+	}
+	stringified := lib.Vars{
+		"title":  "title",
+		"count":  "12345678901234567890",
+		"draft":  "true",
+		"labels": "bug,help wanted",
+		"meta":   `{"priority":1}`,
+		"empty":  "",
+	}
+
+	tbl := []struct {
+		name string
+		desc *lib.Description
+		want lib.Vars
+	}{
+		{name: "plugin doesn't describe itself", desc: nil, want: stringified},
+		{name: "protocol is not reported", desc: &lib.Description{}, want: stringified},
+		{name: "typed values are negotiated", desc: &lib.Description{ProtocolVersion: typedVarsProtocol}, want: vars},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent lib.Vars
+			svc := &JSONRPC{name: "jrpc", desc: tt.desc, cl: &rpcx.ClientMock{
+				CallFunc: func(_ context.Context, _ string, args, _ interface{}) error {
+					sent = args.(lib.Request).Vars
+					return nil
+				},
+			}}
+
+			_, err := svc.Call(context.Background(), Request{Method: "some-method", Vars: vars})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, sent)
+		})
+	}
+}
+
 func TestJSONRPC_Subscribe(t *testing.T) {
 	svc := &JSONRPC{
 		name: "jrpc",
@@ -83,9 +217,11 @@ func TestJSONRPC_Subscribe(t *testing.T) {
 				assert.True(t, ok)
 
 				assert.Equal(t, "plugin.Subscribe", serviceMethod)
+				assert.NotEmpty(t, req.ID)
+				req.Call = lib.Call{}
 				assert.Equal(t, lib.SubscribeReq{
 					WebhookURL: "https://blah.com/webhooks/jrpc/trigger-id",
-					Vars:       map[string]string{"blah": "blah"},
+					Vars:       lib.Vars{"blah": "blah"},
 				}, req)
 				reply.(*lib.SubscribeResp).TrackerRef = "tracker-ref"
 				return nil
@@ -101,6 +237,45 @@ func TestJSONRPC_Subscribe(t *testing.T) {
 	assert.Equal(t, SubscribeResp{TrackerRef: "tracker-ref"}, resp)
 }
 
+func TestJSONRPC_Subscribe_PushedDuringCall(t *testing.T) {
+	sub := store.Subscription{ID: "sub-id", TriggerName: "trigger"}
+
+	t.Run("update is delivered", func(t *testing.T) {
+		var received store.Subscription
+		svc := &JSONRPC{name: "jrpc", l: logx.Nop()}
+		svc.handler = HandlerFunc(func(ctx context.Context, _ store.Update) {
+			var err error
+			received, err = store.GetSubscription(ctx)
+			require.NoError(t, err)
+		})
+		svc.cl = &rpcx.ClientMock{CallFunc: func(ctx context.Context, _ string, _, reply interface{}) error {
+			svc.deliver(ctx, lib.PushedUpdate{SubscriptionID: "sub-id", Task: lib.Task{ID: "task-id"}})
+			reply.(*lib.SubscribeResp).TrackerRef = "tracker-ref"
+			return nil
+		}}
+
+		_, err := svc.Subscribe(context.Background(), SubscribeReq{Subscription: sub})
+		require.NoError(t, err)
+		assert.Equal(t, sub, received)
+
+		expected := sub
+		expected.TrackerRef = "tracker-ref"
+		assert.Equal(t, map[string]SubscribeReq{"sub-id": {Subscription: expected}}, svc.subs)
+	})
+
+	t.Run("subscription is removed on failure", func(t *testing.T) {
+		svc := &JSONRPC{name: "jrpc", l: logx.Nop(), cl: &rpcx.ClientMock{
+			CallFunc: func(context.Context, string, interface{}, interface{}) error {
+				return errors.New("failed")
+			},
+		}}
+
+		_, err := svc.Subscribe(context.Background(), SubscribeReq{Subscription: sub})
+		require.Error(t, err)
+		assert.Empty(t, svc.subs)
+	})
+}
+
 func TestJSONRPC_Unsubscribe(t *testing.T) {
 	svc := &JSONRPC{
 		name: "jrpc",
@@ -110,6 +285,8 @@ func TestJSONRPC_Unsubscribe(t *testing.T) {
 				assert.True(t, ok)
 
 				assert.Equal(t, "plugin.Unsubscribe", serviceMethod)
+				assert.NotEmpty(t, req.ID)
+				req.Call = lib.Call{}
 				assert.Equal(t, lib.UnsubscribeReq{TrackerRef: "tracker-ref"}, req)
 				return nil
 			},
@@ -173,3 +350,573 @@ func TestJSONRPC_HandleWebhook(t *testing.T) {
 	assert.Equal(t, resp.StatusCode, http.StatusOK)
 	require.NoError(t, called.WaitTimeout(5*time.Second))
 }
+
+type subsPlugin struct{}
+
+func (subsPlugin) Subscribe(req lib.SubscribeReq, resp *lib.SubscribeResp) error {
+	resp.TrackerRef = "ref-" + req.Vars.Get("project")
+	return nil
+}
+
+func (subsPlugin) Unsubscribe(lib.UnsubscribeReq, *struct{}) error { return nil }
+
+// startPlugin starts JSON-RPC server with the given receiver.
+func startPlugin(t *testing.T, rcvr interface{}) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() { _ = (&lib.Plugin{Name: "test"}).Serve(ctx, l, rcvr) }()
+
+	return l.Addr().String()
+}
+
+// startLegacyPlugin starts JSON-RPC server, which doesn't serve
+// the plugin.Describe method, as plugins of older versions.
+func startLegacyPlugin(t *testing.T, rcvr interface{}) string {
+	srv := rpc.NewServer()
+	require.NoError(t, srv.RegisterName("plugin", rcvr))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, l.Close()) })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestJSONRPC_Description(t *testing.T) {
+	t.Run("plugin describes itself", func(t *testing.T) {
+		svc, err := NewJSONRPC("rpc", logx.Nop(), lib.Vars{"address": startPlugin(t, &subsPlugin{})})
+		require.NoError(t, err)
+
+		desc, ok := svc.Description()
+		require.True(t, ok)
+		assert.Equal(t, lib.Description{
+			ProtocolVersion: lib.ProtocolVersion,
+			Name:            "test",
+			Subscriptions:   true,
+		}, desc)
+	})
+
+	t.Run("legacy plugin", func(t *testing.T) {
+		svc, err := NewJSONRPC("rpc", logx.Nop(), lib.Vars{"address": startLegacyPlugin(t, &echoPlugin{})})
+		require.NoError(t, err)
+
+		_, ok := svc.Description()
+		assert.False(t, ok)
+
+		_, err = svc.Call(context.Background(), Request{Method: "Echo"})
+		assert.NoError(t, err, "legacy plugins must keep working")
+	})
+
+	t.Run("legacy plugin with string variables", func(t *testing.T) {
+		plugin := &legacyVarsPlugin{}
+		svc, err := NewJSONRPC("rpc", logx.Nop(), lib.Vars{"address": startLegacyPlugin(t, plugin)})
+		require.NoError(t, err)
+
+		_, err = svc.Call(context.Background(), Request{Method: "Echo", Vars: lib.Vars{
+			"title":  "title",
+			"count":  json.Number("3"),
+			"draft":  false,
+			"labels": []interface{}{"bug", "docs"},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"title": "title", "count": "3", "draft": "false", "labels": "bug,docs"},
+			plugin.received)
+	})
+}
+
+// legacyVarsPlugin decodes variables as plugins, built before typed values of variables.
+type legacyVarsPlugin struct{ received map[string]string }
+
+func (p *legacyVarsPlugin) Echo(req struct {
+	Vars map[string]string `json:"vars"`
+}, _ *lib.Response) error {
+	p.received = req.Vars
+	return nil
+}
+
+func TestJSONRPC_TypedVars(t *testing.T) {
+	plugin := &echoPlugin{}
+	svc, err := NewJSONRPC("rpc", logx.Nop(), lib.Vars{"address": startPlugin(t, plugin)})
+	require.NoError(t, err)
+
+	vars := lib.Vars{
+		"str":  "a,b",
+		"num":  json.Number("9007199254740993"),
+		"bool": true,
+		"list": []interface{}{"a,b", "c"},
+		"map":  map[string]interface{}{"nested": []interface{}{json.Number("1.10")}},
+	}
+
+	resp, err := svc.Call(context.Background(), Request{Method: "Echo", TaskID: "task-id", Vars: vars})
+	require.NoError(t, err)
+	assert.Equal(t, "task-id", resp.Task.ID)
+	assert.Equal(t, vars, plugin.received, "values must be carried intact")
+	assert.Equal(t, []string{"a,b", "c"}, plugin.received.List("list"))
+	assert.Equal(t, "a,b", plugin.received.Get("str"))
+	assert.Equal(t, "9007199254740993", plugin.received.Get("num"), "numbers mustn't be rounded")
+}
+
+type echoPlugin struct{ received lib.Vars }
+
+func (p *echoPlugin) Echo(req lib.Request, resp *lib.Response) error {
+	p.received = req.Vars
+	resp.Task.ID = req.TaskID
+	return nil
+}
+
+func TestJSONRPC_Cancellation(t *testing.T) {
+	plugin := &waitPlugin{started: make(chan struct{}, 1), canceled: make(chan error, 1)}
+	svc, err := NewJSONRPC("rpc", logx.Nop(), lib.Vars{"address": startPlugin(t, plugin)})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-plugin.started
+		cancel()
+	}()
+
+	_, err = svc.Call(ctx, Request{Method: "Wait"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	select {
+	case err := <-plugin.canceled:
+		assert.Equal(t, context.Canceled, err, "plugin must be notified about the cancellation")
+	case <-time.After(5 * time.Second):
+		t.Fatal("plugin wasn't notified about the cancellation")
+	}
+}
+
+type waitPlugin struct {
+	started  chan struct{}
+	canceled chan error
+}
+
+func (p *waitPlugin) Wait(req lib.Request, _ *lib.Response) error {
+	p.started <- struct{}{}
+	<-req.Context().Done()
+	p.canceled <- req.Context().Err()
+	return req.Context().Err()
+}
+
+func TestJSONRPC_SecureTransport(t *testing.T) {
+	dir := t.TempDir()
+	writeCerts(t, dir)
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	serverTLS, err := lib.ServerTLS(path("server.pem"), path("server.key"), path("ca.pem"))
+	require.NoError(t, err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = (&lib.Plugin{TLS: serverTLS, Token: "secret"}).Serve(ctx, l, &echoPlugin{}) }()
+
+	vars := func(upd lib.Vars) lib.Vars {
+		res := lib.Vars{
+			"address":  l.Addr().String(),
+			"tls_ca":   path("ca.pem"),
+			"tls_cert": path("client.pem"),
+			"tls_key":  path("client.key"),
+			"token":    "secret",
+		}
+		for k, v := range upd {
+			if v == nil {
+				delete(res, k)
+				continue
+			}
+			res[k] = v
+		}
+		return res
+	}
+
+	t.Run("mTLS and token", func(t *testing.T) {
+		svc, err := NewJSONRPC("rpc", logx.Nop(), vars(nil))
+		require.NoError(t, err)
+		defer svc.Close()
+
+		resp, err := svc.Call(context.Background(), Request{Method: "Echo", TaskID: "task-id"})
+		require.NoError(t, err)
+		assert.Equal(t, "task-id", resp.Task.ID)
+	})
+
+	tbl := []struct {
+		name string
+		vars lib.Vars
+	}{
+		{name: "wrong token", vars: lib.Vars{"token": "wrong"}},
+		{name: "no token", vars: lib.Vars{"token": nil}},
+		{name: "no client certificate", vars: lib.Vars{"tls_cert": nil, "tls_key": nil}},
+		{name: "plain connection", vars: lib.Vars{"tls_ca": nil, "tls_cert": nil, "tls_key": nil}},
+	}
+	for _, tt := range tbl {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			svc, err := NewJSONRPC("rpc", logx.Nop(), vars(tt.vars))
+			if err == nil {
+				// connection is established before the calls are rejected
+				_, err = svc.Call(context.Background(), Request{Method: "Echo"})
+				_ = svc.Close()
+			}
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestJSONRPC_HTTPTransport(t *testing.T) {
+	dir := t.TempDir()
+	writeCerts(t, dir)
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	serverTLS, err := lib.ServerTLS(path("server.pem"), path("server.key"), "")
+	require.NoError(t, err)
+
+	start := func(t *testing.T, p *lib.Plugin, rcvr interface{}) string {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		p.Transport = lib.TransportHTTP
+		go func() { _ = p.Serve(ctx, l, rcvr) }()
+		return l.Addr().String()
+	}
+
+	t.Run("plain with token", func(t *testing.T) {
+		addr := start(t, &lib.Plugin{Name: "test", Token: "secret"}, &echoPlugin{})
+		svc, err := NewJSONRPC("rpc", logx.Nop(),
+			lib.Vars{"address": "http://" + addr + "/", "transport": "http", "token": "secret"})
+		require.NoError(t, err)
+		defer svc.Close()
+
+		desc, ok := svc.Description()
+		require.True(t, ok)
+		assert.Equal(t, "test", desc.Name)
+
+		resp, err := svc.Call(context.Background(), Request{Method: "Echo", TaskID: "task-id"})
+		require.NoError(t, err)
+		assert.Equal(t, "task-id", resp.Task.ID)
+
+		_, err = svc.Call(context.Background(), Request{Method: "Unknown"})
+		var rpcErr *lib.RPCError
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, lib.CodeMethodNotFound, rpcErr.Code)
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		addr := start(t, &lib.Plugin{TLS: serverTLS}, &echoPlugin{})
+		svc, err := NewJSONRPC("rpc", logx.Nop(),
+			lib.Vars{"address": addr, "transport": "http", "tls_ca": path("ca.pem")})
+		require.NoError(t, err)
+		defer svc.Close()
+
+		resp, err := svc.Call(context.Background(), Request{Method: "Echo", TaskID: "task-id"})
+		require.NoError(t, err)
+		assert.Equal(t, "task-id", resp.Task.ID)
+	})
+
+	t.Run("wrong token", func(t *testing.T) {
+		addr := start(t, &lib.Plugin{Token: "secret"}, &echoPlugin{})
+		_, err := NewJSONRPC("rpc", logx.Nop(),
+			lib.Vars{"address": addr, "transport": "http", "token": "wrong"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid token")
+	})
+
+	t.Run("cancellation", func(t *testing.T) {
+		plugin := &waitPlugin{started: make(chan struct{}, 1), canceled: make(chan error, 1)}
+		addr := start(t, &lib.Plugin{}, plugin)
+		svc, err := NewJSONRPC("rpc", logx.Nop(), lib.Vars{"address": addr, "transport": "http"})
+		require.NoError(t, err)
+		defer svc.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-plugin.started
+			cancel()
+		}()
+
+		_, err = svc.Call(ctx, Request{Method: "Wait"})
+		assert.ErrorIs(t, err, context.Canceled)
+
+		select {
+		case err := <-plugin.canceled:
+			assert.Equal(t, context.Canceled, err, "plugin must be notified about the cancellation")
+		case <-time.After(5 * time.Second):
+			t.Fatal("plugin wasn't notified about the cancellation")
+		}
+	})
+
+	t.Run("unsupported transport", func(t *testing.T) {
+		_, err := NewJSONRPC("rpc", logx.Nop(), lib.Vars{"address": "127.0.0.1:1", "transport": "grpc"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unsupported transport "grpc"`)
+	})
+}
+
+func TestJSONRPC_ParseWebhook(t *testing.T) {
+	plugin := &webhookPlugin{}
+	svc, err := NewJSONRPC("rpc", logx.Nop(), lib.Vars{"address": startPlugin(t, plugin)})
+	require.NoError(t, err)
+	defer svc.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates := make(chan store.Update, 10)
+	go func() {
+		_ = svc.Listen(ctx, HandlerFunc(func(_ context.Context, upd store.Update) { updates <- upd }))
+	}()
+
+	webhook := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/webhook-id?source=test", bytes.NewBufferString(body))
+		req.Header.Set("X-Signature", "signature")
+		req = req.WithContext(store.PutSubscription(req.Context(),
+			store.Subscription{ID: "webhook-id", TrackerRef: "ref"}))
+		rec := httptest.NewRecorder()
+		svc.HandleWebhook(rec, req)
+		return rec
+	}
+
+	t.Run("verification challenge", func(t *testing.T) {
+		rec := webhook(`{"challenge": "abc"}`)
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "abc", rec.Body.String())
+		assert.Equal(t, lib.WebhookReq{
+			SubscriptionID: "webhook-id",
+			TrackerRef:     "ref",
+			Method:         http.MethodPost,
+			URL:            "/webhook-id?source=test",
+			Headers:        map[string][]string{"X-Signature": {"signature"}},
+			Body:           []byte(`{"challenge": "abc"}`),
+		}, plugin.received)
+	})
+
+	t.Run("batched events", func(t *testing.T) {
+		// listener might not be set up yet, so the webhook is retried until handled
+		require.Eventually(t, func() bool {
+			rec := webhook(`{"events": [{"id": "task-1", "title": "first"}, {"id": "task-2", "title": "second"}]}`)
+			return rec.Code == http.StatusOK && len(updates) > 0
+		}, 5*time.Second, 10*time.Millisecond)
+
+		assert.Equal(t, store.Update{ReceivedFrom: store.Locator{Tracker: "rpc", ID: "task-1"},
+			Content: store.Content{Title: "first"}}, <-updates)
+		assert.Equal(t, store.Update{ReceivedFrom: store.Locator{Tracker: "rpc", ID: "task-2"},
+			Content: store.Content{Title: "second"}}, <-updates)
+	})
+
+	t.Run("plugin failed to parse webhook", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadGateway, webhook(`not a json`).Code)
+	})
+}
+
+type webhookPlugin struct{ received lib.WebhookReq }
+
+func (p *webhookPlugin) ParseWebhook(req lib.WebhookReq, resp *lib.WebhookResp) error {
+	req.Call = lib.Call{}
+	p.received = req
+
+	var body struct {
+		Challenge string     `json:"challenge"`
+		Events    []lib.Task `json:"events"`
+	}
+	if err := json.Unmarshal(req.Body, &body); err != nil {
+		return fmt.Errorf("decode body: %w", err)
+	}
+
+	if body.Challenge != "" {
+		resp.Status = http.StatusAccepted
+		resp.Headers = map[string]string{"Content-Type": "text/plain"}
+		resp.Body = []byte(body.Challenge)
+		return nil
+	}
+
+	resp.Updates = body.Events
+	return nil
+}
+
+func TestJSONRPC_PushedUpdates(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	plugin := &lib.Plugin{PushUpdates: true}
+	go func() { _ = plugin.Serve(ctx, l, &subsPlugin{}) }()
+
+	svc, err := NewJSONRPC("rpc", logx.Nop(), lib.Vars{"address": l.Addr().String()})
+	require.NoError(t, err)
+	defer svc.Close()
+
+	sub := store.Subscription{ID: "sub-id", TrackerName: "rpc", TriggerName: "trigger"}
+	_, err = svc.Subscribe(ctx, SubscribeReq{Vars: lib.Vars{"project": "project"}, Subscription: sub})
+	require.NoError(t, err)
+
+	type received struct {
+		sub store.Subscription
+		upd store.Update
+	}
+	updates := make(chan received, 10)
+	go func() {
+		_ = svc.Listen(ctx, HandlerFunc(func(ctx context.Context, upd store.Update) {
+			sub, err := store.GetSubscription(ctx)
+			assert.NoError(t, err)
+			updates <- received{sub: sub, upd: upd}
+		}))
+	}()
+
+	require.NoError(t, plugin.Push(ctx, "unknown-sub-id", lib.Task{ID: "task-0"}))
+	require.NoError(t, plugin.Push(ctx, "sub-id", lib.Task{ID: "task-1", URL: "url", Title: "title"}))
+
+	select {
+	case r := <-updates:
+		sub.TrackerRef = "ref-project"
+		assert.Equal(t, sub, r.sub)
+		assert.Equal(t, store.Update{
+			URL:          "url",
+			ReceivedFrom: store.Locator{Tracker: "rpc", ID: "task-1"},
+			Content:      store.Content{Title: "title"},
+		}, r.upd)
+	case <-time.After(5 * time.Second):
+		t.Fatal("pushed update wasn't delivered")
+	}
+
+	// updates for the removed subscriptions are dropped
+	require.NoError(t, svc.Unsubscribe(ctx, UnsubscribeReq{TrackerRef: "ref-project"}))
+	require.NoError(t, plugin.Push(ctx, "sub-id", lib.Task{ID: "task-2"}))
+	select {
+	case r := <-updates:
+		t.Fatalf("unexpected update %+v", r)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestJSONRPC_PluginErrors(t *testing.T) {
+	startHTTP := func(t *testing.T) lib.Vars {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		go func() { _ = (&lib.Plugin{Transport: lib.TransportHTTP}).Serve(ctx, l, &errPlugin{}) }()
+		return lib.Vars{"address": l.Addr().String(), "transport": "http"}
+	}
+
+	transports := map[string]func(t *testing.T) lib.Vars{
+		"rpc":  func(t *testing.T) lib.Vars { return lib.Vars{"address": startPlugin(t, &errPlugin{})} },
+		"http": startHTTP,
+	}
+
+	for name, start := range transports {
+		start := start
+		t.Run(name, func(t *testing.T) {
+			svc, err := NewJSONRPC("rpc", logx.Nop(), start(t))
+			require.NoError(t, err)
+			defer svc.Close()
+
+			call := func(code string) error {
+				_, err := svc.Call(context.Background(), Request{Method: "Fail", Vars: lib.Vars{"code": code}})
+				require.Error(t, err)
+				return err
+			}
+
+			assert.ErrorIs(t, call("not_found"), errs.ErrNotFound)
+
+			var invalid errs.ErrInvalidArgument
+			require.ErrorAs(t, call("invalid_argument"), &invalid)
+			assert.Equal(t, errs.ErrInvalidArgument("failure"), invalid)
+
+			var conflict errs.ErrConflict
+			require.ErrorAs(t, call("conflict"), &conflict)
+			assert.Equal(t, errs.ErrConflict("failure"), conflict)
+
+			var unavailable errs.ErrUnavailable
+			require.ErrorAs(t, call("unavailable"), &unavailable)
+			assert.Equal(t, errs.ErrUnavailable{Message: "failure"}, unavailable)
+
+			var rateLimited errs.ErrRateLimited
+			require.ErrorAs(t, call("rate_limited"), &rateLimited)
+			assert.Equal(t, errs.ErrRateLimited{Message: "failure", RetryAfter: 30 * time.Second}, rateLimited)
+			retryAfter, ok := errs.RetryAfter(rateLimited)
+			assert.True(t, ok)
+			assert.Equal(t, 30*time.Second, retryAfter)
+
+			err = call("")
+			assert.Equal(t, "call remote method Fail: failure", err.Error())
+			_, ok = errs.RetryAfter(err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+type errPlugin struct{}
+
+func (errPlugin) Fail(req lib.Request, _ *lib.Response) error {
+	switch code := lib.ErrorCode(req.Vars.Get("code")); code {
+	case "":
+		return errors.New("failure")
+	case lib.ErrRateLimited:
+		return &lib.Error{Code: code, Message: "failure", RetryAfter: 30 * time.Second}
+	default:
+		return fmt.Errorf("wrapped: %w", lib.Errorf(code, "failure"))
+	}
+}
+
+// writeCerts writes the CA, server's and client's certificates and keys to the directory.
+func writeCerts(t *testing.T, dir string) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	writePEM(t, filepath.Join(dir, "ca.pem"), "CERTIFICATE", caDER)
+
+	issue := func(name string, serial int64, usage x509.ExtKeyUsage) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caTmpl, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		writePEM(t, filepath.Join(dir, name+".pem"), "CERTIFICATE", der)
+
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		writePEM(t, filepath.Join(dir, name+".key"), "EC PRIVATE KEY", keyDER)
+	}
+	issue("server", 2, x509.ExtKeyUsageServerAuth)
+	issue("client", 3, x509.ExtKeyUsageClientAuth)
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	require.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600))
+}
@@ -0,0 +1,348 @@
+package tracker
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/lib"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+	"github.com/cappuccinotm/dastracker/pkg/rpcx"
+)
+
+// ProcessParams describes parameters to launch the plugin.
+type ProcessParams struct {
+	Command []string    // binary of the plugin and its arguments
+	Network string      // "tcp" or "unix", tcp by default
+	Dialer  rpcx.Dialer // dials the address of the plugin, plain JSON-RPC by default
+	Logger  logx.Logger
+
+	// OnRestart is called after the crashed plugin is restarted,
+	// the restarted plugin doesn't keep the state of the crashed one
+	OnRestart func()
+
+	// all durations have reasonable defaults
+	StartTimeout time.Duration // time to wait for the handshake
+	StopTimeout  time.Duration // time to wait for the plugin to exit gracefully, before killing it
+	MinBackoff   time.Duration // delay before the first restart of the crashed plugin
+	MaxBackoff   time.Duration // maximal delay between restarts
+}
+
+// Process launches the plugin binary, restarts it when it exits and
+// dials the address, reported by the plugin in the handshake. It implements
+// rpcx.Dialer, so the reconnecting client follows the restarted plugin.
+type Process struct {
+	ProcessParams
+	sockDir string
+
+	mu      sync.Mutex
+	network string
+	addr    string
+	ready   chan struct{} // closed when the plugin is running and the address is known
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// StartProcess launches the plugin and waits for its handshake.
+func StartProcess(params ProcessParams) (*Process, error) {
+	if len(params.Command) == 0 {
+		return nil, errors.New("command is empty")
+	}
+
+	p := &Process{
+		ProcessParams: params,
+		ready:         make(chan struct{}),
+		stop:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	p.setDefaults()
+
+	if p.Network == "unix" {
+		dir, err := ioutil.TempDir("", "dastracker-plugin-")
+		if err != nil {
+			return nil, fmt.Errorf("make directory for the socket: %w", err)
+		}
+		p.sockDir = dir
+	}
+
+	cmd, exited, err := p.start()
+	if err != nil {
+		p.removeSocket()
+		return nil, err
+	}
+
+	go p.supervise(cmd, exited)
+	return p, nil
+}
+
+// Dial waits for the plugin to be running and dials its address.
+// Network and address arguments are ignored.
+func (p *Process) Dial(ctx context.Context, _, _ string) (rpcx.RPCClient, error) {
+	network, addr, err := p.Addr(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.Dialer.Dial(ctx, network, addr)
+}
+
+// Addr waits for the plugin to be running and returns its address.
+func (p *Process) Addr(ctx context.Context) (network, addr string, err error) {
+	p.mu.Lock()
+	ready := p.ready
+	p.mu.Unlock()
+
+	select {
+	case <-ready:
+	case <-p.stopped:
+		return "", "", errors.New("plugin is stopped")
+	case <-ctx.Done():
+		return "", "", ctx.Err()
+	case <-time.After(p.MaxBackoff + p.StartTimeout):
+		return "", "", errors.New("plugin is not running")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.network, p.addr, nil
+}
+
+// Close stops the plugin: interrupts it and kills,
+// if it doesn't exit in StopTimeout.
+func (p *Process) Close() error {
+	select {
+	case <-p.stop:
+	default:
+		close(p.stop)
+	}
+	<-p.stopped
+	p.removeSocket()
+	return nil
+}
+
+// supervise waits for the plugin to exit and restarts it with the
+// exponential backoff, until the process is closed.
+func (p *Process) supervise(cmd *exec.Cmd, exited <-chan error) {
+	defer close(p.stopped)
+
+	backoff := p.MinBackoff
+	startedAt := time.Now()
+
+	for {
+		select {
+		case <-p.stop:
+			p.terminate(cmd, exited)
+			return
+		case err := <-exited:
+			p.mu.Lock()
+			p.ready = make(chan struct{})
+			p.mu.Unlock()
+
+			// the plugin, that worked for a while, is considered recovered
+			if time.Since(startedAt) > p.MaxBackoff {
+				backoff = p.MinBackoff
+			}
+
+			p.Logger.Printf("[WARN] plugin exited: %v", err)
+			for {
+				p.Logger.Printf("[INFO] restarting plugin in %s", backoff)
+
+				select {
+				case <-p.stop:
+					return
+				case <-time.After(backoff):
+				}
+
+				if backoff *= 2; backoff > p.MaxBackoff {
+					backoff = p.MaxBackoff
+				}
+
+				if cmd, exited, err = p.start(); err == nil {
+					startedAt = time.Now()
+					if p.OnRestart != nil {
+						go p.OnRestart()
+					}
+					break
+				}
+				p.Logger.Printf("[WARN] failed to restart plugin: %v", err)
+			}
+		}
+	}
+}
+
+// start launches the plugin and waits for its handshake.
+func (p *Process) start() (*exec.Cmd, <-chan error, error) {
+	cmd := exec.Command(p.Command[0], p.Command[1:]...) //nolint:gosec // the command is set by the operator
+	cmd.Env = append(os.Environ(), lib.EnvManaged+"=1", lib.EnvNetwork+"="+p.Network)
+	if p.Network == "unix" {
+		sock := filepath.Join(p.sockDir, "plugin.sock")
+		// the socket might be left by the crashed plugin
+		if err := os.Remove(sock); err != nil && !os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+		cmd.Env = append(cmd.Env, lib.EnvSocket+"="+sock)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("make stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("make stderr pipe: %w", err)
+	}
+
+	if err = cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("start plugin: %w", err)
+	}
+
+	type handshake struct {
+		network, addr string
+		err           error
+	}
+	hs := make(chan handshake, 1)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		p.forward(stderr, nil)
+	}()
+	go func() {
+		defer wg.Done()
+		p.forward(stdout, func(line string) bool {
+			network, addr, ok, err := lib.ParseHandshake(line)
+			if ok {
+				select {
+				case hs <- handshake{network: network, addr: addr, err: err}:
+				default: // repeated handshakes are ignored
+				}
+			}
+			return ok
+		})
+	}()
+
+	// Wait must be called after all reads from the pipes are completed
+	exited := make(chan error, 1)
+	go func() {
+		wg.Wait()
+		exited <- cmd.Wait()
+	}()
+
+	select {
+	case h := <-hs:
+		if h.err != nil {
+			p.kill(cmd, exited)
+			return nil, nil, fmt.Errorf("invalid handshake: %w", h.err)
+		}
+		p.mu.Lock()
+		p.network, p.addr = h.network, h.addr
+		close(p.ready)
+		p.mu.Unlock()
+		p.Logger.Printf("[INFO] plugin started, pid %d, listening on %s://%s", cmd.Process.Pid, h.network, h.addr)
+		return cmd, exited, nil
+	case err = <-exited:
+		return nil, nil, fmt.Errorf("plugin exited before handshake: %v", err)
+	case <-time.After(p.StartTimeout):
+		p.kill(cmd, exited)
+		return nil, nil, fmt.Errorf("no handshake from plugin in %s", p.StartTimeout)
+	}
+}
+
+// forward writes the output of the plugin to the log, lines, for which
+// intercept returns true, are not logged.
+func (p *Process) forward(r io.Reader, intercept func(line string) bool) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLogLine)
+	for sc.Scan() {
+		line := sc.Text()
+		if intercept != nil && intercept(line) {
+			continue
+		}
+
+		// lines, logged by the plugin, usually have their own timestamps
+		// and levels, level is kept, the rest is replaced by our logger
+		lvl := "INFO"
+		for _, l := range []string{"DEBUG", "INFO", "WARN", "ERROR"} {
+			if idx := strings.Index(line, "["+l+"]"); idx >= 0 {
+				lvl, line = l, strings.TrimSpace(line[idx+len(l)+2:])
+				break
+			}
+		}
+		p.Logger.Printf("["+lvl+"] %s", line)
+	}
+
+	if err := sc.Err(); err != nil {
+		p.Logger.Printf("[WARN] stopped forwarding output of the plugin: %v", err)
+		// the plugin blocks on writing to the full pipe, if it's not read
+		_, _ = io.Copy(ioutil.Discard, r)
+	}
+}
+
+// maxLogLine is the maximum length of the line of the plugin's output.
+const maxLogLine = 1024 * 1024
+
+// terminate interrupts the plugin and kills it, if it doesn't exit in time.
+func (p *Process) terminate(cmd *exec.Cmd, exited <-chan error) {
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		p.kill(cmd, exited)
+		return
+	}
+
+	select {
+	case err := <-exited:
+		p.Logger.Printf("[INFO] plugin stopped: %v", err)
+	case <-time.After(p.StopTimeout):
+		p.Logger.Printf("[WARN] plugin didn't stop in %s, killing", p.StopTimeout)
+		p.kill(cmd, exited)
+	}
+}
+
+func (p *Process) kill(cmd *exec.Cmd, exited <-chan error) {
+	if err := cmd.Process.Kill(); err != nil {
+		p.Logger.Printf("[WARN] failed to kill plugin: %v", err)
+	}
+	<-exited
+}
+
+func (p *Process) removeSocket() {
+	if p.sockDir == "" {
+		return
+	}
+	if err := os.RemoveAll(p.sockDir); err != nil {
+		p.Logger.Printf("[WARN] failed to remove directory of the socket: %v", err)
+	}
+}
+
+func (p *Process) setDefaults() {
+	if p.Network == "" {
+		p.Network = "tcp"
+	}
+	if p.Dialer == nil {
+		p.Dialer = rpcx.JSONRPC()
+	}
+	if p.Logger == nil {
+		p.Logger = logx.Nop()
+	}
+	if p.StartTimeout == 0 {
+		p.StartTimeout = 10 * time.Second
+	}
+	if p.StopTimeout == 0 {
+		p.StopTimeout = 5 * time.Second
+	}
+	if p.MinBackoff == 0 {
+		p.MinBackoff = time.Second
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = time.Minute
+	}
+}
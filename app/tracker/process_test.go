@@ -0,0 +1,269 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/lib"
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHelperPlugin is not a real test, it runs the plugin,
+// when the test binary is launched by the Process.
+func TestHelperPlugin(t *testing.T) {
+	mode := os.Getenv("DASTRACKER_TEST_PLUGIN")
+	if mode == "" {
+		return
+	}
+	defer os.Exit(0)
+
+	if mode == "silent" {
+		time.Sleep(time.Minute)
+		return
+	}
+
+	log.Printf("[WARN] helper plugin started")
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	pl := &lib.Plugin{Name: "helper", PushUpdates: true}
+	_ = pl.Listen(ctx, helperPlugin{pl: pl})
+}
+
+type helperPlugin struct{ pl *lib.Plugin }
+
+// Subscribe pushes updates of the task with the pid of the plugin, until the plugin exits.
+func (p helperPlugin) Subscribe(req lib.SubscribeReq, resp *lib.SubscribeResp) error {
+	go func() {
+		for {
+			_ = p.pl.Push(context.Background(), req.SubscriptionID, lib.Task{ID: strconv.Itoa(os.Getpid())})
+			time.Sleep(50 * time.Millisecond)
+		}
+	}()
+	resp.TrackerRef = "ref-" + req.SubscriptionID
+	return nil
+}
+
+func (helperPlugin) Unsubscribe(lib.UnsubscribeReq, *struct{}) error { return nil }
+
+func (helperPlugin) Pid(_ lib.Request, resp *lib.Response) error {
+	resp.Task.ID = strconv.Itoa(os.Getpid())
+	return nil
+}
+
+func (helperPlugin) Exit(lib.Request, *lib.Response) error {
+	os.Exit(1)
+	return nil
+}
+
+func helperCommand(t *testing.T, mode string) []string {
+	t.Setenv("DASTRACKER_TEST_PLUGIN", mode)
+	return []string{os.Args[0], "-test.run=^TestHelperPlugin$"}
+}
+
+func TestNewJSONRPC_Command(t *testing.T) {
+	for _, network := range []string{"tcp", "unix"} {
+		network := network
+		t.Run(network, func(t *testing.T) {
+			cmd := helperCommand(t, "plugin")
+
+			lg := &logsCollector{}
+			svc, err := NewJSONRPC("rpc", lg.Logger(), lib.Vars{
+				"command": []interface{}{cmd[0], cmd[1]},
+				"network": network,
+			})
+			require.NoError(t, err)
+
+			desc, ok := svc.Description()
+			require.True(t, ok)
+			assert.Equal(t, "helper", desc.Name)
+
+			resp, err := svc.Call(context.Background(), Request{Method: "Pid"})
+			require.NoError(t, err)
+			pid, err := strconv.Atoi(resp.Task.ID)
+			require.NoError(t, err)
+
+			require.NoError(t, svc.Close())
+			assert.Error(t, findProcess(pid), "plugin must be stopped")
+			assert.Contains(t, lg.Lines(), "[WARN] helper plugin started", "logs of the plugin must be forwarded")
+		})
+	}
+}
+
+func TestJSONRPC_RestartedPlugin(t *testing.T) {
+	cmd := helperCommand(t, "plugin")
+	lg := &logsCollector{}
+	svc, err := NewJSONRPC("rpc", lg.Logger(), lib.Vars{"command": []interface{}{cmd[0], cmd[1]}})
+	require.NoError(t, err)
+	defer svc.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates := make(chan string, 100)
+	go func() {
+		_ = svc.Listen(ctx, HandlerFunc(func(_ context.Context, upd store.Update) {
+			select {
+			case updates <- upd.ReceivedFrom.ID:
+			default:
+			}
+		}))
+	}()
+
+	_, err = svc.Subscribe(ctx, SubscribeReq{Subscription: store.Subscription{ID: "sub-id"}})
+	require.NoError(t, err)
+
+	var before string
+	select {
+	case before = <-updates:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pushed update wasn't delivered")
+	}
+
+	_, err = svc.Call(ctx, Request{Method: "Exit"})
+	require.Error(t, err)
+
+	deadline := time.After(10 * time.Second)
+	for {
+		select {
+		case pid := <-updates:
+			if pid == before {
+				continue
+			}
+			assert.Contains(t, lg.Lines(), `[INFO] restored subscription "sub-id" after the restart of the plugin`)
+			return
+		case <-deadline:
+			t.Fatal("updates from the restarted plugin weren't delivered")
+		}
+	}
+}
+
+func TestProcess_Restart(t *testing.T) {
+	proc, err := StartProcess(ProcessParams{
+		Command:    helperCommand(t, "plugin"),
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer proc.Close()
+
+	pid := func() string {
+		cl, err := proc.Dial(context.Background(), "", "")
+		require.NoError(t, err)
+		defer cl.Close()
+
+		var resp lib.Response
+		require.NoError(t, cl.Call("plugin.Pid", lib.Request{}, &resp))
+		return resp.Task.ID
+	}
+
+	before := pid()
+
+	cl, err := proc.Dial(context.Background(), "", "")
+	require.NoError(t, err)
+	assert.Error(t, cl.Call("plugin.Exit", lib.Request{}, &lib.Response{}))
+	_ = cl.Close()
+
+	// the address of the plugin is unknown until the plugin is restarted
+	require.Eventually(t, func() bool {
+		cl, err := proc.Dial(context.Background(), "", "")
+		if err != nil {
+			return false
+		}
+		defer cl.Close()
+		return cl.Call("plugin.Pid", lib.Request{}, &lib.Response{}) == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.NotEqual(t, before, pid(), "plugin must be restarted")
+}
+
+func TestProcess_Addr(t *testing.T) {
+	// the plugin is being restarted
+	proc := &Process{
+		ProcessParams: ProcessParams{StartTimeout: time.Minute, MaxBackoff: time.Minute},
+		ready:         make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, _, err := proc.Addr(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "waiting for the plugin must be interrupted by the context")
+}
+
+func TestStartProcess_NoHandshake(t *testing.T) {
+	_, err := StartProcess(ProcessParams{
+		Command:      helperCommand(t, "silent"),
+		StartTimeout: 100 * time.Millisecond,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no handshake from plugin in 100ms")
+
+	_, err = StartProcess(ProcessParams{Command: []string{"/non/existing/plugin"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "start plugin")
+}
+
+func TestProcess_forward(t *testing.T) {
+	t.Run("long line", func(t *testing.T) {
+		lg := &logsCollector{}
+		long := strings.Repeat("a", 100*1024)
+		(&Process{ProcessParams: ProcessParams{Logger: lg.Logger()}}).
+			forward(strings.NewReader("[WARN] "+long+"\nnext\n"), nil)
+		assert.Equal(t, []string{"[WARN] " + long, "[INFO] next"}, lg.Lines())
+	})
+
+	t.Run("too long line", func(t *testing.T) {
+		lg := &logsCollector{}
+		r := strings.NewReader("first\n" + strings.Repeat("a", maxLogLine+1) + "\nnext\n")
+		(&Process{ProcessParams: ProcessParams{Logger: lg.Logger()}}).forward(r, nil)
+
+		lines := lg.Lines()
+		require.Len(t, lines, 2)
+		assert.Equal(t, "[INFO] first", lines[0])
+		assert.Contains(t, lines[1], "[WARN] stopped forwarding output of the plugin")
+		assert.Zero(t, r.Len(), "output must be drained")
+	})
+}
+
+func findProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	// signal 0 checks the existence of the process
+	return p.Signal(syscall.Signal(0))
+}
+
+type logsCollector struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (c *logsCollector) Logger() logx.Logger {
+	return &logx.LoggerMock{
+		PrintfFunc: func(s string, args ...interface{}) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.lines = append(c.lines, fmt.Sprintf(s, args...))
+		},
+	}
+}
+
+func (c *logsCollector) Lines() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.lines...)
+}
@@ -39,12 +39,28 @@ type Interface interface {
 	Listen(ctx context.Context, h Handler) error
 }
 
+// Describer is implemented by trackers, which are able to report
+// their capabilities, e.g. plugins, that describe themselves.
+type Describer interface {
+	// Description returns the capabilities of the tracker,
+	// false if they are unknown.
+	Description() (lib.Description, bool)
+}
+
 // Request describes a requests to tracker's action.
 type Request struct {
 	// TaskID in the target tracker, might be empty if the request is for creation
 	TaskID string
 	Method string
 	Vars   lib.Vars
+
+	// context of the call: the update, that triggered the job,
+	// the ticket at the moment of the call and the run of the job
+	Update      store.Update
+	Ticket      store.Ticket
+	JobName     string
+	TriggerName string
+	RunID       string
 }
 
 // Response describes possible return values of the Interface.Call
@@ -56,6 +72,9 @@ type Response struct {
 type SubscribeReq struct {
 	Vars       lib.Vars
 	WebhookURL string
+	// Subscription, which receives the updates, trackers, that deliver
+	// updates not via webhooks, must put it to the context of the update
+	Subscription store.Subscription
 }
 
 // SubscribeResp describes response from tracker on subscription request.
@@ -0,0 +1,121 @@
+// Package trackertest provides a conformance test suite, which any
+// implementation of tracker.Interface must pass.
+package trackertest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/app/store"
+	"github.com/cappuccinotm/dastracker/app/tracker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Suite describes the tracker under test along with the fixtures, which
+// it must handle. The tracker is expected to be connected to a fake
+// remote, which accepts the fixtures.
+type Suite struct {
+	// New makes a fresh instance of the tracker under test.
+	New func(t *testing.T) tracker.Interface
+	// Subscribe is a subscription request, which must be accepted by the tracker.
+	Subscribe tracker.SubscribeReq
+	// Webhook makes a request, sent by the remote tracker about the task update.
+	Webhook func(t *testing.T) *http.Request
+	// Update is the update, which the tracker must pass to the handler
+	// in response to the Webhook.
+	Update store.Update
+}
+
+// Run runs the conformance suite over the tracker.
+func Run(t *testing.T, s Suite) {
+	tbl := []struct {
+		name string
+		fn   func(t *testing.T, s Suite)
+	}{
+		{name: "name", fn: testName},
+		{name: "listen until canceled", fn: testListen},
+		{name: "subscribe and unsubscribe", fn: testSubscribe},
+		{name: "webhook", fn: testWebhook},
+		{name: "webhook without listener", fn: testWebhookNoListener},
+	}
+
+	for _, tt := range tbl {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) { tt.fn(t, s) })
+	}
+}
+
+func testName(t *testing.T, s Suite) {
+	assert.NotEmpty(t, s.New(t).Name())
+}
+
+func testListen(t *testing.T, s Suite) {
+	svc := s.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- svc.Listen(ctx, tracker.HandlerFunc(func(context.Context, store.Update) {})) }()
+
+	cancel()
+
+	select {
+	case err := <-errCh:
+		assert.Error(t, err, "listener must report the reason of stop")
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener didn't stop after the context cancellation")
+	}
+}
+
+func testSubscribe(t *testing.T, s Suite) {
+	svc := s.New(t)
+
+	resp, err := svc.Subscribe(context.Background(), s.Subscribe)
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.TrackerRef)
+
+	err = svc.Unsubscribe(context.Background(), tracker.UnsubscribeReq{TrackerRef: resp.TrackerRef})
+	require.NoError(t, err)
+}
+
+func testWebhook(t *testing.T, s Suite) {
+	svc := s.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mu := &sync.Mutex{}
+	var updates []store.Update
+
+	go func() {
+		_ = svc.Listen(ctx, tracker.HandlerFunc(func(_ context.Context, upd store.Update) {
+			mu.Lock()
+			defer mu.Unlock()
+			updates = append(updates, upd)
+		}))
+	}()
+
+	// listener might not be set up yet, so the webhook is retried until handled
+	require.Eventually(t, func() bool {
+		rec := httptest.NewRecorder()
+		svc.HandleWebhook(rec, s.Webhook(t))
+		assert.Less(t, rec.Code, http.StatusBadRequest)
+
+		mu.Lock()
+		defer mu.Unlock()
+		return len(updates) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, s.Update, updates[0])
+}
+
+func testWebhookNoListener(t *testing.T, s Suite) {
+	svc := s.New(t)
+	assert.NotPanics(t, func() { svc.HandleWebhook(httptest.NewRecorder(), s.Webhook(t)) })
+}
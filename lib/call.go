@@ -0,0 +1,78 @@
+package lib
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Call contains the metadata of the call, common for all requests to the plugin.
+type Call struct {
+	// ID of the call, used to cancel it, might be empty for the clients,
+	// which don't support cancellation
+	ID string `json:"call_id,omitempty"`
+	// Deadline of the call, nil if there is no deadline
+	Deadline *time.Time `json:"deadline,omitempty"`
+
+	ctx context.Context
+}
+
+// NewCall makes the metadata of the call with the given ID
+// and the deadline of the context.
+func NewCall(ctx context.Context, id string) Call {
+	res := Call{ID: id}
+	if deadline, ok := ctx.Deadline(); ok {
+		res.Deadline = &deadline
+	}
+	return res
+}
+
+// Context returns the context of the call, which is done, when the deadline
+// of the call is exceeded, the call is canceled by dastracker or the plugin
+// is stopped.
+func (c Call) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+func (c *Call) call() *Call { return c }
+
+// caller is implemented by requests with the metadata of the call.
+type caller interface{ call() *Call }
+
+// CancelReq describes parameters of the cancellation of the call.
+type CancelReq struct {
+	ID string `json:"call_id"`
+}
+
+// calls keeps the cancel functions of the running calls by their IDs.
+type calls struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+func (c *calls) add(id string, cancel context.CancelFunc) {
+	if id == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancels == nil {
+		c.cancels = map[string]context.CancelFunc{}
+	}
+	c.cancels[id] = cancel
+}
+
+// cancel cancels the call, returns false if the call is not running.
+func (c *calls) cancel(id string) bool {
+	c.mu.Lock()
+	cancel, ok := c.cancels[id]
+	delete(c.cancels, id)
+	c.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
@@ -0,0 +1,141 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ProtocolVersion is the version of the protocol between dastracker and
+// plugins. It is increased on each incompatible change of the protocol.
+const ProtocolVersion = 1
+
+// VarType describes the type of the variable's value.
+type VarType string
+
+// Possible types of the variables.
+const (
+	TypeAny    VarType = ""
+	TypeString VarType = "string"
+	TypeNumber VarType = "number"
+	TypeBool   VarType = "bool"
+	TypeList   VarType = "list"
+	TypeMap    VarType = "map"
+)
+
+// TypeOf returns the type of the variable's value.
+func TypeOf(val interface{}) VarType {
+	switch val.(type) {
+	case string:
+		return TypeString
+	case json.Number, float64, int:
+		return TypeNumber
+	case bool:
+		return TypeBool
+	case []interface{}:
+		return TypeList
+	case map[string]interface{}:
+		return TypeMap
+	default:
+		return TypeAny
+	}
+}
+
+// Description describes the plugin and its capabilities. It is returned
+// by the plugin.Describe method, served by Plugin automatically.
+type Description struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	Name            string   `json:"name"`
+	Version         string   `json:"version"`
+	Methods         []Method `json:"methods"`
+	Subscriptions   bool     `json:"subscriptions"` // whether Subscribe and Unsubscribe are supported
+	Webhooks        bool     `json:"webhooks"`      // whether ParseWebhook is supported
+	Push            bool     `json:"push"`          // whether plugin.Updates is served
+}
+
+// Method returns the description of the method with the given name.
+func (d Description) Method(name string) (Method, bool) {
+	for _, m := range d.Methods {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Method{}, false
+}
+
+// MethodNames returns the names of the methods of the plugin.
+func (d Description) MethodNames() []string {
+	res := make([]string, 0, len(d.Methods))
+	for _, m := range d.Methods {
+		res = append(res, m.Name)
+	}
+	return res
+}
+
+// Method describes a single method of the plugin.
+type Method struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Vars        []Var  `json:"vars,omitempty"`
+}
+
+// Var describes a variable, accepted by the method.
+type Var struct {
+	Name        string  `json:"name"`
+	Type        VarType `json:"type,omitempty"` // any type, if empty
+	Required    bool    `json:"required,omitempty"`
+	Description string  `json:"description,omitempty"`
+}
+
+// describe makes the description of the receiver: methods are taken from
+// the receiver's method set, their schemas - from the specs provided by the
+// plugin's author.
+func (p *Plugin) describe(rcvr interface{}) (Description, error) {
+	res := Description{ProtocolVersion: ProtocolVersion, Name: p.Name, Version: p.Version, Push: p.PushUpdates}
+
+	_, res.Subscriptions = rcvr.(SubscriptionSupporter)
+	_, res.Webhooks = rcvr.(WebhookParser)
+
+	specs := map[string]Method{}
+	for _, spec := range p.Methods {
+		specs[spec.Name] = spec
+	}
+
+	typ := reflect.TypeOf(rcvr)
+	for i := 0; i < typ.NumMethod(); i++ {
+		m := typ.Method(i)
+		if !isRPCMethod(m) || m.Name == "Subscribe" || m.Name == "Unsubscribe" || m.Name == "ParseWebhook" ||
+			builtinMethods[m.Name] {
+			continue
+		}
+		spec, ok := specs[m.Name]
+		if !ok {
+			spec = Method{Name: m.Name}
+		}
+		delete(specs, m.Name)
+		res.Methods = append(res.Methods, spec)
+	}
+
+	if len(specs) > 0 {
+		names := make([]string, 0, len(specs))
+		for name := range specs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return Description{}, fmt.Errorf("methods %v are described, but not implemented", names)
+	}
+
+	sort.Slice(res.Methods, func(i, j int) bool { return res.Methods[i].Name < res.Methods[j].Name })
+	return res, nil
+}
+
+var typeOfError = reflect.TypeOf((*error)(nil)).Elem()
+
+// isRPCMethod checks that the method might be served by net/rpc,
+// i.e. it looks like "func (t T) Method(args A, reply *R) error".
+func isRPCMethod(m reflect.Method) bool {
+	mt := m.Type
+	return m.PkgPath == "" && mt.NumIn() == 3 && mt.In(2).Kind() == reflect.Ptr &&
+		mt.NumOut() == 1 && mt.Out(0) == typeOfError
+}
@@ -0,0 +1,90 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrorCode describes the kind of the error, so that
+// dastracker knows how to handle it.
+type ErrorCode string
+
+// Possible codes of the errors.
+const (
+	ErrNotFound        ErrorCode = "not_found"        // the task or the subscription doesn't exist
+	ErrInvalidArgument ErrorCode = "invalid_argument" // variables of the request are invalid, retry is pointless
+	ErrUnavailable     ErrorCode = "unavailable"      // the tracker is temporarily unavailable, call might be retried
+	ErrRateLimited     ErrorCode = "rate_limited"     // the tracker limits the calls, call might be retried later
+	ErrConflict        ErrorCode = "conflict"         // the task has been changed concurrently
+)
+
+var errorCodes = []ErrorCode{ErrNotFound, ErrInvalidArgument, ErrUnavailable, ErrRateLimited, ErrConflict}
+
+// Error is the error of the plugin's method with the code. net/rpc passes
+// errors as strings, thus the code is encoded to the message, as
+// "code: message" or "code (retry after 30s): message". The error might
+// be wrapped, as long as the encoded error is in the end of the message.
+type Error struct {
+	Code       ErrorCode
+	Message    string
+	RetryAfter time.Duration // optional, for unavailable and rate_limited errors
+}
+
+// Errorf makes new Error with the given code and formatted message.
+func Errorf(code ErrorCode, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Error returns the encoded error.
+func (e *Error) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s (retry after %s): %s", e.Code, e.RetryAfter, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// ParseError extracts the Error from the message, received from the
+// plugin. Returns false if the message doesn't contain the encoded error.
+func ParseError(msg string) (*Error, bool) {
+	// the error might be wrapped, thus each segment of the message is checked
+	for start := 0; start < len(msg); {
+		if e, ok := parseError(msg[start:]); ok {
+			return e, true
+		}
+		idx := strings.Index(msg[start:], ": ")
+		if idx < 0 {
+			break
+		}
+		start += idx + 2
+	}
+	return nil, false
+}
+
+func parseError(msg string) (*Error, bool) {
+	for _, code := range errorCodes {
+		rest := strings.TrimPrefix(msg, string(code))
+		if len(rest) == len(msg) {
+			continue
+		}
+
+		if strings.HasPrefix(rest, ": ") {
+			return &Error{Code: code, Message: rest[2:]}, true
+		}
+
+		if !strings.HasPrefix(rest, " (retry after ") {
+			continue
+		}
+		rest = strings.TrimPrefix(rest, " (retry after ")
+		idx := strings.Index(rest, "): ")
+		if idx < 0 {
+			continue
+		}
+		retryAfter, err := time.ParseDuration(rest[:idx])
+		if err != nil {
+			continue
+		}
+		return &Error{Code: code, Message: rest[idx+3:], RetryAfter: retryAfter}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,68 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseError(t *testing.T) {
+	tbl := []struct {
+		name string
+		err  error
+		want *Error
+	}{
+		{
+			name: "plain",
+			err:  Errorf(ErrNotFound, "issue %d not found", 42),
+			want: &Error{Code: ErrNotFound, Message: "issue 42 not found"},
+		},
+		{
+			name: "with retry after",
+			err:  &Error{Code: ErrRateLimited, Message: "too many requests", RetryAfter: 30 * time.Second},
+			want: &Error{Code: ErrRateLimited, Message: "too many requests", RetryAfter: 30 * time.Second},
+		},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("create issue: %w", Errorf(ErrInvalidArgument, "title: must not be empty")),
+			want: &Error{Code: ErrInvalidArgument, Message: "title: must not be empty"},
+		},
+		{
+			name: "untyped",
+			err:  errors.New("create issue: something went wrong"),
+		},
+		{
+			name: "unknown code",
+			err:  errors.New("forbidden: access denied"),
+		},
+		{
+			name: "invalid retry after",
+			err:  errors.New("unavailable (retry after soon): try later"),
+		},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseError(tt.err.Error())
+			assert.Equal(t, tt.want != nil, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRPCError_PluginError(t *testing.T) {
+	e, ok := (&RPCError{Code: CodeServerError, Message: "rate limited",
+		Data: []byte(`{"code":"rate_limited","retry_after":5}`)}).PluginError()
+	assert.True(t, ok)
+	assert.Equal(t, &Error{Code: ErrRateLimited, Message: "rate limited", RetryAfter: 5 * time.Second}, e)
+
+	e, ok = (&RPCError{Code: CodeServerError, Message: "conflict: task is changed"}).PluginError()
+	assert.True(t, ok)
+	assert.Equal(t, &Error{Code: ErrConflict, Message: "task is changed"}, e)
+
+	_, ok = (&RPCError{Code: CodeServerError, Message: "failure"}).PluginError()
+	assert.False(t, ok)
+}
@@ -0,0 +1,233 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/rpc"
+	"strings"
+	"time"
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeServerError    = -32000 // error, returned by the method of the plugin
+	CodeUnauthorized   = -32001 // token is missing or invalid
+)
+
+// RPCRequest is a JSON-RPC 2.0 request, sent over HTTP transport.
+type RPCRequest struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// RPCResponse is a JSON-RPC 2.0 response, sent over HTTP transport.
+type RPCResponse struct {
+	Version string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// RPCError is a JSON-RPC 2.0 error.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Error returns the message of the error.
+func (e *RPCError) Error() string { return e.Message }
+
+// PluginError returns the Error, described either by the data of the
+// error, or encoded in its message.
+func (e *RPCError) PluginError() (*Error, bool) {
+	var data ErrorData
+	if len(e.Data) > 0 && json.Unmarshal(e.Data, &data) == nil && data.Code != "" {
+		// the message might be already encoded by the Go plugin
+		if parsed, ok := ParseError(e.Message); ok && parsed.Code == data.Code {
+			return parsed, true
+		}
+		return &Error{Code: data.Code, Message: e.Message, RetryAfter: time.Duration(data.RetryAfter) * time.Second}, true
+	}
+	return ParseError(e.Message)
+}
+
+// ErrorData is the data of RPCError, which describes the Error of the plugin.
+type ErrorData struct {
+	Code       ErrorCode `json:"code"`
+	RetryAfter int       `json:"retry_after,omitempty"` // in seconds
+}
+
+// Handler returns the HTTP handler, which serves JSON-RPC 2.0 requests
+// to the methods of the receiver, so the plugin might be mounted to the
+// existing HTTP server. Requests must be sent with POST method, the token,
+// if set, must be provided in "Authorization: Bearer <token>" header.
+func (p *Plugin) Handler(rcvr interface{}) (http.Handler, error) {
+	srv, calls, err := p.register(rcvr)
+	if err != nil {
+		return nil, err
+	}
+	return p.httpHandler(srv, calls), nil
+}
+
+func (p *Plugin) httpHandler(srv *rpc.Server, calls *calls) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		if p.Token != "" && !tokenValid("Bearer "+p.Token, r.Header.Get("Authorization")) {
+			_ = writeRPCResponse(w, http.StatusUnauthorized, RPCResponse{
+				Error: &RPCError{Code: CodeUnauthorized, Message: "invalid token"},
+			})
+			return
+		}
+
+		var req RPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			_ = writeRPCResponse(w, http.StatusBadRequest, RPCResponse{
+				Error: &RPCError{Code: CodeParseError, Message: fmt.Sprintf("parse request: %v", err)},
+			})
+			return
+		}
+
+		if req.Version != "2.0" || req.Method == "" {
+			_ = writeRPCResponse(w, http.StatusBadRequest, RPCResponse{
+				ID:    req.ID,
+				Error: &RPCError{Code: CodeInvalidRequest, Message: "invalid request"},
+			})
+			return
+		}
+
+		codec := &httpCodec{req: req, w: w}
+		// the connection is authenticated by the header, if needed
+		err := srv.ServeRequest(&serverCodec{ServerCodec: codec, ctx: r.Context(), calls: calls, authenticated: true})
+		if err != nil && !codec.written {
+			_ = writeRPCResponse(w, http.StatusBadRequest, RPCResponse{
+				ID:    req.ID,
+				Error: &RPCError{Code: CodeInvalidRequest, Message: err.Error()},
+			})
+		}
+	})
+}
+
+// httpCodec is a server codec for a single JSON-RPC 2.0 request over HTTP.
+type httpCodec struct {
+	req     RPCRequest
+	w       http.ResponseWriter
+	invalid bool // whether the params of the request can't be decoded
+	written bool
+}
+
+// ReadRequestHeader sets the name of the method of the request.
+func (c *httpCodec) ReadRequestHeader(r *rpc.Request) error {
+	r.ServiceMethod = c.req.Method
+	r.Seq = 0
+	return nil
+}
+
+// ReadRequestBody decodes the parameters of the request, which might be
+// either an object or an array with a single object.
+func (c *httpCodec) ReadRequestBody(body interface{}) error {
+	if body == nil {
+		return nil
+	}
+
+	params := bytes.TrimSpace(c.req.Params)
+	if len(params) == 0 {
+		return nil
+	}
+	if params[0] == '[' {
+		var arr []json.RawMessage
+		if err := json.Unmarshal(params, &arr); err != nil {
+			c.invalid = true
+			return fmt.Errorf("decode params: %w", err)
+		}
+		if len(arr) != 1 {
+			c.invalid = true
+			return fmt.Errorf("expected a single parameter, got %d", len(arr))
+		}
+		params = arr[0]
+	}
+
+	if err := json.Unmarshal(params, body); err != nil {
+		c.invalid = true
+		return fmt.Errorf("decode params: %w", err)
+	}
+	return nil
+}
+
+// WriteResponse writes the result or the error of the call.
+func (c *httpCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	c.written = true
+
+	if r.Error != "" {
+		code := CodeServerError
+		switch {
+		case c.invalid:
+			code = CodeInvalidParams
+		case strings.HasPrefix(r.Error, "rpc: can't find"):
+			code = CodeMethodNotFound
+		}
+		rpcErr := &RPCError{Code: code, Message: r.Error}
+		if e, ok := ParseError(r.Error); ok {
+			// error is not expected to fail to be marshaled
+			rpcErr.Data, _ = json.Marshal(ErrorData{Code: e.Code, RetryAfter: int(e.RetryAfter / time.Second)})
+		}
+		return writeRPCResponse(c.w, http.StatusOK, RPCResponse{ID: c.req.ID, Error: rpcErr})
+	}
+
+	res, err := json.Marshal(body)
+	if err != nil {
+		return writeRPCResponse(c.w, http.StatusOK, RPCResponse{
+			ID:    c.req.ID,
+			Error: &RPCError{Code: CodeInternalError, Message: fmt.Sprintf("encode result: %v", err)},
+		})
+	}
+
+	return writeRPCResponse(c.w, http.StatusOK, RPCResponse{ID: c.req.ID, Result: res})
+}
+
+// Close does nothing, as the response is written by the HTTP server.
+func (c *httpCodec) Close() error { return nil }
+
+func writeRPCResponse(w http.ResponseWriter, status int, resp RPCResponse) error {
+	resp.Version = "2.0"
+	if resp.ID == nil {
+		resp.ID = json.RawMessage("null")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(resp)
+}
+
+// serveHTTP serves JSON-RPC 2.0 requests over HTTP on the listener.
+func (p *Plugin) serveHTTP(ctx context.Context, listener net.Listener, srv *rpc.Server, calls *calls) error {
+	httpSrv := &http.Server{Handler: p.httpHandler(srv, calls), ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		if err := httpSrv.Close(); err != nil {
+			p.Logger.Printf("[WARN] can't close plugin http server: %v", err)
+		}
+	}()
+
+	if err := httpSrv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serve http failed: %v", err)
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,156 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlugin_Handler(t *testing.T) {
+	h, err := (&Plugin{Name: "test", Token: "secret"}).Handler(&testPlugin{})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	tbl := []struct {
+		name       string
+		token      string
+		body       string
+		wantStatus int
+		wantResult string
+		wantErr    *RPCError
+	}{
+		{
+			name:       "params as object",
+			token:      "secret",
+			body:       `{"jsonrpc":"2.0","method":"plugin.Create","params":{"vars":{"title":"title"}},"id":1}`,
+			wantStatus: http.StatusOK,
+			wantResult: `{"task":{"id":"","url":"","title":"title","body":"","fields":null}}`,
+		},
+		{
+			name:       "params as array",
+			token:      "secret",
+			body:       `{"jsonrpc":"2.0","method":"plugin.Create","params":[{"vars":{"title":"title"}}],"id":"2"}`,
+			wantStatus: http.StatusOK,
+			wantResult: `{"task":{"id":"","url":"","title":"title","body":"","fields":null}}`,
+		},
+		{
+			name:       "builtin method",
+			token:      "secret",
+			body:       `{"jsonrpc":"2.0","method":"plugin.Describe","id":3}`,
+			wantStatus: http.StatusOK,
+			wantResult: `{"protocol_version":1,"name":"test","version":"","methods":[{"name":"Create"},` +
+				`{"name":"Update"},{"name":"Wait"}],"subscriptions":true,"webhooks":true,"push":false}`,
+		},
+		{
+			name:       "unknown method",
+			token:      "secret",
+			body:       `{"jsonrpc":"2.0","method":"plugin.Delete","params":{},"id":4}`,
+			wantStatus: http.StatusOK,
+			wantErr:    &RPCError{Code: CodeMethodNotFound, Message: "rpc: can't find method plugin.Delete"},
+		},
+		{
+			name:       "invalid params",
+			token:      "secret",
+			body:       `{"jsonrpc":"2.0","method":"plugin.Create","params":[1,2],"id":5}`,
+			wantStatus: http.StatusOK,
+			wantErr:    &RPCError{Code: CodeInvalidParams, Message: "expected a single parameter, got 2"},
+		},
+		{
+			name:       "invalid version",
+			token:      "secret",
+			body:       `{"method":"plugin.Create","id":6}`,
+			wantStatus: http.StatusBadRequest,
+			wantErr:    &RPCError{Code: CodeInvalidRequest, Message: "invalid request"},
+		},
+		{
+			name:       "invalid token",
+			token:      "wrong",
+			body:       `{"jsonrpc":"2.0","method":"plugin.Describe","id":7}`,
+			wantStatus: http.StatusUnauthorized,
+			wantErr:    &RPCError{Code: CodeUnauthorized, Message: "invalid token"},
+		},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+
+			var rpcResp RPCResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&rpcResp))
+			assert.Equal(t, "2.0", rpcResp.Version)
+			assert.Equal(t, tt.wantErr, rpcResp.Error)
+			if tt.wantResult != "" {
+				assert.JSONEq(t, tt.wantResult, string(rpcResp.Result))
+			}
+		})
+	}
+
+	t.Run("not a POST request", func(t *testing.T) {
+		resp, err := http.Get(ts.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+}
+
+func TestPlugin_Serve_HTTP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- (&Plugin{Transport: TransportHTTP}).Serve(ctx, l, &testPlugin{}) }()
+
+	resp, err := http.Post("http://"+l.Addr().String(), "application/json",
+		strings.NewReader(`{"jsonrpc":"2.0","method":"plugin.Create","params":{"vars":{"title":"title"}},"id":1}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var rpcResp RPCResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&rpcResp))
+	assert.Nil(t, rpcResp.Error)
+	assert.JSONEq(t, "1", string(rpcResp.ID))
+	assert.JSONEq(t, `{"task":{"id":"","url":"","title":"title","body":"","fields":null}}`, string(rpcResp.Result))
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
+
+func TestPlugin_Handler_PluginError(t *testing.T) {
+	h, err := (&Plugin{}).Handler(&failPlugin{})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/",
+		strings.NewReader(`{"jsonrpc":"2.0","method":"plugin.Fail","params":{},"id":1}`)))
+
+	var rpcResp RPCResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&rpcResp))
+	require.NotNil(t, rpcResp.Error)
+	assert.Equal(t, CodeServerError, rpcResp.Error.Code)
+	assert.Equal(t, "rate_limited (retry after 1m0s): slow down", rpcResp.Error.Message)
+	assert.JSONEq(t, `{"code":"rate_limited","retry_after":60}`, string(rpcResp.Error.Data))
+}
+
+type failPlugin struct{}
+
+func (failPlugin) Fail(Request, *Response) error {
+	return &Error{Code: ErrRateLimited, Message: "slow down", RetryAfter: time.Minute}
+}
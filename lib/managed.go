@@ -0,0 +1,75 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables, set by dastracker for the plugins, launched by it.
+const (
+	EnvManaged = "DASTRACKER_PLUGIN"         // "1", if the plugin is launched by dastracker
+	EnvNetwork = "DASTRACKER_PLUGIN_NETWORK" // network to listen, "tcp" or "unix"
+	EnvSocket  = "DASTRACKER_PLUGIN_SOCKET"  // location of the unix socket to listen
+)
+
+// handshakePrefix starts the line, in which the plugin reports its address.
+const handshakePrefix = "DASTRACKER_PLUGIN"
+
+// Handshake returns the line, which the managed plugin writes to stdout
+// to report the address it listens, e.g.
+// "DASTRACKER_PLUGIN|1|tcp|127.0.0.1:41234".
+func Handshake(network, addr string) string {
+	return fmt.Sprintf("%s|%d|%s|%s", handshakePrefix, ProtocolVersion, network, addr)
+}
+
+// ParseHandshake parses the handshake line, written by the managed plugin.
+// Returns false if the line is not a handshake.
+func ParseHandshake(line string) (network, addr string, ok bool, err error) {
+	parts := strings.SplitN(strings.TrimSpace(line), "|", 4)
+	if len(parts) != 4 || parts[0] != handshakePrefix {
+		return "", "", false, nil
+	}
+
+	ver, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", "", true, fmt.Errorf("parse protocol version %q: %w", parts[1], err)
+	}
+	if ver > ProtocolVersion {
+		return "", "", true, fmt.Errorf("plugin requires protocol version %d, supported up to %d",
+			ver, ProtocolVersion)
+	}
+
+	if parts[2] != "tcp" && parts[2] != "unix" {
+		return "", "", true, fmt.Errorf("unsupported network %q", parts[2])
+	}
+
+	return parts[2], parts[3], true, nil
+}
+
+// managedListener makes a listener for the plugin, launched by dastracker,
+// on the network and the socket requested by dastracker, or on the random
+// local port. Returns false if the plugin is not launched by dastracker.
+func managedListener() (net.Listener, bool, error) {
+	if os.Getenv(EnvManaged) != "1" {
+		return nil, false, nil
+	}
+
+	switch network := os.Getenv(EnvNetwork); network {
+	case "", "tcp":
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		return l, true, err
+	case "unix":
+		sock := os.Getenv(EnvSocket)
+		if sock == "" {
+			return nil, true, errors.New("location of the socket is not set")
+		}
+		l, err := net.Listen("unix", sock)
+		return l, true, err
+	default:
+		return nil, true, fmt.Errorf("unsupported network %q", network)
+	}
+}
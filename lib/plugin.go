@@ -2,43 +2,135 @@ package lib
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net"
 	"net/rpc"
 	"net/rpc/jsonrpc"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Transport describes the protocol of the calls between dastracker and the plugin.
+type Transport string
+
+// Supported transports.
+const (
+	// TransportRPC is JSON-RPC 1.0 over raw connections, as net/rpc/jsonrpc does.
+	TransportRPC Transport = "rpc"
+	// TransportHTTP is JSON-RPC 2.0 over HTTP POST requests.
+	TransportHTTP Transport = "http"
 )
 
 // Plugin provides methods to start listener and register RPC methods.
 type Plugin struct {
-	Address string
+	Address string // "host:port" or "unix:///path/to/socket"
 	Logger  Logger // no-op by default
+
+	// TLS configuration, connections are not encrypted if nil,
+	// see ServerTLS to make it
+	TLS *tls.Config
+	// Token, which dastracker must present to make calls, not checked if empty
+	Token string
+	// Transport of the calls, TransportRPC by default
+	Transport Transport
+
+	// Name and Version of the plugin, reported to dastracker
+	Name    string
+	Version string
+
+	// Methods describe the variables of the methods, the methods themselves
+	// are taken from the receiver, so only methods with variables might be
+	// listed here
+	Methods []Method
+
+	// PushUpdates must be set, if the plugin pushes updates with Push,
+	// dastracker polls for them only if it's set
+	PushUpdates bool
+
+	updates updates // pushed updates, waiting to be received by dastracker
 }
 
 // Listen starts RPC server and listens for incoming connections.
-func (p *Plugin) Listen(ctx context.Context, rcvr interface{}) (err error) {
+// If the plugin is launched by dastracker, Address is ignored, the plugin
+// listens on the address, requested by dastracker, and reports it to stdout.
+func (p *Plugin) Listen(ctx context.Context, rcvr interface{}) error {
+	listener, managed, err := managedListener()
+	switch {
+	case err != nil:
+		return fmt.Errorf("can't listen for dastracker: %v", err)
+	case managed:
+		addr := listener.Addr()
+		if _, err = fmt.Fprintln(os.Stdout, Handshake(addr.Network(), addr.String())); err != nil {
+			_ = listener.Close()
+			return fmt.Errorf("can't write handshake: %v", err)
+		}
+	default:
+		if listener, err = net.Listen(SplitAddress(p.Address)); err != nil {
+			return fmt.Errorf("can't listen on %s: %v", p.Address, err)
+		}
+	}
+
+	return p.Serve(ctx, listener, rcvr)
+}
+
+// Serve registers RPC methods of the receiver and serves incoming
+// connections on the given listener. Besides the receiver's methods,
+// the plugin.Describe method is served to report plugin's capabilities
+// and the plugin.Cancel method to cancel the running calls.
+func (p *Plugin) Serve(ctx context.Context, listener net.Listener, rcvr interface{}) error {
 	ctxCancel, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	if p.Logger == nil {
-		p.Logger = log.New(ioutil.Discard, "", 0)
+	srv, calls, err := p.register(rcvr)
+	if err != nil {
+		_ = listener.Close()
+		return err
 	}
-
-	if err = rpc.RegisterName("plugin", rcvr); err != nil {
-		return fmt.Errorf("can't register plugin: %v", err)
+	if p.TLS != nil {
+		listener = tls.NewListener(listener, p.TLS)
 	}
-	p.Logger.Printf("[INFO] register rpc on address %s", p.Address)
 
-	return p.listen(ctxCancel)
+	switch p.Transport {
+	case "", TransportRPC:
+		p.Logger.Printf("[INFO] register rpc on address %s", listener.Addr())
+		return p.serve(ctxCancel, listener, srv, calls)
+	case TransportHTTP:
+		p.Logger.Printf("[INFO] register http rpc on address %s", listener.Addr())
+		return p.serveHTTP(ctxCancel, listener, srv, calls)
+	default:
+		_ = listener.Close()
+		return fmt.Errorf("unsupported transport %q", p.Transport)
+	}
 }
 
-func (p *Plugin) listen(ctx context.Context) error {
-	listener, err := net.Listen("tcp", p.Address)
+// register makes the RPC server with the receiver's and builtin methods.
+func (p *Plugin) register(rcvr interface{}) (*rpc.Server, *calls, error) {
+	if p.Logger == nil {
+		p.Logger = log.New(ioutil.Discard, "", 0)
+	}
+
+	desc, err := p.describe(rcvr)
 	if err != nil {
-		return fmt.Errorf("can't listen on %s: %v", p.Address, err)
+		return nil, nil, fmt.Errorf("can't describe plugin: %v", err)
 	}
 
+	srv := rpc.NewServer()
+	if err = srv.RegisterName("plugin", rcvr); err != nil {
+		return nil, nil, fmt.Errorf("can't register plugin: %v", err)
+	}
+	calls := &calls{}
+	if err = srv.RegisterName(builtinService, &builtin{desc: desc, calls: calls, updates: p.updates.queue()}); err != nil {
+		return nil, nil, fmt.Errorf("can't register builtin methods: %v", err)
+	}
+	return srv, calls, nil
+}
+
+func (p *Plugin) serve(ctx context.Context, listener net.Listener, srv *rpc.Server, calls *calls) error {
 	go func() {
 		<-ctx.Done()
 		if err := listener.Close(); err != nil {
@@ -58,12 +150,155 @@ func (p *Plugin) listen(ctx context.Context) error {
 		}
 		go func() {
 			p.Logger.Printf("[INFO] accepted connection from %s", conn.RemoteAddr().String())
-			jsonrpc.ServeConn(conn)
+			srv.ServeCodec(&serverCodec{
+				ServerCodec: jsonrpc.NewServerCodec(conn),
+				ctx:         ctx,
+				calls:       calls,
+				token:       p.Token,
+			})
 			p.Logger.Printf("[INFO] stopped to serve connection from %s", conn.RemoteAddr().String())
 		}()
 	}
 }
 
+// builtinService is the name of the service, which serves the methods,
+// provided by the library. net/rpc doesn't allow to add methods to the
+// registered receiver, thus calls are routed to the separate service.
+const builtinService = "dastracker"
+
+// builtinMethods lists the methods, routed to the builtin service.
+var builtinMethods = map[string]bool{"Describe": true, "Cancel": true, "Authenticate": true, "Updates": true}
+
+// builtin serves the methods, provided by the library.
+type builtin struct {
+	desc    Description
+	calls   *calls
+	updates <-chan PushedUpdate
+}
+
+// Describe returns the description of the plugin.
+func (b *builtin) Describe(_ struct{}, resp *Description) error {
+	*resp = b.desc
+	return nil
+}
+
+// Authenticate authenticates the connection, the token is checked by the codec.
+func (b *builtin) Authenticate(req AuthReq, _ *struct{}) error {
+	if !req.valid {
+		return errors.New("invalid token")
+	}
+	return nil
+}
+
+// Unauthenticated rejects the calls over unauthenticated connections.
+func (b *builtin) Unauthenticated(_ interface{}, _ *struct{}) error {
+	return errors.New("connection is not authenticated")
+}
+
+// Cancel cancels the context of the running call.
+func (b *builtin) Cancel(req CancelReq, _ *struct{}) error {
+	b.calls.cancel(req.ID)
+	return nil
+}
+
+// serverCodec routes calls of the builtin methods to the builtin service,
+// checks the authentication of the connection and sets the contexts of
+// the calls.
+type serverCodec struct {
+	rpc.ServerCodec
+	ctx   context.Context // base context of the calls
+	calls *calls
+	token string
+
+	// authenticated is accessed only by the reading goroutine of the server
+	authenticated bool
+
+	seq     uint64 // sequence number of the request, which body is being read
+	mu      sync.Mutex
+	cancels map[uint64]context.CancelFunc // cancel functions of the contexts by sequence numbers
+}
+
+// ReadRequestHeader reads the header of the request and replaces
+// the name of the method, if the request is for the builtin method.
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) error {
+	if err := c.ServerCodec.ReadRequestHeader(r); err != nil {
+		return err
+	}
+	c.seq = r.Seq
+	if method := strings.TrimPrefix(r.ServiceMethod, "plugin."); builtinMethods[method] {
+		r.ServiceMethod = builtinService + "." + method
+	}
+	if c.token != "" && !c.authenticated && r.ServiceMethod != builtinService+".Authenticate" {
+		r.ServiceMethod = builtinService + ".Unauthenticated"
+	}
+	return nil
+}
+
+// ReadRequestBody reads the body of the request and, if the request
+// contains the metadata of the call, sets the context of the call.
+func (c *serverCodec) ReadRequestBody(body interface{}) error {
+	if err := c.ServerCodec.ReadRequestBody(body); err != nil {
+		return err
+	}
+
+	if auth, ok := body.(*AuthReq); ok {
+		auth.valid = c.token == "" || tokenValid(c.token, auth.Token)
+		c.authenticated = c.authenticated || auth.valid
+		return nil
+	}
+
+	req, ok := body.(caller)
+	if !ok {
+		return nil
+	}
+
+	call := req.call()
+	ctx, cancel := context.WithCancel(c.ctx)
+	if call.Deadline != nil {
+		ctx, cancel = context.WithDeadline(c.ctx, *call.Deadline)
+	}
+	call.ctx = ctx
+
+	c.calls.add(call.ID, cancel)
+	c.mu.Lock()
+	if c.cancels == nil {
+		c.cancels = map[uint64]context.CancelFunc{}
+	}
+	c.cancels[c.seq] = func() {
+		cancel()
+		c.calls.cancel(call.ID)
+	}
+	c.mu.Unlock()
+
+	return nil
+}
+
+// WriteResponse writes the response and releases the context of the call.
+func (c *serverCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	c.mu.Lock()
+	cancel, ok := c.cancels[r.Seq]
+	delete(c.cancels, r.Seq)
+	c.mu.Unlock()
+	if ok {
+		cancel()
+	}
+
+	return c.ServerCodec.WriteResponse(r, body)
+}
+
+// Close closes the connection and cancels the running calls,
+// as their responses can't be delivered anymore.
+func (c *serverCodec) Close() error {
+	c.mu.Lock()
+	for seq, cancel := range c.cancels {
+		cancel()
+		delete(c.cancels, seq)
+	}
+	c.mu.Unlock()
+
+	return c.ServerCodec.Close()
+}
+
 // SubscriptionSupporter provides methods to subscribe to events.
 // If plugin needs to support subscriptions, it should implement this interface.
 type SubscriptionSupporter interface {
@@ -71,6 +306,13 @@ type SubscriptionSupporter interface {
 	Unsubscribe(req UnsubscribeReq, _ *struct{}) error
 }
 
+// WebhookParser parses raw webhooks of the tracker. If plugin implements
+// this interface, webhooks are forwarded to it as is, otherwise they are
+// expected to contain a single Task.
+type WebhookParser interface {
+	ParseWebhook(req WebhookReq, resp *WebhookResp) error
+}
+
 // Logger is a logger interface.
 type Logger interface {
 	Printf(format string, v ...interface{})
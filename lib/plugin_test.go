@@ -0,0 +1,219 @@
+package lib
+
+import (
+	"context"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlugin_Serve(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		p := &Plugin{
+			Name:    "test",
+			Version: "v1.0.0",
+			Methods: []Method{{
+				Name:        "Create",
+				Description: "creates a task",
+				Vars:        []Var{{Name: "title", Type: TypeString, Required: true}},
+			}},
+		}
+		done <- p.Serve(ctx, l, &testPlugin{})
+	}()
+
+	cl, err := jsonrpc.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer cl.Close()
+
+	var desc Description
+	require.NoError(t, cl.Call("plugin.Describe", struct{}{}, &desc))
+	assert.Equal(t, Description{
+		ProtocolVersion: ProtocolVersion,
+		Name:            "test",
+		Version:         "v1.0.0",
+		Methods: []Method{
+			{
+				Name:        "Create",
+				Description: "creates a task",
+				Vars:        []Var{{Name: "title", Type: TypeString, Required: true}},
+			},
+			{Name: "Update"},
+			{Name: "Wait"},
+		},
+		Subscriptions: true,
+		Webhooks:      true,
+	}, desc)
+
+	var resp Response
+	require.NoError(t, cl.Call("plugin.Create", Request{Vars: Vars{"title": "title"}}, &resp))
+	assert.Equal(t, "title", resp.Task.Title)
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
+
+func TestPlugin_Serve_CallContext(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = (&Plugin{}).Serve(ctx, l, &testPlugin{}) }()
+
+	cl, err := jsonrpc.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer cl.Close()
+
+	t.Run("canceled", func(t *testing.T) {
+		call := cl.Go("plugin.Wait", Request{Call: Call{ID: "call-id"}}, &Response{}, nil)
+
+		// the call might be not received by the plugin yet, thus cancellation is repeated
+		require.Eventually(t, func() bool {
+			if err := cl.Call("plugin.Cancel", CancelReq{ID: "call-id"}, &struct{}{}); err != nil {
+				return false
+			}
+			select {
+			case <-call.Done:
+				return true
+			case <-time.After(10 * time.Millisecond):
+				return false
+			}
+		}, 5*time.Second, time.Millisecond)
+		require.Error(t, call.Error)
+		assert.Equal(t, context.Canceled.Error(), call.Error.Error())
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := cl.Call("plugin.Wait", Request{Call: NewCall(ctx, "")}, &Response{})
+		require.Error(t, err)
+		assert.Equal(t, context.DeadlineExceeded.Error(), err.Error())
+	})
+
+	t.Run("no metadata", func(t *testing.T) {
+		var resp Response
+		require.NoError(t, cl.Call("plugin.Create", Request{Vars: Vars{"title": "title"}}, &resp))
+		assert.Equal(t, "title", resp.Task.Title)
+	})
+}
+
+func TestPlugin_Listen_Token(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "plugin.sock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = (&Plugin{Address: "unix://" + sock, Token: "secret"}).Listen(ctx, &testPlugin{}) }()
+
+	var cl *rpc.Client
+	require.Eventually(t, func() bool {
+		var err error
+		cl, err = jsonrpc.Dial("unix", sock)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	defer cl.Close()
+
+	var desc Description
+	err := cl.Call("plugin.Describe", struct{}{}, &desc)
+	require.Error(t, err)
+	assert.Equal(t, "connection is not authenticated", err.Error())
+
+	err = cl.Call("plugin.Authenticate", AuthReq{Token: "wrong"}, &struct{}{})
+	require.Error(t, err)
+	assert.Equal(t, "invalid token", err.Error())
+
+	err = cl.Call("plugin.Create", Request{}, &Response{})
+	require.Error(t, err)
+	assert.Equal(t, "connection is not authenticated", err.Error())
+
+	require.NoError(t, cl.Call("plugin.Authenticate", AuthReq{Token: "secret"}, &struct{}{}))
+	require.NoError(t, cl.Call("plugin.Describe", struct{}{}, &desc))
+	require.NoError(t, cl.Call("plugin.Create", Request{}, &Response{}))
+}
+
+func TestSplitAddress(t *testing.T) {
+	tbl := []struct{ addr, network, address string }{
+		{"localhost:9000", "tcp", "localhost:9000"},
+		{"tcp://localhost:9000", "tcp", "localhost:9000"},
+		{"unix:///var/run/plugin.sock", "unix", "/var/run/plugin.sock"},
+	}
+	for _, tt := range tbl {
+		network, address := SplitAddress(tt.addr)
+		assert.Equal(t, tt.network, network, tt.addr)
+		assert.Equal(t, tt.address, address, tt.addr)
+	}
+}
+
+func TestPlugin_Serve_UndescribedMethod(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	p := &Plugin{Methods: []Method{{Name: "Delete"}}}
+	err = p.Serve(context.Background(), l, &testPlugin{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "methods [Delete] are described, but not implemented")
+}
+
+func TestTypeOf(t *testing.T) {
+	assert.Equal(t, TypeString, TypeOf("s"))
+	assert.Equal(t, TypeNumber, TypeOf(float64(1)))
+	assert.Equal(t, TypeBool, TypeOf(true))
+	assert.Equal(t, TypeList, TypeOf([]interface{}{}))
+	assert.Equal(t, TypeMap, TypeOf(map[string]interface{}{}))
+	assert.Equal(t, TypeAny, TypeOf(nil))
+}
+
+type testPlugin struct{}
+
+func (testPlugin) Subscribe(SubscribeReq, *SubscribeResp) error { return nil }
+func (testPlugin) Unsubscribe(UnsubscribeReq, *struct{}) error  { return nil }
+
+func (testPlugin) Create(req Request, resp *Response) error {
+	resp.Task.Title = req.Vars.Get("title")
+	return nil
+}
+
+func (testPlugin) Update(Request, *Response) error { return nil }
+
+func (testPlugin) ParseWebhook(WebhookReq, *WebhookResp) error { return nil }
+
+func (testPlugin) Wait(req Request, _ *Response) error {
+	<-req.Context().Done()
+	return req.Context().Err()
+}
+
+// helper isn't an RPC method and must not be described
+func (testPlugin) Helper() {}
+
+func TestParseHandshake(t *testing.T) {
+	network, addr, ok, err := ParseHandshake(Handshake("tcp", "127.0.0.1:1234") + "\n")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "tcp", network)
+	assert.Equal(t, "127.0.0.1:1234", addr)
+
+	_, _, ok, err = ParseHandshake("2022/01/01 00:00:00 [INFO] some log line")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, ok, err = ParseHandshake("DASTRACKER_PLUGIN|2|tcp|127.0.0.1:1234")
+	assert.True(t, ok)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "plugin requires protocol version 2")
+
+	_, _, _, err = ParseHandshake("DASTRACKER_PLUGIN|1|udp|127.0.0.1:1234")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unsupported network "udp"`)
+}
@@ -0,0 +1,96 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// PushedUpdate is the update of the task, pushed by the plugin
+// for the subscription.
+type PushedUpdate struct {
+	SubscriptionID string `json:"subscription_id"` // as received in SubscribeReq
+	Task           Task   `json:"task"`
+}
+
+// UpdatesReq describes parameters of the long poll for the pushed updates.
+type UpdatesReq struct {
+	Call
+	Timeout int `json:"timeout"` // maximal time to wait for updates in seconds, 30 by default
+	Limit   int `json:"limit"`   // maximal number of updates in the response, 100 by default
+}
+
+// UpdatesResp contains the updates, pushed by the plugin,
+// empty if no updates were pushed in time.
+type UpdatesResp struct {
+	Updates []PushedUpdate `json:"updates"`
+}
+
+// updatesBuffer is the number of pushed updates, that might wait
+// for dastracker to receive them.
+const updatesBuffer = 1000
+
+// updates is the queue of pushed updates.
+type updates struct {
+	once sync.Once
+	ch   chan PushedUpdate
+}
+
+func (u *updates) queue() chan PushedUpdate {
+	u.once.Do(func() { u.ch = make(chan PushedUpdate, updatesBuffer) })
+	return u.ch
+}
+
+// Push queues the update of the task for the subscription, dastracker
+// receives it with plugin.Updates call over the existing connection, so
+// the plugin doesn't need to reach dastracker's webhook server. Blocks,
+// if the queue is full, until the update is queued or the context is done.
+// Requires PushUpdates to be set.
+func (p *Plugin) Push(ctx context.Context, subscriptionID string, task Task) error {
+	if !p.PushUpdates {
+		return errors.New("pushing updates is not enabled, set PushUpdates of the plugin")
+	}
+
+	select {
+	case p.updates.queue() <- PushedUpdate{SubscriptionID: subscriptionID, Task: task}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Updates waits for the updates, pushed by the plugin, and returns them,
+// as soon as at least one update is available.
+func (b *builtin) Updates(req UpdatesReq, resp *UpdatesResp) error {
+	timeout, limit := time.Duration(req.Timeout)*time.Second, req.Limit
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	resp.Updates = []PushedUpdate{}
+	select {
+	case upd := <-b.updates:
+		resp.Updates = append(resp.Updates, upd)
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return nil
+	}
+
+	for len(resp.Updates) < limit {
+		select {
+		case upd := <-b.updates:
+			resp.Updates = append(resp.Updates, upd)
+		default:
+			return nil
+		}
+	}
+	return nil
+}
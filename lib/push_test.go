@@ -0,0 +1,77 @@
+package lib
+
+import (
+	"context"
+	"net"
+	"net/rpc/jsonrpc"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlugin_Push(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := &Plugin{PushUpdates: true}
+	go func() { _ = p.Serve(ctx, l, &testPlugin{}) }()
+
+	cl, err := jsonrpc.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer cl.Close()
+
+	t.Run("push is reported", func(t *testing.T) {
+		var desc Description
+		require.NoError(t, cl.Call("plugin.Describe", struct{}{}, &desc))
+		assert.True(t, desc.Push)
+	})
+
+	t.Run("no updates in time", func(t *testing.T) {
+		var resp UpdatesResp
+		start := time.Now()
+		require.NoError(t, cl.Call("plugin.Updates", UpdatesReq{Timeout: 1}, &resp))
+		assert.Empty(t, resp.Updates)
+		assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	})
+
+	t.Run("pushed updates are received in batches", func(t *testing.T) {
+		for _, id := range []string{"task-1", "task-2", "task-3"} {
+			require.NoError(t, p.Push(ctx, "sub-id", Task{ID: id}))
+		}
+
+		var resp UpdatesResp
+		require.NoError(t, cl.Call("plugin.Updates", UpdatesReq{Limit: 2}, &resp))
+		assert.Equal(t, []PushedUpdate{
+			{SubscriptionID: "sub-id", Task: Task{ID: "task-1"}},
+			{SubscriptionID: "sub-id", Task: Task{ID: "task-2"}},
+		}, resp.Updates)
+
+		require.NoError(t, cl.Call("plugin.Updates", UpdatesReq{}, &resp))
+		assert.Equal(t, []PushedUpdate{{SubscriptionID: "sub-id", Task: Task{ID: "task-3"}}}, resp.Updates)
+	})
+
+	t.Run("waiting call receives the update as soon as it is pushed", func(t *testing.T) {
+		call := cl.Go("plugin.Updates", UpdatesReq{Timeout: 10}, &UpdatesResp{}, nil)
+		time.Sleep(50 * time.Millisecond)
+		require.NoError(t, p.Push(ctx, "sub-id", Task{ID: "task-4"}))
+
+		select {
+		case <-call.Done:
+			require.NoError(t, call.Error)
+			assert.Equal(t, []PushedUpdate{{SubscriptionID: "sub-id", Task: Task{ID: "task-4"}}},
+				call.Reply.(*UpdatesResp).Updates)
+		case <-time.After(5 * time.Second):
+			t.Fatal("update wasn't received")
+		}
+	})
+}
+
+func TestPlugin_Push_NotEnabled(t *testing.T) {
+	err := (&Plugin{}).Push(context.Background(), "sub-id", Task{ID: "task-id"})
+	assert.EqualError(t, err, "pushing updates is not enabled, set PushUpdates of the plugin")
+}
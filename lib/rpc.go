@@ -2,9 +2,33 @@ package lib
 
 // Request describes a request to tracker's action.
 type Request struct {
+	Call
 	// TaskID in the target plugin, might be empty if the request is for creation
 	TaskID string `json:"task_id"`
 	Vars   Vars   `json:"vars"`
+
+	// Update, that triggered the job
+	Update Update `json:"update"`
+	// Ticket at the moment of the call, with the tasks in all trackers
+	Ticket Ticket `json:"ticket"`
+	// Job and Trigger are the names of the running job and of its trigger
+	Job     string `json:"job"`
+	Trigger string `json:"trigger"`
+	// RunID identifies the run of the job in the history of runs,
+	// the same for all calls of the single run
+	RunID string `json:"run_id"`
+}
+
+// Update describes the update of the task, that triggered the job.
+type Update struct {
+	Tracker string `json:"tracker"` // name of the tracker, which sent the update
+	Task
+}
+
+// Ticket describes the ticket, processed by the job.
+type Ticket struct {
+	ID         string          `json:"id"`         // empty if the ticket is not saved yet
+	Variations map[string]Task `json:"variations"` // tasks of the ticket by names of trackers
 }
 
 // Task describes an updated task representation in dastracker.
@@ -23,8 +47,10 @@ type Response struct {
 
 // SubscribeReq describes parameters of the subscription for task updates.
 type SubscribeReq struct {
-	WebhookURL string `json:"webhook_url"`
-	Vars       Vars   `json:"vars"`
+	Call
+	SubscriptionID string `json:"subscription_id"` // to push updates with Plugin.Push
+	WebhookURL     string `json:"webhook_url"`
+	Vars           Vars   `json:"vars"`
 }
 
 // SubscribeResp describes the response of the subscription request.
@@ -34,5 +60,27 @@ type SubscribeResp struct {
 
 // UnsubscribeReq describes parameters of the unsubscription from task updates.
 type UnsubscribeReq struct {
+	Call
 	TrackerRef string `json:"tracker_ref"`
 }
+
+// WebhookReq describes the raw webhook, received by dastracker
+// for the subscription of the plugin.
+type WebhookReq struct {
+	Call
+	SubscriptionID string              `json:"subscription_id"`
+	TrackerRef     string              `json:"tracker_ref"` // reference, returned by Subscribe
+	Method         string              `json:"method"`
+	URL            string              `json:"url"` // path and query of the request
+	Headers        map[string][]string `json:"headers"`
+	Body           []byte              `json:"body"` // base64-encoded in JSON
+}
+
+// WebhookResp describes the updates, parsed from the webhook,
+// and the response to the sender of the webhook.
+type WebhookResp struct {
+	Updates []Task            `json:"updates"` // might be empty, e.g. for verification challenges
+	Status  int               `json:"status"`  // 200 if not set
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    []byte            `json:"body,omitempty"` // base64-encoded in JSON
+}
@@ -0,0 +1,95 @@
+package lib
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// AuthReq describes parameters of the authentication of the connection.
+type AuthReq struct {
+	Token string `json:"token"`
+
+	valid bool // set by the server codec
+}
+
+// SplitAddress returns the network and the address to listen or dial,
+// "unix:///path/to/socket" addresses refer to the unix sockets,
+// the rest (with optional "tcp://" scheme) - to TCP.
+func SplitAddress(addr string) (network, address string) {
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		return "unix", strings.TrimPrefix(addr, "unix://")
+	case strings.HasPrefix(addr, "tcp://"):
+		return "tcp", strings.TrimPrefix(addr, "tcp://")
+	default:
+		return "tcp", addr
+	}
+}
+
+// ServerTLS makes TLS configuration for the plugin with the given certificate
+// and key. If the CA is set, clients must present certificates, signed by it.
+func ServerTLS(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load certificate: %w", err)
+	}
+
+	res := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if caFile == "" {
+		return res, nil
+	}
+
+	if res.ClientCAs, err = loadCA(caFile); err != nil {
+		return nil, err
+	}
+	res.ClientAuth = tls.RequireAndVerifyClientCert
+	return res, nil
+}
+
+// ClientTLS makes TLS configuration to connect to the plugin. The server's
+// certificate is verified with the given CA or with the system CAs, if empty.
+// Certificate and key of the client are optional and needed only if the
+// plugin requires them.
+func ClientTLS(certFile, keyFile, caFile, serverName string) (*tls.Config, error) {
+	res := &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS12}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load certificate: %w", err)
+		}
+		res.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile != "" {
+		var err error
+		if res.RootCAs, err = loadCA(caFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func loadCA(caFile string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(caFile) //nolint:gosec // the file is set by the operator
+	if err != nil {
+		return nil, fmt.Errorf("read CA: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in CA")
+	}
+	return pool, nil
+}
+
+// tokenValid compares tokens in constant time.
+func tokenValid(expected, actual string) bool {
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) == 1
+}
@@ -1,47 +1,194 @@
 package lib
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Vars is an alias for a map with variable values.
-type Vars map[string]string
+// Values might be strings, numbers (json.Number, as decoded from the
+// configuration or JSON, float64 or int), booleans, lists ([]interface{})
+// and maps (map[string]interface{}).
+type Vars map[string]interface{}
 
 // UnmarshalYAML implements unmarshaler in order to parse the map of values.
+// Numbers keep the text of the configuration, so that e.g. versions and
+// identifiers aren't rounded, the ones, that aren't valid JSON numbers,
+// e.g. octal 0755, are kept as strings.
 func (v *Vars) UnmarshalYAML(value *yaml.Node) error {
-	m := map[string]string{}
-	if err := value.Decode(&m); err != nil {
+	val, err := yamlValue(value)
+	if err != nil {
 		return err
 	}
+
+	switch val := val.(type) {
+	case nil:
+		*v = Vars{}
+	case map[string]interface{}:
+		*v = val
+	default:
+		return fmt.Errorf("expected a map of variables, got %s", value.ShortTag())
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes the variables, numbers are decoded
+// to json.Number in order not to be rounded to float64.
+func (v *Vars) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return err
+	}
+
 	*v = m
 	return nil
 }
 
+// yamlValue brings the value of the node to the JSON types.
+func yamlValue(n *yaml.Node) (interface{}, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return yamlValue(n.Content[0])
+	case yaml.AliasNode:
+		return yamlValue(n.Alias)
+	case yaml.SequenceNode:
+		res := make([]interface{}, 0, len(n.Content))
+		for _, item := range n.Content {
+			val, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, val)
+		}
+		return res, nil
+	case yaml.MappingNode:
+		return yamlMap(n)
+	case yaml.ScalarNode:
+		return yamlScalar(n)
+	default:
+		return nil, fmt.Errorf("unexpected yaml node at line %d", n.Line)
+	}
+}
+
+func yamlMap(n *yaml.Node) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+	var merged []map[string]interface{}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, node := n.Content[i], n.Content[i+1]
+		val, err := yamlValue(node)
+		if err != nil {
+			return nil, err
+		}
+
+		if key.ShortTag() != "!!merge" {
+			res[key.Value] = val
+			continue
+		}
+
+		// explicit keys take precedence over the merged ones
+		switch val := val.(type) {
+		case map[string]interface{}:
+			merged = append(merged, val)
+		case []interface{}:
+			for _, item := range val {
+				m, ok := item.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("map merge requires maps at line %d", key.Line)
+				}
+				merged = append(merged, m)
+			}
+		default:
+			return nil, fmt.Errorf("map merge requires a map at line %d", key.Line)
+		}
+	}
+
+	for _, m := range merged {
+		for key, val := range m {
+			if _, ok := res[key]; !ok {
+				res[key] = val
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func yamlScalar(n *yaml.Node) (interface{}, error) {
+	switch n.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	case "!!int", "!!float":
+		var num json.Number
+		if err := json.Unmarshal([]byte(n.Value), &num); err != nil {
+			return n.Value, nil
+		}
+		return num, nil
+	default:
+		return n.Value, nil
+	}
+}
+
 // Has returns true if variable with specified key is present.
 func (v *Vars) Has(key string) bool {
 	if *v == nil {
-		*v = map[string]string{}
+		*v = map[string]interface{}{}
 	}
 	_, ok := (*v)[key]
 	return ok
 }
 
-// Get returns the value of the variable.
-func (v Vars) Get(name string) string { return v[name] }
+// Get returns the value of the variable as a string.
+// Lists are joined with commas, maps are encoded to JSON.
+func (v Vars) Get(name string) string {
+	switch val := v[name].(type) {
+	case []interface{}:
+		return strings.Join(toStrings(val), ",")
+	default:
+		return toString(val)
+	}
+}
 
 // Set sets the value of the variable.
-func (v *Vars) Set(name, val string) {
+func (v *Vars) Set(name string, val interface{}) {
 	if *v == nil {
-		*v = map[string]string{}
+		*v = map[string]interface{}{}
 	}
 	(*v)[name] = val
 }
 
-// List returns a list of strings from var's
-// value parsed in form of "string1,string2,string3"
-func (v Vars) List(s string) []string { return strings.Split(v.Get(s), ",") }
+// List returns a list of strings from var's value. If the value is not
+// a list, it is parsed in form of "string1,string2,string3".
+func (v Vars) List(name string) []string {
+	if l, ok := v[name].([]interface{}); ok {
+		return toStrings(l)
+	}
+	return strings.Split(v.Get(name), ",")
+}
+
+// Map returns the nested variables, nil if the value is not a map.
+func (v Vars) Map(name string) Vars {
+	m, _ := v[name].(map[string]interface{})
+	return m
+}
 
 // Equal returns true if two sets of variables represent the same one.
 // Note: two sets of variables with different Evaluated state are considered
@@ -54,10 +201,43 @@ func (v Vars) Equal(oth Vars) bool {
 
 	for key, val := range v {
 		othVal, present := oth[key]
-		if !present || val != othVal {
+		if !present || !reflect.DeepEqual(val, othVal) {
 			return false
 		}
 	}
 
 	return true
 }
+
+func toStrings(l []interface{}) []string {
+	res := make([]string, 0, len(l))
+	for _, val := range l {
+		res = append(res, toString(val))
+	}
+	return res
+}
+
+func toString(val interface{}) string {
+	switch val := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case bool:
+		return strconv.FormatBool(val)
+	case json.Number:
+		return val.String()
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(val)
+	case fmt.Stringer:
+		return val.String()
+	default:
+		bts, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(bts)
+	}
+}
@@ -1,9 +1,11 @@
 package lib
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
 )
 
@@ -12,19 +14,114 @@ func TestVars_UnmarshalYAML(t *testing.T) {
 	v := Vars{}
 	err := yaml.Unmarshal([]byte(y), &v)
 	assert.NoError(t, err)
-	assert.Equal(t, Vars(map[string]string{"var1": "val1", "var2": "val2"}), v)
+	assert.Equal(t, Vars{"var1": "val1", "var2": "val2"}, v)
+
+	t.Run("typed values", func(t *testing.T) {
+		const y = `
+str: "1,2"
+num: 42
+float: 1.5
+bool: true
+list: [a, "b,c", 3]
+map:
+  key: value
+  nested: [1]
+`
+		v := Vars{}
+		require.NoError(t, yaml.Unmarshal([]byte(y), &v))
+		assert.Equal(t, Vars{
+			"str":   "1,2",
+			"num":   json.Number("42"),
+			"float": json.Number("1.5"),
+			"bool":  true,
+			"list":  []interface{}{"a", "b,c", json.Number("3")},
+			"map":   map[string]interface{}{"key": "value", "nested": []interface{}{json.Number("1")}},
+		}, v)
+
+		// values are carried intact over JSON
+		bts, err := json.Marshal(v)
+		require.NoError(t, err)
+		var decoded Vars
+		require.NoError(t, json.Unmarshal(bts, &decoded))
+		assert.Equal(t, v, decoded)
+	})
+
+	t.Run("numbers are kept intact", func(t *testing.T) {
+		const y = `
+version: 1.10
+mode: 0755
+big: 9007199254740993
+huge: 12345678901234567890
+hex: 0x1F
+neg: -3
+`
+		v := Vars{}
+		require.NoError(t, yaml.Unmarshal([]byte(y), &v))
+
+		want := map[string]string{
+			"version": "1.10",
+			"mode":    "0755",
+			"big":     "9007199254740993",
+			"huge":    "12345678901234567890",
+			"hex":     "0x1F",
+			"neg":     "-3",
+		}
+		for name, val := range want {
+			assert.Equal(t, val, v.Get(name), name)
+		}
+		assert.Equal(t, TypeString, TypeOf(v["mode"]), "invalid JSON number must be kept as string")
+		assert.Equal(t, TypeNumber, TypeOf(v["version"]))
+
+		// plugins receive the same values
+		bts, err := json.Marshal(v)
+		require.NoError(t, err)
+		var decoded Vars
+		require.NoError(t, json.Unmarshal(bts, &decoded))
+		for name, val := range want {
+			assert.Equal(t, val, decoded.Get(name), name)
+		}
+	})
+
+	t.Run("anchors and merges", func(t *testing.T) {
+		const y = `
+base: &base {a: 1, b: x}
+merged:
+  <<: *base
+  b: y
+alias: *base
+`
+		v := Vars{}
+		require.NoError(t, yaml.Unmarshal([]byte(y), &v))
+		assert.Equal(t, Vars{"a": json.Number("1"), "b": "y"}, v.Map("merged"))
+		assert.Equal(t, v.Map("base"), v.Map("alias"))
+	})
+
+	t.Run("not a map", func(t *testing.T) {
+		v := Vars{}
+		assert.Error(t, yaml.Unmarshal([]byte("[a, b]"), &v))
+	})
 }
 
 func TestVars_Has(t *testing.T) {
-	v := Vars(map[string]string{"var1": "val1"})
+	v := Vars{"var1": "val1"}
 	assert.True(t, v.Has("var1"))
 	assert.False(t, v.Has("var2"))
 	assert.NotPanics(t, func() { v := (Vars)(nil); assert.False(t, v.Has("blah")) })
 }
 
 func TestVars_Get(t *testing.T) {
-	v := Vars(map[string]string{"var1": "val1"})
+	v := Vars{
+		"var1": "val1",
+		"num":  float64(42),
+		"bool": false,
+		"list": []interface{}{"a", float64(1.5), true},
+		"map":  map[string]interface{}{"k": "v"},
+	}
 	assert.Equal(t, "val1", v.Get("var1"))
+	assert.Equal(t, "42", v.Get("num"))
+	assert.Equal(t, "false", v.Get("bool"))
+	assert.Equal(t, "a,1.5,true", v.Get("list"))
+	assert.Equal(t, `{"k":"v"}`, v.Get("map"))
 	assert.Empty(t, v.Get("var2"))
 	assert.NotPanics(t, func() { assert.Empty(t, ((Vars)(nil)).Get("var1")) })
 }
@@ -32,21 +129,31 @@ func TestVars_Get(t *testing.T) {
 func TestVars_Set(t *testing.T) {
 	v := Vars{}
 	v.Set("var1", "val1")
-	assert.Equal(t, Vars(map[string]string{"var1": "val1"}), v)
+	v.Set("list", []interface{}{"a"})
+	assert.Equal(t, Vars{"var1": "val1", "list": []interface{}{"a"}}, v)
 	assert.NotPanics(t, func() { v := (Vars)(nil); v.Set("var1", "val1") })
 }
 
 func TestVars_List(t *testing.T) {
-	v := Vars(map[string]string{"list": "a,b,c,d"})
+	v := Vars{"list": "a,b,c,d", "typed": []interface{}{"a,b", "c"}}
 	assert.Equal(t, []string{"a", "b", "c", "d"}, v.List("list"))
+	assert.Equal(t, []string{"a,b", "c"}, v.List("typed"))
 	assert.NotPanics(t, func() { ((Vars)(nil)).List("var1") })
 }
 
+func TestVars_Map(t *testing.T) {
+	v := Vars{"map": map[string]interface{}{"k": "v"}, "str": "s"}
+	assert.Equal(t, Vars{"k": "v"}, v.Map("map"))
+	assert.Equal(t, "v", v.Map("map").Get("k"))
+	assert.Nil(t, v.Map("str"))
+	assert.Nil(t, v.Map("unknown"))
+}
+
 func TestVars_Equal(t *testing.T) {
-	assert.True(t, Vars(map[string]string{"var1": "val1", "var2": "val2"}).
-		Equal(map[string]string{"var2": "val2", "var1": "val1"}))
+	assert.True(t, Vars{"var1": "val1", "var2": "val2"}.Equal(Vars{"var2": "val2", "var1": "val1"}))
 	assert.True(t, Vars{}.Equal(Vars{}))
+	assert.True(t, Vars{"l": []interface{}{"a"}}.Equal(Vars{"l": []interface{}{"a"}}))
 
-	assert.False(t, Vars(map[string]string{"var1": "val1"}).
-		Equal(map[string]string{"var2": "val2", "var1": "val1"}))
+	assert.False(t, Vars{"var1": "val1"}.Equal(Vars{"var2": "val2", "var1": "val1"}))
+	assert.False(t, Vars{"l": []interface{}{"a"}}.Equal(Vars{"l": []interface{}{"b"}}))
 }
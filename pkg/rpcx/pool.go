@@ -0,0 +1,334 @@
+package rpcx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cappuccinotm/dastracker/pkg/logx"
+	"github.com/cappuccinotm/dastracker/pkg/repeaterx"
+	"github.com/go-pkgz/repeater/strategy"
+)
+
+// PoolParams describes parameters of the connection pool.
+type PoolParams struct {
+	Dialer  Dialer
+	Network string
+	Addr    string
+	Size    int                // number of connections, 4 by default
+	Retry   strategy.Interface // strategy to repeat calls, which failed due to the broken connection
+	Logger  logx.Logger        // no-op by default
+
+	// HealthCheck is the method to call on each open connection every
+	// HealthInterval, health checks are disabled if either is empty. Errors
+	// returned by the server are ignored, only the connection errors matter.
+	HealthCheck    string
+	HealthInterval time.Duration
+	HealthTimeout  time.Duration // 5 seconds by default
+}
+
+// Stats describes the usage of the connection pool.
+type Stats struct {
+	Size           int    // number of connections
+	Open           int    // number of connections, that are open at the moment
+	Calls          uint64 // number of calls
+	Failures       uint64 // number of failed calls
+	Dials          uint64 // number of successful dials
+	DialFailures   uint64 // number of failed dials
+	HealthFailures uint64 // number of connections, closed due to the failed health check
+}
+
+// Pool is a concurrency-safe pool of connections to the RPC server.
+// Calls are distributed among the connections in round-robin manner.
+// Broken connections are closed and redialed on the next call.
+type Pool struct {
+	PoolParams
+	repeater *repeaterx.AllowedErrors
+	conns    []*conn
+	next     uint32
+
+	stats struct {
+		calls, failures, dials, dialFailures, healthFailures uint64
+	}
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// conn is a single connection of the pool, nil client means
+// that the connection is not dialed yet or it is broken.
+type conn struct {
+	mu      sync.Mutex
+	cl      RPCClient
+	dialing chan struct{} // closed when the dial in progress is finished, nil if not dialing
+}
+
+// NewPool makes new instance of Pool and dials the first connection,
+// other connections are dialed on demand.
+func NewPool(params PoolParams) (*Pool, error) {
+	p := &Pool{
+		PoolParams: params,
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	p.setDefaults()
+
+	p.repeater = repeaterx.NewAllowedErrors(p.Retry)
+	p.conns = make([]*conn, p.Size)
+	for i := range p.conns {
+		p.conns[i] = &conn{}
+	}
+
+	err := p.repeater.Do(context.Background(), func() error {
+		_, err := p.client(context.Background(), p.conns[0])
+		return err
+	}, rpc.ErrShutdown)
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+
+	if p.HealthCheck != "" && p.HealthInterval > 0 {
+		go p.checkHealth()
+	} else {
+		close(p.stopped)
+	}
+
+	return p, nil
+}
+
+// Call makes a call to the remote RPC server over one of the connections.
+// Calls, that failed due to the shut down connection, are repeated.
+// Returns the error of the context, if it is done before the response
+// is received.
+func (p *Pool) Call(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	atomic.AddUint64(&p.stats.calls, 1)
+
+	err := p.repeater.Do(ctx, func() error {
+		c := p.conns[int(atomic.AddUint32(&p.next, 1)-1)%len(p.conns)]
+
+		cl, err := p.client(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		err = call(ctx, cl, serviceMethod, args, reply)
+		if isConnErr(err) {
+			p.invalidate(c, cl)
+		}
+		return err
+	}, rpc.ErrShutdown)
+
+	if err != nil {
+		atomic.AddUint64(&p.stats.failures, 1)
+	}
+	return err
+}
+
+// Stats returns the usage statistics of the pool.
+func (p *Pool) Stats() Stats {
+	res := Stats{
+		Size:           len(p.conns),
+		Calls:          atomic.LoadUint64(&p.stats.calls),
+		Failures:       atomic.LoadUint64(&p.stats.failures),
+		Dials:          atomic.LoadUint64(&p.stats.dials),
+		DialFailures:   atomic.LoadUint64(&p.stats.dialFailures),
+		HealthFailures: atomic.LoadUint64(&p.stats.healthFailures),
+	}
+	for _, c := range p.conns {
+		c.mu.Lock()
+		if c.cl != nil {
+			res.Open++
+		}
+		c.mu.Unlock()
+	}
+	return res
+}
+
+// Close stops health checks and closes all connections.
+func (p *Pool) Close() error {
+	select {
+	case <-p.stop:
+		return nil
+	default:
+		close(p.stop)
+	}
+	<-p.stopped
+
+	var errs []string
+	for _, c := range p.conns {
+		c.mu.Lock()
+		if c.cl != nil {
+			if err := c.cl.Close(); err != nil && !errors.Is(err, rpc.ErrShutdown) {
+				errs = append(errs, err.Error())
+			}
+			c.cl = nil
+		}
+		c.mu.Unlock()
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("close connections: %v", errs)
+	}
+	return nil
+}
+
+// client returns the client of the connection, dials it if needed.
+// The dial is made without holding the lock of the connection, so the
+// stats and health checks are not blocked by it, concurrent callers
+// wait for the dial in progress until their contexts are done.
+func (p *Pool) client(ctx context.Context, c *conn) (RPCClient, error) {
+	c.mu.Lock()
+	for c.cl == nil && c.dialing != nil {
+		dialing := c.dialing
+		c.mu.Unlock()
+
+		select {
+		case <-dialing:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		c.mu.Lock()
+	}
+
+	if c.cl != nil {
+		defer c.mu.Unlock()
+		return c.cl, nil
+	}
+
+	select {
+	case <-p.stop:
+		c.mu.Unlock()
+		return nil, errors.New("pool is closed")
+	default:
+	}
+
+	dialing := make(chan struct{})
+	c.dialing = dialing
+	c.mu.Unlock()
+
+	cl, err := p.Dialer.Dial(ctx, p.Network, p.Addr)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dialing = nil
+	close(dialing)
+
+	if err != nil {
+		atomic.AddUint64(&p.stats.dialFailures, 1)
+		p.Logger.Printf("[WARN] failed to dial %s://%s: %v", p.Network, p.Addr, err)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("dial %s://%s: %w", p.Network, p.Addr, ctx.Err())
+		}
+		if errors.As(err, &permanentErr{}) {
+			return nil, fmt.Errorf("dial %s://%s: %w", p.Network, p.Addr, err)
+		}
+		// the call might be repeated with the next attempt to dial
+		return nil, fmt.Errorf("%w: dial %s://%s: %v", rpc.ErrShutdown, p.Network, p.Addr, err)
+	}
+
+	// the pool might be closed while dialing
+	select {
+	case <-p.stop:
+		_ = cl.Close()
+		return nil, errors.New("pool is closed")
+	default:
+	}
+
+	atomic.AddUint64(&p.stats.dials, 1)
+	c.cl = cl
+	return cl, nil
+}
+
+// invalidate closes the broken client of the connection,
+// unless it is already replaced by another call.
+func (p *Pool) invalidate(c *conn, cl RPCClient) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cl != cl {
+		return
+	}
+	c.cl = nil
+	if err := cl.Close(); err != nil && !errors.Is(err, rpc.ErrShutdown) {
+		p.Logger.Printf("[DEBUG] failed to close broken connection: %v", err)
+	}
+}
+
+// checkHealth calls the health check method on each open connection
+// every HealthInterval and closes the connections, that failed it.
+func (p *Pool) checkHealth() {
+	defer close(p.stopped)
+
+	ticker := time.NewTicker(p.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		for _, c := range p.conns {
+			c.mu.Lock()
+			cl := c.cl
+			c.mu.Unlock()
+
+			if cl == nil {
+				continue
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), p.HealthTimeout)
+			var reply interface{}
+			err := call(ctx, cl, p.HealthCheck, struct{}{}, &reply)
+			cancel()
+
+			var srvErr rpc.ServerError
+			if err == nil || errors.As(err, &srvErr) {
+				continue
+			}
+
+			atomic.AddUint64(&p.stats.healthFailures, 1)
+			p.Logger.Printf("[WARN] health check of connection to %s://%s failed: %v", p.Network, p.Addr, err)
+			p.invalidate(c, cl)
+		}
+	}
+}
+
+func (p *Pool) setDefaults() {
+	if p.Size <= 0 {
+		p.Size = 4
+	}
+	if p.Retry == nil {
+		p.Retry = &strategy.FixedDelay{Repeats: 3, Delay: time.Second}
+	}
+	if p.Logger == nil {
+		p.Logger = logx.Nop()
+	}
+	if p.HealthTimeout == 0 {
+		p.HealthTimeout = 5 * time.Second
+	}
+}
+
+// call makes an asynchronous call and waits either for its
+// completion or for the context to be done.
+func call(ctx context.Context, cl RPCClient, serviceMethod string, args interface{}, reply interface{}) error {
+	c := cl.Go(serviceMethod, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-c.Done:
+		return c.Error
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isConnErr returns true if the error indicates the broken connection.
+func isConnErr(err error) bool {
+	return errors.Is(err, rpc.ErrShutdown) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
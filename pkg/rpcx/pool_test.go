@@ -0,0 +1,290 @@
+package rpcx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-pkgz/repeater/strategy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool_Call(t *testing.T) {
+	srv := startServer(t)
+
+	p, err := NewPool(PoolParams{Dialer: JSONRPC(), Network: "tcp", Addr: srv.addr, Retry: &strategy.Once{}})
+	require.NoError(t, err)
+	defer p.Close()
+
+	var resp string
+	require.NoError(t, p.Call(context.Background(), "svc.Echo", "hello", &resp))
+	assert.Equal(t, "hello", resp)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = p.Call(ctx, "svc.Hang", "", &resp)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second), "call must be interrupted by the context")
+
+	stats := p.Stats()
+	assert.Equal(t, Stats{Size: 4, Open: 2, Calls: 2, Failures: 1, Dials: 2}, stats,
+		"the first connection is dialed at start, the second - on demand")
+}
+
+func TestPool_Concurrent(t *testing.T) {
+	srv := startServer(t)
+
+	p, err := NewPool(PoolParams{
+		Dialer:  JSONRPC(),
+		Network: "tcp",
+		Addr:    srv.addr,
+		Size:    3,
+		Retry:   &strategy.FixedDelay{Repeats: 5, Delay: time.Millisecond},
+	})
+	require.NoError(t, err)
+	defer p.Close()
+
+	const workers, calls = 20, 50
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < calls; j++ {
+				req := fmt.Sprintf("%d-%d", i, j)
+				var resp string
+				err := p.Call(context.Background(), "svc.Echo", req, &resp)
+				if err != nil {
+					// the calls in flight fail, when the connection is dropped
+					assert.True(t, isConnErr(err), "unexpected error: %v", err)
+					continue
+				}
+				assert.Equal(t, req, resp)
+			}
+		}(i)
+
+		if i%5 == 0 {
+			srv.dropConns()
+		}
+	}
+	wg.Wait()
+
+	// the pool recovers after all connections are dropped,
+	// calls in flight on the dropped connections might fail
+	srv.dropConns()
+	require.Eventually(t, func() bool {
+		return p.Call(context.Background(), "svc.Echo", "after", new(string)) == nil
+	}, 5*time.Second, time.Millisecond)
+	for i := 0; i < 10; i++ {
+		var resp string
+		require.NoError(t, p.Call(context.Background(), "svc.Echo", "after", &resp))
+		assert.Equal(t, "after", resp)
+	}
+
+	stats := p.Stats()
+	assert.Greater(t, stats.Calls, uint64(workers*calls+10))
+	assert.Greater(t, stats.Dials, uint64(3), "dropped connections must be redialed")
+}
+
+func TestPool_HealthCheck(t *testing.T) {
+	srv := startServer(t)
+
+	t.Run("hung connections are closed", func(t *testing.T) {
+		p, err := NewPool(PoolParams{
+			Dialer:         JSONRPC(),
+			Network:        "tcp",
+			Addr:           srv.addr,
+			HealthCheck:    "svc.Hang",
+			HealthInterval: 10 * time.Millisecond,
+			HealthTimeout:  10 * time.Millisecond,
+		})
+		require.NoError(t, err)
+		defer p.Close()
+
+		require.Eventually(t, func() bool {
+			return p.Stats().HealthFailures > 0
+		}, 5*time.Second, 10*time.Millisecond)
+
+		var resp string
+		require.NoError(t, p.Call(context.Background(), "svc.Echo", "hello", &resp))
+		assert.Equal(t, "hello", resp)
+	})
+
+	t.Run("errors of the server are ignored", func(t *testing.T) {
+		p, err := NewPool(PoolParams{
+			Dialer:         JSONRPC(),
+			Network:        "tcp",
+			Addr:           srv.addr,
+			HealthCheck:    "svc.Unknown",
+			HealthInterval: 5 * time.Millisecond,
+		})
+		require.NoError(t, err)
+
+		time.Sleep(50 * time.Millisecond)
+		require.NoError(t, p.Close())
+		assert.Zero(t, p.Stats().HealthFailures)
+	})
+}
+
+func TestNewPool_DialFailed(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	_, err = NewPool(PoolParams{Dialer: JSONRPC(), Network: "tcp", Addr: addr, Retry: &strategy.Once{}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dial tcp://"+addr)
+}
+
+func TestPool_PermanentDialError(t *testing.T) {
+	srv := startServer(t)
+
+	dials := 0
+	_, err := NewPool(PoolParams{
+		Dialer: DialerFunc(func(context.Context, string, string) (RPCClient, error) {
+			dials++
+			return nil, Permanent(errors.New("invalid token"))
+		}),
+		Network: "tcp",
+		Addr:    srv.addr,
+		Retry:   &strategy.FixedDelay{Repeats: 3, Delay: time.Millisecond},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid token")
+	assert.Equal(t, 1, dials, "permanent errors must not be repeated")
+}
+
+func TestPool_SlowDial(t *testing.T) {
+	srv := startServer(t)
+
+	dialing := make(chan struct{}, 1)
+	dials := int32(0)
+	p, err := NewPool(PoolParams{
+		Dialer: DialerFunc(func(ctx context.Context, network, addr string) (RPCClient, error) {
+			if atomic.AddInt32(&dials, 1) == 1 {
+				return JSONRPC().Dial(ctx, network, addr)
+			}
+			// all dials, except the first one, hang until the call is canceled
+			dialing <- struct{}{}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}),
+		Network: "tcp",
+		Addr:    srv.addr,
+		Size:    2,
+		Retry:   &strategy.Once{},
+	})
+	require.NoError(t, err)
+	defer p.Close()
+
+	// the first connection is dialed at start
+	require.NoError(t, p.Call(context.Background(), "svc.Echo", "first", new(string)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hung := make(chan error, 1)
+	go func() { hung <- p.Call(ctx, "svc.Echo", "second", new(string)) }()
+	<-dialing
+
+	t.Run("stats are not blocked", func(t *testing.T) {
+		stats := make(chan Stats, 1)
+		go func() { stats <- p.Stats() }()
+		select {
+		case s := <-stats:
+			assert.Equal(t, 1, s.Open)
+		case <-time.After(time.Second):
+			t.Fatal("stats are blocked by the dial")
+		}
+	})
+
+	t.Run("other connections are not blocked", func(t *testing.T) {
+		var resp string
+		require.NoError(t, p.Call(context.Background(), "svc.Echo", "third", &resp))
+		assert.Equal(t, "third", resp)
+	})
+
+	t.Run("waiting for the dial is interrupted by the context", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err := p.Call(ctx, "svc.Echo", "fourth", new(string))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	})
+
+	cancel()
+	select {
+	case err := <-hung:
+		assert.ErrorIs(t, err, context.Canceled, "dial must be interrupted by the context")
+	case <-time.After(time.Second):
+		t.Fatal("dial is not interrupted by the context")
+	}
+	assert.Equal(t, uint64(1), p.Stats().DialFailures)
+}
+
+type testServer struct {
+	addr string
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func startServer(t *testing.T) *testServer {
+	srv := rpc.NewServer()
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	require.NoError(t, srv.RegisterName("svc", &slowService{release: release}))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	res := &testServer{addr: l.Addr().String()}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			res.mu.Lock()
+			res.conns = append(res.conns, conn)
+			res.mu.Unlock()
+			go srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+		}
+	}()
+
+	return res
+}
+
+// dropConns closes all accepted connections.
+func (s *testServer) dropConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+type slowService struct{ release chan struct{} }
+
+func (s *slowService) Echo(req string, resp *string) error {
+	*resp = req
+	return nil
+}
+
+func (s *slowService) Hang(_ interface{}, _ *string) error {
+	<-s.release
+	return nil
+}
@@ -2,22 +2,27 @@ package rpcx
 
 import (
 	"context"
+	"net"
+	"net/rpc"
 	"net/rpc/jsonrpc"
 )
 
 //go:generate rm -f client_mock.go
 //go:generate moq -out client_mock.go -fmt goimports . Client
 
-// Dialer is a maker interface dialing to rpc server and returning new Client
+// Dialer is a maker interface dialing to rpc server and returning new Client.
+// The dial must be interrupted when the context is done.
 type Dialer interface {
-	Dial(network, address string) (RPCClient, error)
+	Dial(ctx context.Context, network, address string) (RPCClient, error)
 }
 
 // DialerFunc is an adapter to use ordinary functions as Dialer.
-type DialerFunc func(network, address string) (RPCClient, error)
+type DialerFunc func(ctx context.Context, network, address string) (RPCClient, error)
 
 // Dial calls the wrapped function.
-func (f DialerFunc) Dial(network, addr string) (RPCClient, error) { return f(network, addr) }
+func (f DialerFunc) Dial(ctx context.Context, network, addr string) (RPCClient, error) {
+	return f(ctx, network, addr)
+}
 
 // Client defines interface for a client to make contextual remote calls.
 type Client interface {
@@ -28,12 +33,26 @@ type Client interface {
 // RPCClient describes a pure standard library rpc client.
 type RPCClient interface {
 	Call(serviceMethod string, args interface{}, reply interface{}) error
+	Go(serviceMethod string, args interface{}, reply interface{}, done chan *rpc.Call) *rpc.Call
 	Close() error
 }
 
-// JSONRPC returns the jsonrpc.Dial dialer.
+// Permanent marks the error of the dialer as permanent, e.g. authentication
+// failure, thus the dial is not repeated.
+func Permanent(err error) error { return permanentErr{err: err} }
+
+type permanentErr struct{ err error }
+
+func (e permanentErr) Error() string { return e.err.Error() }
+func (e permanentErr) Unwrap() error { return e.err }
+
+// JSONRPC returns the dialer, that makes jsonrpc clients.
 func JSONRPC() Dialer {
-	return DialerFunc(func(network, addr string) (RPCClient, error) {
-		return jsonrpc.Dial(network, addr)
+	return DialerFunc(func(ctx context.Context, network, addr string) (RPCClient, error) {
+		conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return jsonrpc.NewClient(conn), nil
 	})
 }